@@ -4,6 +4,10 @@ go 1.24.5
 
 require (
 	github.com/mark3labs/mcp-go v0.37.0
+	github.com/tetratelabs/wazero v1.10.1
+	go.starlark.net v0.0.0-20250417143717-f57e51f710eb
+	golang.org/x/crypto v0.40.0
+	golang.org/x/sys v0.34.0
 	gopkg.in/yaml.v2 v2.4.0
 	modernc.org/sqlite v1.38.2
 )
@@ -22,7 +26,6 @@ require (
 	github.com/wk8/go-ordered-map/v2 v2.1.8 // indirect
 	github.com/yosida95/uritemplate/v3 v3.0.2 // indirect
 	golang.org/x/exp v0.0.0-20250620022241-b7579e27df2b // indirect
-	golang.org/x/sys v0.34.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 	modernc.org/libc v1.66.3 // indirect
 	modernc.org/mathutil v1.7.1 // indirect
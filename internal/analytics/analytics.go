@@ -22,8 +22,13 @@ type CommandEvent struct {
 	Duration      time.Duration
 	Success       bool
 	OutputSize    int64
-	ExecutionMode string // "local" or "container"
+	ExecutionMode string // "local", "sandbox", "ssh", "wasm", "starlark", "sql", "container", "kubernetes", "webhook", "plugin", "workflow" or a custom executor type; where the command actually ran
+	ExitCode      int
+	Cancelled     bool // stopped by the client; counted neither as success nor failure
+	Fallback      string // failure that made the command run elsewhere: "unavailable", "pull" or "timeout"
 	Error         string
+	RunID         string // identifies this run
+	ParentRunID   string // run of the workflow or Starlark script that ran this command, if any
 }
 
 // UsageStats represents aggregated usage statistics
@@ -33,6 +38,7 @@ type UsageStats struct {
 	TopCommands      []CommandSummary `json:"top_commands"`
 	ErrorsLast24h    int64            `json:"errors_last_24h"`
 	AvgDurationMs    int64            `json:"avg_duration_ms"`
+	Fallbacks        int64            `json:"fallbacks"`
 }
 
 // CommandSummary represents a command usage summary
@@ -41,6 +47,7 @@ type CommandSummary struct {
 	Count        int64   `json:"count"`
 	SuccessRate  float64 `json:"success_rate"`
 	AvgDuration  int64   `json:"avg_duration_ms"`
+	Fallbacks    int64   `json:"fallbacks"`
 }
 
 // HTTPEvent represents an HTTP server event
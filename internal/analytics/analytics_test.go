@@ -2,6 +2,7 @@ package analytics
 
 import (
 	"context"
+	"database/sql"
 	"os"
 	"testing"
 	"time"
@@ -89,4 +90,144 @@ func TestNoOpAnalytics(t *testing.T) {
 	if err != nil {
 		t.Errorf("NoOp analytics close should not error: %v", err)
 	}
-}
\ No newline at end of file
+}
+func TestSQLiteAnalyticsMigratesExitCode(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "test_analytics_migrate.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmpfile.Name())
+	tmpfile.Close()
+
+	// Create a database with the schema used before exit codes were recorded
+	db, err := sql.Open("sqlite", tmpfile.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec(`CREATE TABLE events (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		timestamp DATETIME DEFAULT CURRENT_TIMESTAMP,
+		session_id TEXT, command_name TEXT, duration_ms INTEGER, success BOOLEAN,
+		error_message TEXT, output_size INTEGER, execution_mode TEXT)`)
+	db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	analytics, err := NewSQLiteAnalytics(tmpfile.Name())
+	if err != nil {
+		t.Fatalf("Failed to open existing database: %v", err)
+	}
+	defer analytics.Close()
+
+	analytics.RecordCommand(context.Background(), CommandEvent{
+		CommandName:   "failing",
+		ExecutionMode: "local",
+		ExitCode:      2,
+	})
+
+	var exitCode int
+	if err := analytics.db.QueryRow(`SELECT exit_code FROM events WHERE command_name = 'failing'`).Scan(&exitCode); err != nil {
+		t.Fatalf("Failed to read exit code: %v", err)
+	}
+	if exitCode != 2 {
+		t.Errorf("Expected exit code 2, got %d", exitCode)
+	}
+}
+
+func TestSQLiteAnalyticsExcludesCancelled(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "test_analytics_cancelled.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmpfile.Name())
+	tmpfile.Close()
+
+	analytics, err := NewSQLiteAnalytics(tmpfile.Name())
+	if err != nil {
+		t.Fatalf("Failed to create analytics: %v", err)
+	}
+	defer analytics.Close()
+
+	ctx := context.Background()
+	analytics.RecordCommand(ctx, CommandEvent{CommandName: "build", ExecutionMode: "local", Success: true})
+	analytics.RecordCommand(ctx, CommandEvent{
+		CommandName:   "build",
+		ExecutionMode: "local",
+		Cancelled:     true,
+		ExitCode:      -1,
+		Error:         "command cancelled",
+	})
+
+	stats, err := analytics.GetStats(7)
+	if err != nil {
+		t.Fatalf("Failed to get stats: %v", err)
+	}
+	if stats.TotalCommands != 2 {
+		t.Errorf("Expected cancelled run to be counted, got %d commands", stats.TotalCommands)
+	}
+	if stats.SuccessRate != 100.0 {
+		t.Errorf("Expected cancelled run not to lower the success rate, got %.1f", stats.SuccessRate)
+	}
+	if stats.ErrorsLast24h != 0 {
+		t.Errorf("Expected cancelled run not to count as an error, got %d", stats.ErrorsLast24h)
+	}
+}
+
+func TestSQLiteAnalyticsFallbacks(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "analytics_fallback.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmpfile.Name())
+	tmpfile.Close()
+
+	analytics, err := NewSQLiteAnalytics(tmpfile.Name())
+	if err != nil {
+		t.Fatalf("Failed to create analytics: %v", err)
+	}
+	defer analytics.Close()
+
+	ctx := context.Background()
+	analytics.RecordCommand(ctx, CommandEvent{CommandName: "render", ExecutionMode: "container", Success: true})
+	analytics.RecordCommand(ctx, CommandEvent{CommandName: "render", ExecutionMode: "local", Success: true, Fallback: "unavailable"})
+
+	stats, err := analytics.GetStats(7)
+	if err != nil {
+		t.Fatalf("Failed to get stats: %v", err)
+	}
+	if stats.Fallbacks != 1 {
+		t.Errorf("Expected 1 fallback, got %d", stats.Fallbacks)
+	}
+	if len(stats.TopCommands) != 1 || stats.TopCommands[0].Fallbacks != 1 {
+		t.Errorf("Expected per-command fallback count, got %+v", stats.TopCommands)
+	}
+}
+
+func TestSQLiteAnalyticsChildEvents(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "analytics_runs.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmpfile.Name())
+	tmpfile.Close()
+
+	analytics, err := NewSQLiteAnalytics(tmpfile.Name())
+	if err != nil {
+		t.Fatalf("Failed to create analytics: %v", err)
+	}
+	defer analytics.Close()
+
+	ctx := context.Background()
+	analytics.RecordCommand(ctx, CommandEvent{CommandName: "fetch", ExecutionMode: "webhook", Success: true, RunID: "b", ParentRunID: "a"})
+	analytics.RecordCommand(ctx, CommandEvent{CommandName: "store", ExecutionMode: "sql", Success: true, RunID: "c", ParentRunID: "a"})
+	analytics.RecordCommand(ctx, CommandEvent{CommandName: "sync", ExecutionMode: "workflow", Success: true, RunID: "a"})
+
+	var children int
+	if err := analytics.db.QueryRow(`SELECT COUNT(*) FROM events WHERE parent_run_id = 'a'`).Scan(&children); err != nil {
+		t.Fatalf("Failed to query child events: %v", err)
+	}
+	if children != 2 {
+		t.Errorf("Expected 2 child events of the workflow run, got %d", children)
+	}
+}
@@ -21,7 +21,7 @@ type SQLiteAnalytics struct {
 // NewSQLiteAnalytics creates a new SQLite analytics instance
 func NewSQLiteAnalytics(dbPath string) (*SQLiteAnalytics, error) {
 	// Resolve path (handle ~ and relative paths)
-	resolvedPath, err := resolvePath(dbPath)
+	resolvedPath, err := ResolvePath(dbPath)
 	if err != nil {
 		return nil, err
 	}
@@ -51,7 +51,12 @@ func (a *SQLiteAnalytics) createTables() error {
 		success BOOLEAN,
 		error_message TEXT,
 		output_size INTEGER,
-		execution_mode TEXT
+		execution_mode TEXT,
+		exit_code INTEGER,
+		cancelled BOOLEAN DEFAULT 0,
+		fallback TEXT DEFAULT '',
+		run_id TEXT DEFAULT '',
+		parent_run_id TEXT DEFAULT ''
 	);
 
 	CREATE TABLE IF NOT EXISTS http_events (
@@ -76,7 +81,55 @@ func (a *SQLiteAnalytics) createTables() error {
 	CREATE INDEX IF NOT EXISTS idx_http_events_status ON http_events(status_code);
 	`
 
-	_, err := a.db.Exec(schema)
+	if _, err := a.db.Exec(schema); err != nil {
+		return err
+	}
+
+	// Columns added after the initial schema, for databases created by older versions
+	if err := a.addColumnIfMissing("events", "exit_code", "INTEGER"); err != nil {
+		return err
+	}
+	if err := a.addColumnIfMissing("events", "cancelled", "BOOLEAN DEFAULT 0"); err != nil {
+		return err
+	}
+	if err := a.addColumnIfMissing("events", "fallback", "TEXT DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := a.addColumnIfMissing("events", "run_id", "TEXT DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := a.addColumnIfMissing("events", "parent_run_id", "TEXT DEFAULT ''"); err != nil {
+		return err
+	}
+	// Created once the column exists, so child events of a run can be listed
+	_, err := a.db.Exec("CREATE INDEX IF NOT EXISTS idx_events_parent_run ON events(parent_run_id)")
+	return err
+}
+
+// addColumnIfMissing adds a column to an existing table unless it is already present
+func (a *SQLiteAnalytics) addColumnIfMissing(table, column, definition string) error {
+	rows, err := a.db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, colType string
+		var defaultValue sql.NullString
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &defaultValue, &pk); err != nil {
+			return err
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	_, err = a.db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
 	return err
 }
 
@@ -85,10 +138,12 @@ func (a *SQLiteAnalytics) RecordCommand(ctx context.Context, event CommandEvent)
 	// Sync insert for now to ensure data is written
 	_, err := a.db.Exec(`
 		INSERT INTO events (session_id, command_name, duration_ms, success, 
-						   error_message, output_size, execution_mode)
-		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+						   error_message, output_size, execution_mode, exit_code, cancelled, fallback,
+						   run_id, parent_run_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 		event.SessionID, event.CommandName, event.Duration.Milliseconds(),
-		event.Success, event.Error, event.OutputSize, event.ExecutionMode)
+		event.Success, event.Error, event.OutputSize, event.ExecutionMode, event.ExitCode,
+		event.Cancelled, event.Fallback, event.RunID, event.ParentRunID)
 	
 	if err != nil {
 		log.Printf("Analytics command recording failed: %v", err)
@@ -116,15 +171,16 @@ func (a *SQLiteAnalytics) GetStats(days int) (*UsageStats, error) {
 	row := a.db.QueryRow(`
 		SELECT 
 			COUNT(*) as total_commands,
-			COALESCE(AVG(CASE WHEN success THEN 1.0 ELSE 0.0 END), 0) as success_rate,
+			COALESCE(AVG(CASE WHEN cancelled THEN NULL WHEN success THEN 1.0 ELSE 0.0 END), 0) as success_rate,
 			COALESCE(AVG(duration_ms), 0) as avg_duration,
-			COALESCE(SUM(CASE WHEN timestamp > datetime('now', '-1 day') AND success = 0 THEN 1 ELSE 0 END), 0) as errors_24h
+			COALESCE(SUM(CASE WHEN timestamp > datetime('now', '-1 day') AND success = 0 AND NOT cancelled THEN 1 ELSE 0 END), 0) as errors_24h,
+			COALESCE(SUM(CASE WHEN fallback != '' THEN 1 ELSE 0 END), 0) as fallbacks
 		FROM events 
 		WHERE timestamp > datetime('now', '-' || ? || ' days')`, days)
 
 	var stats UsageStats
 	var avgDuration float64
-	err := row.Scan(&stats.TotalCommands, &stats.SuccessRate, &avgDuration, &stats.ErrorsLast24h)
+	err := row.Scan(&stats.TotalCommands, &stats.SuccessRate, &avgDuration, &stats.ErrorsLast24h, &stats.Fallbacks)
 	if err != nil {
 		return nil, err
 	}
@@ -137,8 +193,9 @@ func (a *SQLiteAnalytics) GetStats(days int) (*UsageStats, error) {
 		SELECT 
 			command_name,
 			COUNT(*) as count,
-			COALESCE(AVG(CASE WHEN success THEN 1.0 ELSE 0.0 END), 0) * 100 as success_rate,
-			COALESCE(AVG(duration_ms), 0) as avg_duration
+			COALESCE(AVG(CASE WHEN cancelled THEN NULL WHEN success THEN 1.0 ELSE 0.0 END), 0) * 100 as success_rate,
+			COALESCE(AVG(duration_ms), 0) as avg_duration,
+			COALESCE(SUM(CASE WHEN fallback != '' THEN 1 ELSE 0 END), 0) as fallbacks
 		FROM events 
 		WHERE timestamp > datetime('now', '-' || ? || ' days')
 		GROUP BY command_name 
@@ -154,7 +211,7 @@ func (a *SQLiteAnalytics) GetStats(days int) (*UsageStats, error) {
 	for rows.Next() {
 		var cmd CommandSummary
 		var avgDur float64
-		err := rows.Scan(&cmd.Name, &cmd.Count, &cmd.SuccessRate, &avgDur)
+		err := rows.Scan(&cmd.Name, &cmd.Count, &cmd.SuccessRate, &avgDur, &cmd.Fallbacks)
 		if err != nil {
 			continue
 		}
@@ -267,9 +324,9 @@ func (a *SQLiteAnalytics) GetWebhookStats(days int) (*WebhookStats, error) {
 	row := a.db.QueryRow(`
 		SELECT 
 			COUNT(*) as total_calls,
-			COALESCE(AVG(CASE WHEN success THEN 1.0 ELSE 0.0 END), 0) as success_rate,
+			COALESCE(AVG(CASE WHEN cancelled THEN NULL WHEN success THEN 1.0 ELSE 0.0 END), 0) as success_rate,
 			COALESCE(AVG(duration_ms), 0) as avg_latency,
-			COALESCE(SUM(CASE WHEN timestamp > datetime('now', '-1 day') AND success = 0 THEN 1 ELSE 0 END), 0) as errors_24h
+			COALESCE(SUM(CASE WHEN timestamp > datetime('now', '-1 day') AND success = 0 AND NOT cancelled THEN 1 ELSE 0 END), 0) as errors_24h
 		FROM events 
 		WHERE timestamp > datetime('now', '-' || ? || ' days')
 		AND execution_mode = 'webhook'`, days)
@@ -289,7 +346,7 @@ func (a *SQLiteAnalytics) GetWebhookStats(days int) (*WebhookStats, error) {
 		SELECT 
 			command_name,
 			COUNT(*) as count,
-			COALESCE(AVG(CASE WHEN success THEN 1.0 ELSE 0.0 END), 0) * 100 as success_rate,
+			COALESCE(AVG(CASE WHEN cancelled THEN NULL WHEN success THEN 1.0 ELSE 0.0 END), 0) * 100 as success_rate,
 			COALESCE(AVG(duration_ms), 0) as avg_latency
 		FROM events 
 		WHERE timestamp > datetime('now', '-' || ? || ' days')
@@ -332,6 +389,7 @@ func (a *SQLiteAnalytics) GetWebhookStats(days int) (*WebhookStats, error) {
 		WHERE timestamp > datetime('now', '-1 day')
 		AND execution_mode = 'webhook'
 		AND success = 0
+		AND NOT cancelled
 		AND error_message != ''
 		GROUP BY error_type
 		ORDER BY count DESC`)
@@ -356,8 +414,8 @@ func (a *SQLiteAnalytics) Close() error {
 	return a.db.Close()
 }
 
-// resolvePath resolves ~ and relative paths to absolute paths
-func resolvePath(path string) (string, error) {
+// ResolvePath resolves ~ and relative database paths to absolute paths
+func ResolvePath(path string) (string, error) {
 	if path == "" {
 		return "", nil
 	}
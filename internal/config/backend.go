@@ -0,0 +1,160 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// backend is an execution backend, selected by its own section of a command.
+// Commands without any backend section run their script locally.
+type backend struct {
+	name      string
+	set       func(cmd *Command) bool
+	script    scriptUse                           // Whether the backend runs the command's script
+	artifacts bool                                // Whether the backend supports artifacts
+	pool      bool                                // Whether the backend supports warm pools
+	validate  func(c *Config, cmd *Command) error // Checks the backend's section; may be nil
+}
+
+// scriptUse says whether a backend needs, allows or rejects a script
+type scriptUse int
+
+const (
+	scriptOptional scriptUse = iota
+	scriptRequired
+	scriptRejected
+)
+
+// customBackend is the backend of commands run by a registered custom executor
+const customBackend = "executor"
+
+// backends lists every backend in dispatch order. Each built-in backend has an
+// executor of the same name in the executor package.
+var backends = []backend{
+	{name: "webhook", set: (*Command).IsWebhook, artifacts: true, validate: validateWebhook},
+	{name: "kubernetes", set: (*Command).IsKubernetes, validate: validateKubernetes},
+	{name: "container", set: (*Command).IsContainerized, artifacts: true, pool: true, validate: validateContainer},
+	{name: "ssh", set: (*Command).IsRemote, script: scriptRequired, validate: func(c *Config, cmd *Command) error {
+		return cmd.SSH.validate()
+	}},
+	{name: "sql", set: (*Command).IsSQL, script: scriptRejected, artifacts: true, validate: func(c *Config, cmd *Command) error {
+		return cmd.SQL.validate(cmd.Parameters)
+	}},
+	{name: "starlark", set: (*Command).IsStarlark, script: scriptRejected, artifacts: true, validate: func(c *Config, cmd *Command) error {
+		if err := cmd.Starlark.validate(); err != nil {
+			return err
+		}
+		return c.validateStarlark(cmd)
+	}},
+	{name: "wasm", set: (*Command).IsWasm, script: scriptRejected, artifacts: true, validate: func(c *Config, cmd *Command) error {
+		return cmd.Wasm.validate()
+	}},
+	{name: "sandbox", set: (*Command).IsSandboxed, script: scriptRequired, artifacts: true, validate: func(c *Config, cmd *Command) error {
+		return cmd.Sandbox.validate()
+	}},
+	{name: "plugin", set: (*Command).IsPlugin, script: scriptRejected, validate: validatePlugin},
+	{name: "workflow", set: (*Command).IsWorkflow, script: scriptRejected, validate: (*Config).validateWorkflow},
+	{name: customBackend, set: (*Command).IsCustom, validate: func(c *Config, cmd *Command) error {
+		return cmd.Executor.validate(cmd)
+	}},
+}
+
+// Backends returns the names of the built-in backends in dispatch order
+func Backends() []string {
+	var names []string
+	for _, b := range backends {
+		if b.name != customBackend {
+			names = append(names, b.name)
+		}
+	}
+	return names
+}
+
+// Backend returns the name of the built-in backend that runs the command, or ""
+// if it runs locally or on a custom executor
+func (c *Command) Backend() string {
+	for _, b := range backends {
+		if b.name != customBackend && b.set(c) {
+			return b.name
+		}
+	}
+	return ""
+}
+
+// validateBackend checks a command sets at most one backend section and validates
+// that backend
+func (c *Config) validateBackend(cmd *Command) error {
+	var set []backend
+	var names []string
+	for _, b := range backends {
+		if b.set(cmd) {
+			set = append(set, b)
+			names = append(names, b.name)
+		}
+	}
+	if len(set) == 0 {
+		if cmd.Pool != nil {
+			return fmt.Errorf("pool requires a container")
+		}
+		return nil
+	}
+	if len(set) > 1 {
+		return fmt.Errorf("only one of %s can be set", strings.Join(names, ", "))
+	}
+
+	b := set[0]
+	if b.script == scriptRequired && cmd.Script == "" {
+		return fmt.Errorf("%s requires a script", b.name)
+	}
+	if b.script == scriptRejected && cmd.Script != "" {
+		return fmt.Errorf("%s cannot be combined with script", b.name)
+	}
+	if !b.artifacts && len(cmd.Artifacts) > 0 {
+		return fmt.Errorf("artifacts are not supported with %s", b.name)
+	}
+	if !b.pool && cmd.Pool != nil {
+		return fmt.Errorf("pool is not supported with %s", b.name)
+	}
+	if b.validate == nil {
+		return nil
+	}
+	return b.validate(c, cmd)
+}
+
+// validateWebhook checks the webhook has somewhere to send requests
+func validateWebhook(c *Config, cmd *Command) error {
+	if cmd.Webhook.URL == "" {
+		return fmt.Errorf("webhook requires a url")
+	}
+	return nil
+}
+
+// validateKubernetes checks the Job settings and the container they run
+func validateKubernetes(c *Config, cmd *Command) error {
+	if cmd.Container == "" {
+		return fmt.Errorf("kubernetes requires a container")
+	}
+	return cmd.Kubernetes.validate(cmd.ContainerOptions)
+}
+
+// validateContainer checks the warm pool settings. Pooled calls are exec'd into
+// the keepalive container, so the image's default command never runs.
+func validateContainer(c *Config, cmd *Command) error {
+	if cmd.Pool == nil {
+		return nil
+	}
+	if cmd.Script == "" && len(cmd.Args) == 0 {
+		return fmt.Errorf("pool requires a script or args")
+	}
+	return cmd.Pool.validate()
+}
+
+// validatePlugin resolves the plugin binary
+func validatePlugin(c *Config, cmd *Command) error {
+	path, err := c.Plugins.resolve(cmd.Plugin)
+	if err != nil {
+		return err
+	}
+	cmd.PluginPath = path
+	return nil
+}
@@ -1,8 +1,11 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"time"
 
 	"gopkg.in/yaml.v2"
 )
@@ -11,24 +14,111 @@ import (
 type Command struct {
 	Name        string            `yaml:"name"`
 	Script      string            `yaml:"script"`
-	Args        []string          `yaml:"args"`
+	Args        []string          `yaml:"args" expand:"template"`
 	Description string            `yaml:"description"`
-	Container   string            `yaml:"container"`
+	Container   string            `yaml:"-"` // Image, set from ContainerOptions on load
 	Timeout     string            `yaml:"timeout"`
-	Env         map[string]string `yaml:"env"`
+	KillGrace   string            `yaml:"kill_grace"` // Time between SIGTERM and SIGKILL on timeout (default: 5s)
+	Env         map[string]string `yaml:"env" expand:"template"`
+	LogOutput   bool              `yaml:"log_output"` // Stream output lines as MCP log messages
+	Async       bool              `yaml:"async"`      // Return a job ID at once and run in the background
+	MaxOutputBytes int            `yaml:"max_output_bytes"` // Per-stream limit before truncation (default: output.max_output_bytes, -1: unlimited)
+	// Typed tool inputs exposed as the MCP inputSchema
+	Parameters  []Parameter       `yaml:"parameters,omitempty"`
+	// Container image and isolation settings; `container: image` is shorthand for the image
+	ContainerOptions *ContainerConfig `yaml:"container,omitempty"`
+	// Remote host the script runs on instead of the local machine
+	SSH         *SSHConfig        `yaml:"ssh,omitempty"`
+	// Parameterized query against a database/sql data source
+	SQL         *SQLConfig        `yaml:"sql,omitempty"`
+	// Starlark script run in-process as the tool body, for tools that combine API calls
+	Starlark    *StarlarkConfig   `yaml:"starlark,omitempty"`
+	// WebAssembly (WASI) module run in-process instead of a script
+	Wasm        *WasmConfig       `yaml:"wasm,omitempty"`
+	// Linux sandbox for local commands: namespaces, landlock, rlimits and seccomp
+	Sandbox     *SandboxConfig    `yaml:"sandbox,omitempty"`
+	// Run the container as a Kubernetes Job instead of with the container runtime
+	Kubernetes  *KubernetesConfig `yaml:"kubernetes,omitempty"`
+	// Warm container pool; calls exec into pre-started containers instead of starting one each
+	Pool        *PoolConfig       `yaml:"pool,omitempty"`
+	// Where to run a containerized command when its container cannot: none (default), local or a command name
+	Fallback    string            `yaml:"fallback"`
+	// Failures that trigger the fallback: unavailable, pull, timeout (default: all)
+	FallbackOn  []string          `yaml:"fallback_on,omitempty"`
 	// Webhook/API configuration
 	Webhook     *WebhookConfig    `yaml:"webhook,omitempty"`
+	// Custom executor registered by an embedding program, selected by its type
+	Executor    *ExecutorConfig   `yaml:"executor,omitempty"`
+	// External plugin binary in the plugin directory, spoken to over JSON on stdin/stdout
+	Plugin      string            `yaml:"plugin"`
+	// Steps running other configured commands, exposed as a single tool
+	Workflow    *WorkflowConfig   `yaml:"workflow,omitempty"`
+	// Files the command writes to its output directory, returned as tool result content
+	Artifacts   []Artifact        `yaml:"artifacts,omitempty"`
+	// Cron schedule the command also runs on, independent of any client
+	Schedule    *ScheduleConfig   `yaml:"schedule,omitempty"`
+
+	// Parsed at load time from Timeout and KillGrace
+	TimeoutDuration   time.Duration `yaml:"-"`
+	KillGraceDuration time.Duration `yaml:"-"`
+	// Resolved at load time when Fallback names another command
+	FallbackCommand *Command `yaml:"-"`
+	// Resolved at load time from Plugin
+	PluginPath string `yaml:"-"`
+}
+
+// DefaultKillGrace is how long a timed out process gets to exit after SIGTERM before SIGKILL
+const DefaultKillGrace = 5 * time.Second
+
+// Fallback policies; any other value names the command to run instead
+const (
+	FallbackNone  = "none"
+	FallbackLocal = "local"
+)
+
+// Container failures that can trigger a fallback
+const (
+	FailureUnavailable = "unavailable" // The container runtime cannot be reached
+	FailurePull        = "pull"        // The image cannot be pulled
+	FailureTimeout     = "timeout"     // The containerized run timed out
+)
+
+// DefaultFallbackOn lists the failures that trigger a fallback unless fallback_on is set
+var DefaultFallbackOn = []string{FailureUnavailable, FailurePull, FailureTimeout}
+
+// Artifact is a file produced by a command and returned as image, audio or resource content
+type Artifact struct {
+	Path     string `yaml:"path"`      // Relative to the run's output directory; may be a glob pattern
+	MIMEType string `yaml:"mime_type"` // Detected from the file contents when empty
 }
 
 // WebhookConfig represents webhook/API call configuration
 type WebhookConfig struct {
-	URL         string            `yaml:"url"`
-	Method      string            `yaml:"method"`      // GET, POST, PUT, DELETE, etc.
-	Headers     map[string]string `yaml:"headers"`     // Custom headers
-	Body        string            `yaml:"body"`        // Request body template
-	BodyFormat  string            `yaml:"body_format"` // json, xml, form, text
-	Auth        *WebhookAuth      `yaml:"auth,omitempty"`
-	Retry       *WebhookRetry     `yaml:"retry,omitempty"`
+	URL        string            `yaml:"url" expand:"template"`
+	Method     string            `yaml:"method"`                    // GET, POST, PUT, DELETE, etc.
+	Headers    map[string]string `yaml:"headers" expand:"template"` // Custom headers
+	Body       string            `yaml:"body" expand:"template"`    // Request body template
+	BodyFormat string            `yaml:"body_format"`               // json, xml, form, text
+	Auth       *WebhookAuth      `yaml:"auth,omitempty"`
+	Retry      *WebhookRetry     `yaml:"retry,omitempty"`
+	Client     *WebhookClient    `yaml:"client,omitempty"` // HTTP client tuning
+}
+
+// WebhookClient holds per-command HTTP client settings
+type WebhookClient struct {
+	Timeout             string `yaml:"timeout"`                 // Per-attempt timeout (default: 30s)
+	MaxIdleConns        int    `yaml:"max_idle_conns"`          // Default: 100
+	MaxIdleConnsPerHost int    `yaml:"max_idle_conns_per_host"` // Default: 10
+	MaxConnsPerHost     int    `yaml:"max_conns_per_host"`      // Default: unlimited
+	IdleConnTimeout     string `yaml:"idle_conn_timeout"`       // Keep-alive idle timeout (default: 90s)
+	DisableKeepAlives   bool   `yaml:"disable_keep_alives"`
+	FollowRedirects     *bool  `yaml:"follow_redirects"`  // Default: true
+	MaxRedirects        int    `yaml:"max_redirects"`     // Default: 10
+	MaxResponseSize     int64  `yaml:"max_response_size"` // Bytes (default: 10MB)
+
+	// Parsed at load time from Timeout and IdleConnTimeout
+	TimeoutDuration         time.Duration `yaml:"-"`
+	IdleConnTimeoutDuration time.Duration `yaml:"-"`
 }
 
 // WebhookAuth represents authentication for webhook calls
@@ -54,8 +144,27 @@ type Config struct {
 	Commands  []Command       `yaml:"commands"`
 	Server    ServerConfig    `yaml:"server"`
 	Analytics AnalyticsConfig `yaml:"analytics"`
+	Output    OutputConfig    `yaml:"output"`
+	ContainerRuntime ContainerRuntimeConfig `yaml:"container_runtime"`
+	Plugins   PluginsConfig   `yaml:"plugins"`
+	Jobs      JobsConfig      `yaml:"jobs"`
 }
 
+// OutputConfig controls how much command output is returned inline in tool results.
+// Output beyond the limit is truncated and kept as an mcpfier://runs/<id>/<stream> resource.
+type OutputConfig struct {
+	MaxOutputBytes int `yaml:"max_output_bytes"` // Default per-stream limit (default: 64KB, -1: unlimited)
+	PageSize       int `yaml:"page_size"`        // Bytes per resource page (default: 64KB)
+	MaxRuns        int `yaml:"max_runs"`         // Truncated runs kept for reading (default: 100)
+}
+
+// Output defaults
+const (
+	DefaultMaxOutputBytes = 64 * 1024
+	DefaultOutputPageSize = 64 * 1024
+	DefaultMaxRuns        = 100
+)
+
 // ServerConfig holds server configuration
 type ServerConfig struct {
 	HTTP HTTPConfig `yaml:"http"`
@@ -116,12 +225,150 @@ func Load(path string) (*Config, error) {
 		return nil, err
 	}
 
+	// Expand environment variable and secret file references
+	if err := expandConfig(reflect.ValueOf(&config), "", false); err != nil {
+		return nil, fmt.Errorf("config %s: %w", path, err)
+	}
+
 	// Apply defaults
 	config.applyDefaults()
+	if !filepath.IsAbs(config.Plugins.Dir) {
+		config.Plugins.Dir = filepath.Join(filepath.Dir(path), config.Plugins.Dir)
+	}
+
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
 
 	return &config, nil
 }
 
+// validate checks command definitions for errors that would otherwise only surface at call time
+func (c *Config) validate() error {
+	if err := c.ContainerRuntime.validate(); err != nil {
+		return fmt.Errorf("container_runtime: %w", err)
+	}
+	if err := c.Plugins.validate(); err != nil {
+		return fmt.Errorf("plugins: %w", err)
+	}
+	if err := c.Jobs.validate(); err != nil {
+		return fmt.Errorf("jobs: %w", err)
+	}
+	if err := c.validateJobTools(); err != nil {
+		return err
+	}
+
+	for i := range c.Commands {
+		cmd := &c.Commands[i]
+		if err := cmd.parseDurations(); err != nil {
+			return fmt.Errorf("command '%s': %w", cmd.Name, err)
+		}
+
+		if cmd.ContainerOptions != nil {
+			if err := cmd.ContainerOptions.validate(); err != nil {
+				return fmt.Errorf("command '%s': %w", cmd.Name, err)
+			}
+			cmd.Container = cmd.ContainerOptions.Image
+		}
+
+		if err := c.validateBackend(cmd); err != nil {
+			return fmt.Errorf("command '%s': %w", cmd.Name, err)
+		}
+
+		if err := c.validateFallback(cmd); err != nil {
+			return fmt.Errorf("command '%s': %w", cmd.Name, err)
+		}
+
+		if err := validateParameters(cmd.Parameters); err != nil {
+			return fmt.Errorf("command '%s': %w", cmd.Name, err)
+		}
+
+		if cmd.Schedule != nil {
+			if err := cmd.Schedule.validate(cmd); err != nil {
+				return fmt.Errorf("command '%s': %w", cmd.Name, err)
+			}
+		}
+
+		for _, artifact := range cmd.Artifacts {
+			if !filepath.IsLocal(artifact.Path) {
+				return fmt.Errorf("command '%s': artifact path '%s' must be relative to the output directory", cmd.Name, artifact.Path)
+			}
+			if _, err := filepath.Match(artifact.Path, ""); err != nil {
+				return fmt.Errorf("command '%s': invalid artifact pattern '%s': %w", cmd.Name, artifact.Path, err)
+			}
+		}
+	}
+	return c.checkWorkflowCycles()
+}
+
+// validateParameters checks parameter definitions and rejects duplicate names
+func validateParameters(params []Parameter) error {
+	seen := make(map[string]bool, len(params))
+	for _, param := range params {
+		if err := param.validate(); err != nil {
+			return err
+		}
+		if seen[param.Name] {
+			return fmt.Errorf("duplicate parameter '%s'", param.Name)
+		}
+		seen[param.Name] = true
+	}
+	return nil
+}
+
+// validateFallback checks the fallback policy and resolves a fallback command
+func (c *Config) validateFallback(cmd *Command) error {
+	if cmd.Fallback == "" || cmd.Fallback == FallbackNone {
+		if len(cmd.FallbackOn) > 0 {
+			return fmt.Errorf("fallback_on requires a fallback")
+		}
+		return nil
+	}
+	if cmd.Container == "" {
+		return fmt.Errorf("fallback requires a container")
+	}
+
+	if cmd.Fallback == FallbackLocal {
+		if cmd.Script == "" {
+			return fmt.Errorf("fallback to local requires a script")
+		}
+	} else {
+		for i := range c.Commands {
+			if c.Commands[i].Name == cmd.Fallback && &c.Commands[i] != cmd {
+				cmd.FallbackCommand = &c.Commands[i]
+			}
+		}
+		if cmd.FallbackCommand == nil {
+			return fmt.Errorf("fallback command '%s' not found", cmd.Fallback)
+		}
+	}
+
+	if len(cmd.FallbackOn) == 0 {
+		cmd.FallbackOn = DefaultFallbackOn
+	}
+	for _, failure := range cmd.FallbackOn {
+		switch failure {
+		case FailureUnavailable, FailurePull, FailureTimeout:
+		default:
+			return fmt.Errorf("unknown fallback_on failure '%s', expected unavailable, pull or timeout", failure)
+		}
+	}
+	return nil
+}
+
+// FallsBackOn reports whether a failure class triggers the command's fallback
+func (c *Command) FallsBackOn(failure string) bool {
+	if c.Fallback == "" || c.Fallback == FallbackNone {
+		return false
+	}
+	for _, f := range c.FallbackOn {
+		if f == failure {
+			return true
+		}
+	}
+	return false
+}
+
 // applyDefaults applies default values to the configuration
 func (c *Config) applyDefaults() {
 	// Server defaults
@@ -144,6 +391,34 @@ func (c *Config) applyDefaults() {
 	if c.Server.HTTP.CORS.Enabled && len(c.Server.HTTP.CORS.AllowedHeaders) == 0 {
 		c.Server.HTTP.CORS.AllowedHeaders = []string{"Authorization", "Content-Type", "X-API-Key"}
 	}
+
+	// Output defaults; commands without their own limit inherit the global one
+	if c.Output.MaxOutputBytes == 0 {
+		c.Output.MaxOutputBytes = DefaultMaxOutputBytes
+	}
+	if c.Output.PageSize <= 0 {
+		c.Output.PageSize = DefaultOutputPageSize
+	}
+	if c.Output.MaxRuns <= 0 {
+		c.Output.MaxRuns = DefaultMaxRuns
+	}
+
+	if c.Plugins.Dir == "" {
+		c.Plugins.Dir = DefaultPluginDir
+	}
+
+	// Jobs are kept in the analytics database unless configured otherwise
+	if c.Jobs.DatabasePath == "" {
+		c.Jobs.DatabasePath = c.Analytics.DatabasePath
+	}
+	if c.Jobs.DatabasePath == "" {
+		c.Jobs.DatabasePath = "./analytics.db"
+	}
+	for i := range c.Commands {
+		if c.Commands[i].MaxOutputBytes == 0 {
+			c.Commands[i].MaxOutputBytes = c.Output.MaxOutputBytes
+		}
+	}
 }
 
 // LoadFromDefaultPath loads configuration using the default search paths
@@ -192,6 +467,71 @@ func FindConfigFile() string {
 	return "config.yaml"
 }
 
+// parseDurations parses the timeout and kill grace period once so executors don't have to
+func (c *Command) parseDurations() error {
+	if c.Timeout != "" {
+		d, err := time.ParseDuration(c.Timeout)
+		if err != nil {
+			return fmt.Errorf("invalid timeout: %w", err)
+		}
+		if d < 0 {
+			return fmt.Errorf("invalid timeout: %s", c.Timeout)
+		}
+		c.TimeoutDuration = d
+	}
+
+	if c.KillGrace != "" {
+		d, err := time.ParseDuration(c.KillGrace)
+		if err != nil {
+			return fmt.Errorf("invalid kill_grace: %w", err)
+		}
+		if d <= 0 {
+			return fmt.Errorf("invalid kill_grace: %s", c.KillGrace)
+		}
+		c.KillGraceDuration = d
+	}
+
+	if c.Webhook != nil && c.Webhook.Client != nil {
+		if err := c.Webhook.Client.parseDurations(); err != nil {
+			return fmt.Errorf("invalid webhook client %w", err)
+		}
+	}
+	if c.Starlark != nil && c.Starlark.Client != nil {
+		if err := c.Starlark.Client.parseDurations(); err != nil {
+			return fmt.Errorf("invalid starlark client %w", err)
+		}
+	}
+
+	return nil
+}
+
+// parseDurations parses the client timeouts
+func (w *WebhookClient) parseDurations() error {
+	if w.Timeout != "" {
+		d, err := time.ParseDuration(w.Timeout)
+		if err != nil {
+			return fmt.Errorf("timeout: %w", err)
+		}
+		w.TimeoutDuration = d
+	}
+	if w.IdleConnTimeout != "" {
+		d, err := time.ParseDuration(w.IdleConnTimeout)
+		if err != nil {
+			return fmt.Errorf("idle_conn_timeout: %w", err)
+		}
+		w.IdleConnTimeoutDuration = d
+	}
+	return nil
+}
+
+// GetKillGrace returns the grace period between SIGTERM and SIGKILL
+func (c Command) GetKillGrace() time.Duration {
+	if c.KillGraceDuration > 0 {
+		return c.KillGraceDuration
+	}
+	return DefaultKillGrace
+}
+
 // GetDescription returns a description for the command
 func (c Command) GetDescription() string {
 	if c.Description != "" {
@@ -200,12 +540,58 @@ func (c Command) GetDescription() string {
 	return "Execute " + c.Name + " with configured arguments"
 }
 
-// IsContainerized returns true if the command should run in a container
+// IsContainerized returns true if the command should run in a container with the
+// container runtime; Kubernetes commands run their container as a Job instead
 func (c Command) IsContainerized() bool {
-	return c.Container != ""
+	return c.Container != "" && c.Kubernetes == nil
+}
+
+// IsRemote returns true if the command runs on a remote host over SSH
+func (c Command) IsRemote() bool {
+	return c.SSH != nil
+}
+
+// IsSQL returns true if the command runs a database query
+func (c Command) IsSQL() bool {
+	return c.SQL != nil
+}
+
+// IsStarlark returns true if the command runs a Starlark script
+func (c Command) IsStarlark() bool {
+	return c.Starlark != nil
+}
+
+// IsKubernetes returns true if the command's container runs as a Kubernetes Job
+func (c Command) IsKubernetes() bool {
+	return c.Kubernetes != nil
+}
+
+// IsWasm returns true if the command runs a WebAssembly module
+func (c Command) IsWasm() bool {
+	return c.Wasm != nil
+}
+
+// IsSandboxed returns true if the command should run locally in a Linux sandbox
+func (c Command) IsSandboxed() bool {
+	return c.Sandbox != nil
 }
 
 // IsWebhook returns true if the command is a webhook/API call
 func (c Command) IsWebhook() bool {
-	return c.Webhook != nil && c.Webhook.URL != ""
-}
\ No newline at end of file
+	return c.Webhook != nil
+}
+
+// IsPlugin returns true if the command runs an external executor plugin
+func (c Command) IsPlugin() bool {
+	return c.Plugin != ""
+}
+
+// IsWorkflow returns true if the command runs a workflow of other commands
+func (c Command) IsWorkflow() bool {
+	return c.Workflow != nil
+}
+
+// IsCustom returns true if the command runs on a registered custom executor
+func (c Command) IsCustom() bool {
+	return c.Executor != nil
+}
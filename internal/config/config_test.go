@@ -1,8 +1,15 @@
 package config
 
 import (
+	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestLoadConfig(t *testing.T) {
@@ -62,9 +69,18 @@ commands:
 	if command.Timeout != "30s" {
 		t.Errorf("Expected timeout '30s', got '%s'", command.Timeout)
 	}
+	if command.TimeoutDuration != 30*time.Second {
+		t.Errorf("Expected parsed timeout 30s, got %s", command.TimeoutDuration)
+	}
+	if command.GetKillGrace() != DefaultKillGrace {
+		t.Errorf("Expected default kill grace, got %s", command.GetKillGrace())
+	}
 	if command.Env["TEST_VAR"] != "test_value" {
 		t.Errorf("Expected env var TEST_VAR='test_value', got '%s'", command.Env["TEST_VAR"])
 	}
+	if command.MaxOutputBytes != DefaultMaxOutputBytes {
+		t.Errorf("Expected command to inherit default max_output_bytes, got %d", command.MaxOutputBytes)
+	}
 }
 
 func TestCommandMethods(t *testing.T) {
@@ -94,4 +110,1243 @@ func TestCommandMethods(t *testing.T) {
 	if cmd.IsContainerized() {
 		t.Error("Expected command to not be containerized")
 	}
-}
\ No newline at end of file
+}
+
+func TestResolveArguments(t *testing.T) {
+	cmd := Command{
+		Name: "weather",
+		Parameters: []Parameter{
+			{Name: "city", Type: "string", Required: true},
+			{Name: "days", Type: "number", Default: 3},
+			{Name: "metric", Type: "boolean"},
+			{Name: "format", Type: "enum", Values: []string{"short", "long"}, Default: "short"},
+			{Name: "tags", Type: "array", Items: "string"},
+		},
+	}
+
+	params, err := cmd.ResolveArguments(map[string]interface{}{
+		"city": "Lisbon",
+		"tags": []interface{}{"a", "b"},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if params["city"] != "Lisbon" {
+		t.Errorf("Expected city 'Lisbon', got %v", params["city"])
+	}
+	if params["days"] != float64(3) {
+		t.Errorf("Expected default days 3, got %v", params["days"])
+	}
+	if params["format"] != "short" {
+		t.Errorf("Expected default format 'short', got %v", params["format"])
+	}
+	if _, ok := params["metric"]; ok {
+		t.Error("Expected optional parameter without default to be absent")
+	}
+
+	invalid := []map[string]interface{}{
+		{},                                        // missing required
+		{"city": 42.0},                            // wrong type
+		{"city": "x", "format": "verbose"},        // not in enum
+		{"city": "x", "tags": []interface{}{1.0}}, // wrong item type
+		{"city": "x", "unknown": "y"},             // unknown argument
+	}
+	for i, args := range invalid {
+		if _, err := cmd.ResolveArguments(args); err == nil {
+			t.Errorf("Case %d: expected validation error for %v", i, args)
+		}
+	}
+}
+
+func TestLoadConfigInvalidParameters(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "config.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmpfile.Name())
+
+	content := `
+commands:
+  - name: bad
+    script: echo
+    parameters:
+      - name: mode
+        type: enum
+`
+	if _, err := tmpfile.Write([]byte(content)); err != nil {
+		t.Fatal(err)
+	}
+	tmpfile.Close()
+
+	if _, err := Load(tmpfile.Name()); err == nil {
+		t.Error("Expected error for enum parameter without values")
+	}
+
+	os.WriteFile(tmpfile.Name(), []byte(`
+commands:
+  - name: bad
+    script: echo
+    timeout: "ten seconds"
+`), 0644)
+	if _, err := Load(tmpfile.Name()); err == nil {
+		t.Error("Expected error for invalid timeout")
+	}
+
+	os.WriteFile(tmpfile.Name(), []byte(`
+commands:
+  - name: bad
+    script: echo
+    artifacts:
+      - path: "../etc/passwd"
+`), 0644)
+	if _, err := Load(tmpfile.Name()); err == nil {
+		t.Error("Expected error for artifact path outside the output directory")
+	}
+
+	os.WriteFile(tmpfile.Name(), []byte(`
+commands:
+  - name: bad
+    script: python
+    sandbox:
+      read: [./scripts]
+`), 0644)
+	if _, err := Load(tmpfile.Name()); err == nil {
+		t.Error("Expected error for relative sandbox path")
+	}
+
+	os.WriteFile(tmpfile.Name(), []byte(`
+commands:
+  - name: bad
+    script: python
+    wasm:
+      module: tool.wasm
+`), 0644)
+	if _, err := Load(tmpfile.Name()); err == nil {
+		t.Error("Expected error for wasm combined with a script")
+	}
+}
+
+func TestLoadConfigExpansion(t *testing.T) {
+	secret, err := os.CreateTemp("", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(secret.Name())
+	secret.WriteString("s3cr3t\n")
+	secret.Close()
+
+	t.Setenv("MCPFIER_TEST_TOKEN", "abc123")
+	t.Setenv("MCPFIER_TEST_EMPTY", "")
+	t.Setenv("MCPFIER_TEST_TEMPLATE", "{{.params.path}}")
+
+	tmpfile, err := os.CreateTemp("", "config.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmpfile.Name())
+
+	content := `
+commands:
+  - name: api
+    env:
+      REGION: "${MCPFIER_TEST_EMPTY:-eu-west-1}"
+      PATTERN: "${MCPFIER_TEST_TEMPLATE}"
+    webhook:
+      url: "https://example.com/${MCPFIER_TEST_UNSET:-v1}/items?literal=$${NOT_EXPANDED}"
+      auth:
+        type: bearer
+        token: "${MCPFIER_TEST_TOKEN}"
+      headers:
+        X-Secret: "${file:` + secret.Name() + `}"
+  - name: query
+    sql:
+      dsn: "${MCPFIER_TEST_EMPTY:-app.db}"
+      query: "SELECT '${MCPFIER_TEST_UNSET}'"
+  - name: script
+    starlark:
+      source: "def main(params): return '${MCPFIER_TEST_UNSET}'"
+`
+	tmpfile.WriteString(content)
+	tmpfile.Close()
+
+	config, err := Load(tmpfile.Name())
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	cmd := config.Commands[0]
+	if cmd.Webhook.Auth.Token != "abc123" {
+		t.Errorf("Expected expanded token 'abc123', got '%s'", cmd.Webhook.Auth.Token)
+	}
+	if cmd.Env["REGION"] != "eu-west-1" {
+		t.Errorf("Expected default 'eu-west-1', got '%s'", cmd.Env["REGION"])
+	}
+	if cmd.Webhook.URL != "https://example.com/v1/items?literal=${NOT_EXPANDED}" {
+		t.Errorf("Unexpected URL '%s'", cmd.Webhook.URL)
+	}
+	if cmd.Webhook.Headers["X-Secret"] != "s3cr3t" {
+		t.Errorf("Expected secret from file, got '%s'", cmd.Webhook.Headers["X-Secret"])
+	}
+	// Values substituted into templated fields cannot inject template actions
+	if cmd.Env["PATTERN"] != `{{"{{"}}.params.path}}` {
+		t.Errorf("Expected escaped template delimiters, got '%s'", cmd.Env["PATTERN"])
+	}
+	// Queries and scripts are code and keep ${ as written
+	if query := config.Commands[1].SQL; query.DSN != "app.db" || query.Query != "SELECT '${MCPFIER_TEST_UNSET}'" {
+		t.Errorf("Expected only the DSN to be expanded, got %+v", query)
+	}
+	if source := config.Commands[2].Starlark.Source; source != "def main(params): return '${MCPFIER_TEST_UNSET}'" {
+		t.Errorf("Expected Starlark source as written, got %q", source)
+	}
+
+	// A required variable that is unset fails loading
+	tmpfile, err = os.CreateTemp("", "config.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmpfile.Name())
+	tmpfile.WriteString(`
+commands:
+  - name: api
+    webhook:
+      url: "https://example.com"
+      auth:
+        type: bearer
+        token: "${MCPFIER_TEST_UNSET}"
+`)
+	tmpfile.Close()
+
+	_, err = Load(tmpfile.Name())
+	if err == nil || !strings.Contains(err.Error(), "MCPFIER_TEST_UNSET") {
+		t.Errorf("Expected error naming the unset variable, got %v", err)
+	}
+}
+
+func TestLoadConfigContainerBlock(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "config.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmpfile.Name())
+
+	tmpfile.WriteString(`
+commands:
+  - name: sandboxed
+    script: python
+    container:
+      image: python:3.12-slim
+      memory: 256m
+      cpus: "0.5"
+      network: none
+      user: "1000:1000"
+      read_only: true
+      cap_drop: [ALL]
+      mounts:
+        - source: /srv/data
+          target: /data
+          read_only: true
+  - name: plain
+    script: echo
+    container: alpine:3
+    pool:
+      size: 2
+      idle_timeout: 30s
+`)
+	tmpfile.Close()
+
+	config, err := Load(tmpfile.Name())
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	sandboxed := config.Commands[0]
+	if sandboxed.Container != "python:3.12-slim" || !sandboxed.IsContainerized() {
+		t.Errorf("Expected image from container block, got '%s'", sandboxed.Container)
+	}
+	opts := sandboxed.ContainerOptions
+	if opts.Memory != "256m" || opts.Network != "none" || !opts.ReadOnly || len(opts.Mounts) != 1 || !opts.Mounts[0].ReadOnly {
+		t.Errorf("Unexpected container options: %+v", opts)
+	}
+	if config.Commands[1].Container != "alpine:3" {
+		t.Errorf("Expected plain image string to keep working, got '%s'", config.Commands[1].Container)
+	}
+	pool := config.Commands[1].Pool
+	if pool.Size != 2 || pool.MaxUses != DefaultPoolMaxUses || pool.IdleTimeoutDuration != 30*time.Second || len(pool.Keepalive) == 0 {
+		t.Errorf("Unexpected pool settings: %+v", pool)
+	}
+
+	os.WriteFile(tmpfile.Name(), []byte(`
+commands:
+  - name: bad
+    container:
+      image: alpine
+      memory: lots
+`), 0644)
+	if _, err := Load(tmpfile.Name()); err == nil {
+		t.Error("Expected error for invalid memory limit")
+	}
+
+	os.WriteFile(tmpfile.Name(), []byte(`
+commands:
+  - name: bad
+    script: echo
+    pool:
+      size: 2
+`), 0644)
+	if _, err := Load(tmpfile.Name()); err == nil {
+		t.Error("Expected error for pool without a container")
+	}
+
+	os.WriteFile(tmpfile.Name(), []byte(`
+commands:
+  - name: bad
+    container: alpine
+    pool:
+      size: 2
+`), 0644)
+	if _, err := Load(tmpfile.Name()); err == nil || !strings.Contains(err.Error(), "pool requires a script") {
+		t.Errorf("Expected error for pool without a script, got %v", err)
+	}
+}
+
+func TestLoadConfigFallback(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "config.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmpfile.Name())
+
+	tmpfile.WriteString(`
+commands:
+  - name: render
+    script: convert
+    container: imagemagick
+    fallback: local
+  - name: render-remote
+    container: renderer
+    fallback: render-api
+    fallback_on: [unavailable]
+  - name: render-api
+    webhook:
+      url: https://render.example.com
+`)
+	tmpfile.Close()
+
+	config, err := Load(tmpfile.Name())
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	render := config.Commands[0]
+	if !render.FallsBackOn(FailureTimeout) || !render.FallsBackOn(FailurePull) {
+		t.Errorf("Expected fallback on all failures by default, got %v", render.FallbackOn)
+	}
+	remote := config.Commands[1]
+	if remote.FallbackCommand == nil || remote.FallbackCommand.Name != "render-api" {
+		t.Errorf("Expected fallback command to be resolved, got %+v", remote.FallbackCommand)
+	}
+	if remote.FallsBackOn(FailureTimeout) || !remote.FallsBackOn(FailureUnavailable) {
+		t.Errorf("Expected fallback only when unavailable, got %v", remote.FallbackOn)
+	}
+
+	invalid := map[string]string{
+		"fallback without a container": `
+commands:
+  - name: bad
+    script: echo
+    fallback: local
+`,
+		"unknown fallback command": `
+commands:
+  - name: bad
+    script: echo
+    container: alpine
+    fallback: missing
+`,
+		"unknown failure class": `
+commands:
+  - name: bad
+    script: echo
+    container: alpine
+    fallback: local
+    fallback_on: [crash]
+`,
+	}
+	for name, content := range invalid {
+		os.WriteFile(tmpfile.Name(), []byte(content), 0644)
+		if _, err := Load(tmpfile.Name()); err == nil {
+			t.Errorf("Expected error for %s", name)
+		}
+	}
+}
+
+func TestLoadConfigStarlark(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "config.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmpfile.Name())
+
+	t.Setenv("MCPFIER_TEST_TOKEN", "secret")
+	tmpfile.WriteString(`
+commands:
+  - name: lookup
+    starlark:
+      source: |
+        def main(params):
+            return http.get("https://api.example.com", auth="api").body
+      auth:
+        api:
+          type: bearer
+          token: ${MCPFIER_TEST_TOKEN}
+      client:
+        timeout: 5s
+      commands: [echo]
+  - name: echo
+    script: echo
+`)
+	tmpfile.Close()
+
+	config, err := Load(tmpfile.Name())
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	lookup := config.Commands[0]
+	if !lookup.IsStarlark() || lookup.Starlark.MaxSteps != DefaultStarlarkMaxSteps {
+		t.Errorf("Expected starlark command with default step limit, got %+v", lookup.Starlark)
+	}
+	if lookup.Starlark.Auth["api"].Token != "secret" {
+		t.Errorf("Expected named auth to be expanded, got %q", lookup.Starlark.Auth["api"].Token)
+	}
+	if lookup.Starlark.Client.TimeoutDuration != 5*time.Second {
+		t.Errorf("Expected client timeout to be parsed, got %v", lookup.Starlark.Client.TimeoutDuration)
+	}
+	if lookup.Starlark.CommandRefs["echo"] != &config.Commands[1] {
+		t.Errorf("Expected run() command to be resolved, got %+v", lookup.Starlark.CommandRefs)
+	}
+
+	invalid := map[string]string{
+		"source and file": `
+commands:
+  - name: bad
+    starlark:
+      source: "def main(params): pass"
+      file: tool.star
+`,
+		"starlark with a script": `
+commands:
+  - name: bad
+    script: echo
+    starlark:
+      source: "def main(params): pass"
+`,
+		"unknown run command": `
+commands:
+  - name: bad
+    starlark:
+      source: "def main(params): pass"
+      commands: [missing]
+`,
+	}
+	for name, content := range invalid {
+		os.WriteFile(tmpfile.Name(), []byte(content), 0644)
+		if _, err := Load(tmpfile.Name()); err == nil {
+			t.Errorf("Expected error for %s", name)
+		}
+	}
+}
+
+func TestLoadConfigSQL(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "config.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmpfile.Name())
+
+	tmpfile.WriteString(`
+commands:
+  - name: find-user
+    parameters:
+      - name: email
+        type: string
+        required: true
+    sql:
+      dsn: /var/lib/app.db
+      query: SELECT id, name FROM users WHERE email = :email
+      read_only: true
+      timeout: 2s
+`)
+	tmpfile.Close()
+
+	config, err := Load(tmpfile.Name())
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	query := config.Commands[0].SQL
+	if !config.Commands[0].IsSQL() || query.Driver != DefaultSQLDriver || query.Format != SQLFormatTable || query.MaxRows != DefaultSQLMaxRows {
+		t.Errorf("Expected sql defaults to be applied, got %+v", query)
+	}
+	if query.TimeoutDuration != 2*time.Second {
+		t.Errorf("Expected query timeout to be parsed, got %v", query.TimeoutDuration)
+	}
+
+	invalid := map[string]string{
+		"missing query": `
+commands:
+  - name: bad
+    sql:
+      dsn: app.db
+`,
+		"unknown format": `
+commands:
+  - name: bad
+    sql:
+      dsn: app.db
+      query: SELECT 1
+      format: xml
+`,
+		"undeclared bind parameter": `
+commands:
+  - name: bad
+    sql:
+      dsn: app.db
+      query: SELECT * FROM users WHERE id = ?
+      bind: [id]
+`,
+	}
+	for name, content := range invalid {
+		os.WriteFile(tmpfile.Name(), []byte(content), 0644)
+		if _, err := Load(tmpfile.Name()); err == nil {
+			t.Errorf("Expected error for %s", name)
+		}
+	}
+}
+
+func TestLoadConfigSSH(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "config.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmpfile.Name())
+
+	tmpfile.WriteString(`
+commands:
+  - name: nginx-status
+    script: systemctl
+    args: ["status", "nginx"]
+    ssh:
+      host: web1.internal
+      user: ops
+      key_file: /etc/mcpfier/id_ed25519
+      jump:
+        host: bastion.example.com
+        port: 2222
+        user: ops
+        agent: true
+        connect_timeout: 3s
+`)
+	tmpfile.Close()
+
+	config, err := Load(tmpfile.Name())
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	remote := config.Commands[0].SSH
+	if !config.Commands[0].IsRemote() || remote.Port != DefaultSSHPort || remote.ConnectTimeoutDuration != DefaultSSHConnectTimeout {
+		t.Errorf("Expected ssh defaults to be applied, got %+v", remote)
+	}
+	if remote.Jump.Port != 2222 || remote.Jump.ConnectTimeoutDuration != 3*time.Second {
+		t.Errorf("Expected jump host settings to be parsed, got %+v", remote.Jump)
+	}
+
+	invalid := map[string]string{
+		"no authentication": `
+commands:
+  - name: bad
+    script: uptime
+    ssh:
+      host: web1
+      user: ops
+`,
+		"ssh without a script": `
+commands:
+  - name: bad
+    ssh:
+      host: web1
+      user: ops
+      agent: true
+`,
+		"invalid jump host": `
+commands:
+  - name: bad
+    script: uptime
+    ssh:
+      host: web1
+      user: ops
+      agent: true
+      jump:
+        host: bastion
+`,
+	}
+	for name, content := range invalid {
+		os.WriteFile(tmpfile.Name(), []byte(content), 0644)
+		if _, err := Load(tmpfile.Name()); err == nil {
+			t.Errorf("Expected error for %s", name)
+		}
+	}
+}
+
+func TestLoadConfigKubernetes(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "config.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmpfile.Name())
+
+	tmpfile.WriteString(`
+commands:
+  - name: report
+    script: /bin/report
+    container:
+      image: registry.internal/report:1.4
+      memory: 256m
+      user: "1000"
+    kubernetes:
+      context: prod
+      namespace: tools
+      ttl_after_finished: 1h
+  - name: in-pod
+    container: alpine:3
+    kubernetes:
+      in_cluster: true
+`)
+	tmpfile.Close()
+
+	config, err := Load(tmpfile.Name())
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if !config.Commands[0].IsKubernetes() || config.Commands[0].Kubernetes.TTLAfterFinishedDuration != time.Hour {
+		t.Errorf("Expected kubernetes settings to be parsed, got %+v", config.Commands[0].Kubernetes)
+	}
+	if config.Commands[1].Kubernetes.TTLAfterFinishedDuration != DefaultKubernetesTTL {
+		t.Errorf("Expected default TTL, got %s", config.Commands[1].Kubernetes.TTLAfterFinishedDuration)
+	}
+
+	invalid := map[string]string{
+		"kubernetes without a container": `
+commands:
+  - name: bad
+    script: uptime
+    kubernetes:
+      namespace: tools
+`,
+		"in_cluster with a context": `
+commands:
+  - name: bad
+    container: alpine:3
+    kubernetes:
+      in_cluster: true
+      context: prod
+`,
+		"non-numeric user": `
+commands:
+  - name: bad
+    container:
+      image: alpine:3
+      user: nobody
+    kubernetes: {}
+`,
+		"host mounts": `
+commands:
+  - name: bad
+    container:
+      image: alpine:3
+      mounts:
+        - source: /data
+          target: /data
+    kubernetes: {}
+`,
+		"invalid ttl": `
+commands:
+  - name: bad
+    container: alpine:3
+    kubernetes:
+      ttl_after_finished: soon
+`,
+	}
+	for name, content := range invalid {
+		os.WriteFile(tmpfile.Name(), []byte(content), 0644)
+		if _, err := Load(tmpfile.Name()); err == nil {
+			t.Errorf("Expected error for %s", name)
+		}
+	}
+}
+
+// executorTypeCount numbers the executor types registered by tests
+var executorTypeCount atomic.Int64
+
+// uniqueExecutorType returns a new executor type name on every call, since
+// registrations last as long as the test binary, e.g. across -count runs
+func uniqueExecutorType(base string) string {
+	return fmt.Sprintf("%s-%d", base, executorTypeCount.Add(1))
+}
+
+func TestLoadConfigExecutor(t *testing.T) {
+	queueType := uniqueExecutorType("test-queue")
+	RegisterExecutorType(queueType, func(cmd *Command) error {
+		var settings struct {
+			Queue string   `yaml:"queue"`
+			Tags  []string `yaml:"tags"`
+		}
+		if err := cmd.Executor.Decode(&settings); err != nil {
+			return err
+		}
+		if settings.Queue == "" {
+			return errors.New("queue is required")
+		}
+		return nil
+	})
+	os.Setenv("TEST_QUEUE", "jobs")
+	defer os.Unsetenv("TEST_QUEUE")
+
+	tmpfile, err := os.CreateTemp("", "config.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmpfile.Name())
+
+	tmpfile.WriteString(`
+commands:
+  - name: enqueue
+    script: report
+    executor:
+      type: ` + queueType + `
+      queue: ${TEST_QUEUE}
+      tags: ["${TEST_QUEUE}"]
+`)
+	tmpfile.Close()
+
+	config, err := Load(tmpfile.Name())
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	cmd := config.Commands[0]
+	if !cmd.IsCustom() || cmd.Executor.Type != queueType {
+		t.Errorf("Expected executor block to be parsed, got %+v", cmd.Executor)
+	}
+	var settings struct {
+		Queue string   `yaml:"queue"`
+		Tags  []string `yaml:"tags"`
+	}
+	if err := cmd.Executor.Decode(&settings); err != nil || settings.Queue != "jobs" || len(settings.Tags) != 1 || settings.Tags[0] != "jobs" {
+		t.Errorf("Expected expanded executor settings, got %+v (%v)", settings, err)
+	}
+
+	invalid := map[string]string{
+		"unknown executor type": `
+commands:
+  - name: bad
+    executor:
+      type: nomad
+`,
+		"failed executor validation": `
+commands:
+  - name: bad
+    executor:
+      type: ` + queueType + `
+`,
+		"executor with a container": `
+commands:
+  - name: bad
+    container: alpine:3
+    executor:
+      type: ` + queueType + `
+      queue: jobs
+`,
+	}
+	for name, content := range invalid {
+		os.WriteFile(tmpfile.Name(), []byte(content), 0644)
+		if _, err := Load(tmpfile.Name()); err == nil {
+			t.Errorf("Expected error for %s", name)
+		}
+	}
+}
+
+func TestLoadConfigPlugin(t *testing.T) {
+	dir := t.TempDir()
+	os.Mkdir(filepath.Join(dir, "plugins"), 0755)
+	binary := "vault-tool"
+	if runtime.GOOS == "windows" {
+		binary += ".exe"
+	}
+	os.WriteFile(filepath.Join(dir, "plugins", binary), []byte("#!/bin/sh\n"), 0755)
+
+	path := filepath.Join(dir, "config.yaml")
+	os.WriteFile(path, []byte(`
+commands:
+  - name: read-secret
+    plugin: vault-tool
+`), 0644)
+
+	config, err := Load(path)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	cmd := config.Commands[0]
+	if !cmd.IsPlugin() || cmd.PluginPath != filepath.Join(dir, "plugins", binary) {
+		t.Errorf("Expected plugin to be resolved in the default plugin directory, got %q", cmd.PluginPath)
+	}
+	if config.Plugins.DescribeTimeoutDuration != DefaultPluginDescribeTimeout {
+		t.Errorf("Expected default describe timeout, got %s", config.Plugins.DescribeTimeoutDuration)
+	}
+
+	if err := cmd.ApplyPluginDescription("Read a secret", []Parameter{{Name: "path"}, {Name: "path"}}); err == nil {
+		t.Error("Expected duplicate advertised parameters to be rejected")
+	}
+	if err := cmd.ApplyPluginDescription("Read a secret", []Parameter{{Name: "path", Required: true}}); err != nil || cmd.Description != "Read a secret" || len(cmd.Parameters) != 1 {
+		t.Errorf("Expected advertised description and parameters to be applied, got %v", err)
+	}
+
+	invalid := map[string]string{
+		"missing plugin": `
+commands:
+  - name: bad
+    plugin: nope
+`,
+		"plugin path outside the plugin directory": `
+commands:
+  - name: bad
+    plugin: ../plugins/vault-tool
+`,
+		"plugin with a script": `
+commands:
+  - name: bad
+    script: echo
+    plugin: vault-tool
+`,
+		"invalid describe timeout": `
+plugins:
+  describe_timeout: soon
+commands:
+  - name: bad
+    plugin: vault-tool
+`,
+	}
+	for name, content := range invalid {
+		os.WriteFile(path, []byte(content), 0644)
+		if _, err := Load(path); err == nil {
+			t.Errorf("Expected error for %s", name)
+		}
+	}
+}
+
+func TestLoadConfigWorkflow(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	os.WriteFile(path, []byte(`
+commands:
+  - name: fetch
+    script: curl
+    parameters:
+      - name: url
+  - name: store
+    script: cp
+  - name: undo
+    script: rm
+  - name: sync
+    workflow:
+      steps:
+        - command: fetch
+          params:
+            url: "{{.params.url}}"
+        - parallel:
+            - id: first
+              command: store
+              on_failure: compensate
+              compensate:
+                command: undo
+            - id: second
+              command: store
+              on_failure: continue
+`), 0644)
+
+	config, err := Load(path)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	cmd := config.Commands[3]
+	if !cmd.IsWorkflow() {
+		t.Fatal("Expected a workflow command")
+	}
+	fetch := cmd.Workflow.Steps[0]
+	if fetch.ID != "fetch" || fetch.OnFailure != OnFailureAbort || fetch.CommandRef != &config.Commands[0] {
+		t.Errorf("Expected step id and policy defaults and a resolved command, got %+v", fetch)
+	}
+	first := cmd.Workflow.Steps[1].Parallel[0]
+	if first.Compensate.ID != "undo" || first.Compensate.CommandRef != &config.Commands[2] {
+		t.Errorf("Expected the compensating step to be resolved, got %+v", first.Compensate)
+	}
+
+	invalid := map[string]string{
+		"no steps": `
+commands:
+  - name: bad
+    workflow: {}
+`,
+		"unknown command": `
+commands:
+  - name: bad
+    workflow:
+      steps:
+        - command: nope
+`,
+		"runs itself": `
+commands:
+  - name: bad
+    workflow:
+      steps:
+        - command: bad
+`,
+		"cycle through another workflow": `
+commands:
+  - name: a
+    workflow:
+      steps:
+        - command: b
+  - name: b
+    workflow:
+      steps:
+        - command: a
+`,
+		"duplicate step id": `
+commands:
+  - name: echo
+    script: echo
+  - name: bad
+    workflow:
+      steps:
+        - command: echo
+        - command: echo
+`,
+		"undeclared param": `
+commands:
+  - name: echo
+    script: echo
+  - name: bad
+    workflow:
+      steps:
+        - command: echo
+          params:
+            text: hi
+`,
+		"unknown on_failure": `
+commands:
+  - name: echo
+    script: echo
+  - name: bad
+    workflow:
+      steps:
+        - command: echo
+          on_failure: retry
+`,
+		"compensate without a step": `
+commands:
+  - name: echo
+    script: echo
+  - name: bad
+    workflow:
+      steps:
+        - command: echo
+          on_failure: compensate
+`,
+		"nested parallel": `
+commands:
+  - name: echo
+    script: echo
+  - name: bad
+    workflow:
+      steps:
+        - parallel:
+            - parallel:
+                - command: echo
+`,
+		"workflow with a script": `
+commands:
+  - name: echo
+    script: echo
+  - name: bad
+    script: echo
+    workflow:
+      steps:
+        - command: echo
+`,
+	}
+	for name, content := range invalid {
+		os.WriteFile(path, []byte(content), 0644)
+		if _, err := Load(path); err == nil {
+			t.Errorf("Expected error for %s", name)
+		}
+	}
+}
+
+func TestLoadConfigBackends(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+
+	tests := map[string]struct {
+		content string
+		want    string
+	}{
+		"two backend sections": {`
+commands:
+  - name: bad
+    script: curl
+    container: alpine:3
+    webhook:
+      url: https://example.com
+`, "command 'bad': only one of webhook, container can be set"},
+		"three backend sections": {`
+commands:
+  - name: bad
+    sql:
+      query: SELECT 1
+    wasm:
+      module: tool.wasm
+    starlark:
+      source: "def main(params): return 1"
+`, "only one of sql, starlark, wasm can be set"},
+		"backend requiring a script": {`
+commands:
+  - name: bad
+    sandbox: {}
+`, "command 'bad': sandbox requires a script"},
+		"backend rejecting a script": {`
+commands:
+  - name: bad
+    script: cat
+    wasm:
+      module: tool.wasm
+`, "command 'bad': wasm cannot be combined with script"},
+		"backend without artifacts": {`
+commands:
+  - name: bad
+    script: uptime
+    ssh:
+      host: example.com
+      user: ops
+    artifacts:
+      - path: out.txt
+`, "command 'bad': artifacts are not supported with ssh"},
+		"backend without pools": {`
+commands:
+  - name: bad
+    container: alpine:3
+    script: uptime
+    kubernetes: {}
+    pool:
+      size: 2
+`, "command 'bad': pool is not supported with kubernetes"},
+		"pool without a backend": {`
+commands:
+  - name: bad
+    script: uptime
+    pool:
+      size: 2
+`, "command 'bad': pool requires a container"},
+		"kubernetes without a container": {`
+commands:
+  - name: bad
+    script: uptime
+    kubernetes: {}
+`, "command 'bad': kubernetes requires a container"},
+		"kubernetes with another backend": {`
+commands:
+  - name: bad
+    script: uptime
+    container: alpine:3
+    kubernetes: {}
+    ssh:
+      host: example.com
+      user: ops
+`, "command 'bad': only one of kubernetes, ssh can be set"},
+		"webhook without a url": {`
+commands:
+  - name: bad
+    webhook:
+      method: POST
+`, "command 'bad': webhook requires a url"},
+		"backend validation": {`
+commands:
+  - name: bad
+    sql:
+      dsn: app.db
+      query: SELECT 1
+      format: xml
+`, "command 'bad': invalid sql format 'xml'"},
+	}
+	for name, tt := range tests {
+		os.WriteFile(path, []byte(tt.content), 0644)
+		if _, err := Load(path); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected error containing %q, got %v", name, tt.want, err)
+		}
+	}
+}
+
+func TestLoadConfigJobs(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	os.WriteFile(path, []byte(`
+analytics:
+  database_path: /var/lib/mcpfier/analytics.db
+commands:
+  - name: build
+    script: make
+    async: true
+  - name: lint
+    script: make
+`), 0644)
+
+	config, err := Load(path)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if !config.HasAsync() || !config.Commands[0].Async || config.Commands[1].Async {
+		t.Error("Expected only the build command to be async")
+	}
+	if config.Jobs.DatabasePath != "/var/lib/mcpfier/analytics.db" || config.Jobs.RetentionDuration != DefaultJobRetention {
+		t.Errorf("Expected jobs in the analytics database for the default period, got %+v", config.Jobs)
+	}
+
+	invalid := map[string]string{
+		"invalid retention": `
+jobs:
+  retention: forever
+commands:
+  - name: build
+    script: make
+    async: true
+`,
+		"command named like a job tool": `
+commands:
+  - name: build
+    script: make
+    async: true
+  - name: job_status
+    script: echo
+`,
+	}
+	for name, content := range invalid {
+		os.WriteFile(path, []byte(content), 0644)
+		if _, err := Load(path); err == nil {
+			t.Errorf("Expected error for %s", name)
+		}
+	}
+}
+
+func TestLoadConfigSchedule(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	os.WriteFile(path, []byte(`
+commands:
+  - name: refresh
+    script: ./refresh.sh
+    schedule: "*/15 * * * *"
+  - name: report
+    script: ./report.sh
+    parameters:
+      - name: days
+        type: number
+        required: true
+    schedule:
+      cron: "0 9 * * mon-fri"
+      overlap: queue
+      jitter: 30s
+      timezone: Europe/Berlin
+      params:
+        days: 7
+  - name: manual
+    script: ./manual.sh
+`), 0644)
+
+	config, err := Load(path)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if !config.HasSchedules() || config.Commands[2].Schedule != nil {
+		t.Error("Expected only refresh and report to be scheduled")
+	}
+
+	refresh := config.Commands[0].Schedule
+	if refresh.Cron != "*/15 * * * *" || refresh.Overlap != OverlapSkip || refresh.JitterDuration != 0 || refresh.Location != time.Local {
+		t.Errorf("Expected shorthand schedule with defaults, got %+v", refresh)
+	}
+	from := time.Date(2026, 1, 16, 10, 7, 0, 0, time.Local)
+	if next := refresh.CronSchedule.Next(from); !next.Equal(from.Add(8 * time.Minute)) {
+		t.Errorf("Expected next run at 10:15, got %v", next)
+	}
+
+	report := config.Commands[1].Schedule
+	if report.Overlap != OverlapQueue || report.JitterDuration != 30*time.Second || report.Location.String() != "Europe/Berlin" {
+		t.Errorf("Expected schedule block to be parsed, got %+v", report)
+	}
+
+	invalid := map[string]string{
+		"invalid cron expression": `
+commands:
+  - name: refresh
+    script: ./refresh.sh
+    schedule: "every 15 minutes"
+`,
+		"unknown overlap policy": `
+commands:
+  - name: refresh
+    script: ./refresh.sh
+    schedule:
+      cron: "@hourly"
+      overlap: replace
+`,
+		"negative jitter": `
+commands:
+  - name: refresh
+    script: ./refresh.sh
+    schedule:
+      cron: "@hourly"
+      jitter: -1m
+`,
+		"unknown timezone": `
+commands:
+  - name: refresh
+    script: ./refresh.sh
+    schedule:
+      cron: "@hourly"
+      timezone: Mars/Olympus
+`,
+		"missing required param": `
+commands:
+  - name: report
+    script: ./report.sh
+    parameters:
+      - name: days
+        type: number
+        required: true
+    schedule: "@daily"
+`,
+		"unknown param": `
+commands:
+  - name: refresh
+    script: ./refresh.sh
+    schedule:
+      cron: "@hourly"
+      params:
+        force: true
+`,
+	}
+	for name, content := range invalid {
+		os.WriteFile(path, []byte(content), 0644)
+		if _, err := Load(path); err == nil {
+			t.Errorf("Expected error for %s", name)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Container runtime types
+const (
+	RuntimeDocker  = "docker"
+	RuntimePodman  = "podman"
+	RuntimeNerdctl = "nerdctl"
+	RuntimeEngine  = "engine" // Docker Engine API, also served by Podman's compat socket
+)
+
+// ContainerRuntimeConfig selects how containers are started
+type ContainerRuntimeConfig struct {
+	Type   string `yaml:"type"`   // docker (default), podman, nerdctl or engine
+	Binary string `yaml:"binary"` // CLI path for docker, podman and nerdctl (default: the type name)
+	Host   string `yaml:"host"`   // Engine API address (default: $DOCKER_HOST or unix:///var/run/docker.sock)
+}
+
+// GetType returns the runtime type, defaulting to docker
+func (r ContainerRuntimeConfig) GetType() string {
+	if r.Type == "" {
+		return RuntimeDocker
+	}
+	return r.Type
+}
+
+// validate checks the runtime type and Engine API address
+func (r ContainerRuntimeConfig) validate() error {
+	switch r.GetType() {
+	case RuntimeDocker, RuntimePodman, RuntimeNerdctl:
+	case RuntimeEngine:
+		if r.Host != "" {
+			u, err := url.Parse(r.Host)
+			if err != nil || (u.Scheme != "unix" && u.Scheme != "tcp" && u.Scheme != "http") {
+				return fmt.Errorf("invalid host '%s', expected unix://, tcp:// or http://", r.Host)
+			}
+		}
+	default:
+		return fmt.Errorf("unknown type '%s'", r.Type)
+	}
+	return nil
+}
+
+// ContainerConfig holds container isolation settings. In YAML, `container:` accepts
+// either a plain image name or a block with these fields.
+type ContainerConfig struct {
+	Image      string   `yaml:"image"`
+	Memory     string   `yaml:"memory"`     // Memory limit, e.g. 512m or 2g
+	CPUs       string   `yaml:"cpus"`       // CPU limit, e.g. "0.5"
+	PidsLimit  int      `yaml:"pids_limit"` // Maximum number of processes
+	Network    string   `yaml:"network"`    // none, bridge, host or a named network
+	User       string   `yaml:"user"`       // uid[:gid] or user name
+	ReadOnly   bool     `yaml:"read_only"`  // Read-only root filesystem
+	CapDrop    []string `yaml:"cap_drop"`   // Capabilities to drop, e.g. [ALL]
+	CapAdd     []string `yaml:"cap_add"`    // Capabilities to add back
+	Workdir    string   `yaml:"workdir"`    // Working directory inside the container
+	Entrypoint string   `yaml:"entrypoint"` // Overrides the image entrypoint
+	Mounts     []Mount  `yaml:"mounts"`     // Bind mounts and named volumes
+	Tmpfs      []string `yaml:"tmpfs"`      // Writable tmpfs paths, useful with read_only
+}
+
+// PoolConfig keeps pre-started containers for a command; each call runs via exec in one of them
+type PoolConfig struct {
+	Size        int      `yaml:"size"`         // Warm containers to keep (default: 1)
+	MaxUses     int      `yaml:"max_uses"`     // Replace a container after this many calls (default: 100)
+	IdleTimeout string   `yaml:"idle_timeout"` // Replace a container unused for this long (default: 10m)
+	Keepalive   []string `yaml:"keepalive"`    // Long-running process that keeps it up (default: sleep infinity)
+
+	// Parsed at load time from IdleTimeout
+	IdleTimeoutDuration time.Duration `yaml:"-"`
+}
+
+// Pool defaults
+const (
+	DefaultPoolSize        = 1
+	DefaultPoolMaxUses     = 100
+	DefaultPoolIdleTimeout = 10 * time.Minute
+)
+
+// DefaultPoolKeepalive keeps a pooled container running without doing any work
+var DefaultPoolKeepalive = []string{"sleep", "infinity"}
+
+// validate applies defaults and parses the idle timeout
+func (p *PoolConfig) validate() error {
+	if p.Size < 0 || p.MaxUses < 0 {
+		return fmt.Errorf("pool size and max_uses must not be negative")
+	}
+	if p.Size == 0 {
+		p.Size = DefaultPoolSize
+	}
+	if p.MaxUses == 0 {
+		p.MaxUses = DefaultPoolMaxUses
+	}
+	if len(p.Keepalive) == 0 {
+		p.Keepalive = DefaultPoolKeepalive
+	}
+
+	p.IdleTimeoutDuration = DefaultPoolIdleTimeout
+	if p.IdleTimeout != "" {
+		d, err := time.ParseDuration(p.IdleTimeout)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("invalid pool idle_timeout '%s'", p.IdleTimeout)
+		}
+		p.IdleTimeoutDuration = d
+	}
+	return nil
+}
+
+// Mount is a bind mount (absolute host path) or named volume in a container
+type Mount struct {
+	Source   string `yaml:"source"`
+	Target   string `yaml:"target"`
+	ReadOnly bool   `yaml:"read_only"`
+}
+
+// memoryPattern matches docker-style memory sizes
+var memoryPattern = regexp.MustCompile(`^[0-9]+[bkmgBKMG]?$`)
+
+// UnmarshalYAML accepts both `container: image` and a `container:` block
+func (c *ContainerConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var image string
+	if err := unmarshal(&image); err == nil {
+		*c = ContainerConfig{Image: image}
+		return nil
+	}
+
+	type plain ContainerConfig
+	return unmarshal((*plain)(c))
+}
+
+// validate checks container settings that the runtime would otherwise reject at call time
+func (c *ContainerConfig) validate() error {
+	if c.Image == "" {
+		return fmt.Errorf("container image is required")
+	}
+	if c.Memory != "" && !memoryPattern.MatchString(c.Memory) {
+		return fmt.Errorf("invalid container memory '%s'", c.Memory)
+	}
+	if c.CPUs != "" {
+		if cpus, err := strconv.ParseFloat(c.CPUs, 64); err != nil || cpus <= 0 {
+			return fmt.Errorf("invalid container cpus '%s'", c.CPUs)
+		}
+	}
+	if c.PidsLimit < 0 {
+		return fmt.Errorf("container pids_limit must not be negative")
+	}
+	if c.Workdir != "" && !path.IsAbs(c.Workdir) {
+		return fmt.Errorf("container workdir '%s' must be absolute", c.Workdir)
+	}
+	for _, mount := range c.Mounts {
+		if mount.Source == "" {
+			return fmt.Errorf("container mount for '%s' needs a source", mount.Target)
+		}
+		if !path.IsAbs(mount.Target) {
+			return fmt.Errorf("container mount target '%s' must be absolute", mount.Target)
+		}
+	}
+	for _, tmpfs := range c.Tmpfs {
+		if !path.IsAbs(tmpfs) {
+			return fmt.Errorf("container tmpfs path '%s' must be absolute", tmpfs)
+		}
+	}
+	return nil
+}
+
+// MemoryBytes returns the memory limit in bytes, or 0 if unset
+func (c *ContainerConfig) MemoryBytes() int64 {
+	return parseMemory(c.Memory)
+}
+
+// parseMemory converts a docker-style memory size to bytes, or 0 if empty
+func parseMemory(memory string) int64 {
+	if memory == "" {
+		return 0
+	}
+	value := strings.ToLower(memory)
+	multiplier := int64(1)
+	switch value[len(value)-1] {
+	case 'b':
+		value = value[:len(value)-1]
+	case 'k':
+		multiplier, value = 1<<10, value[:len(value)-1]
+	case 'm':
+		multiplier, value = 1<<20, value[:len(value)-1]
+	case 'g':
+		multiplier, value = 1<<30, value[:len(value)-1]
+	}
+	n, _ := strconv.ParseInt(value, 10, 64)
+	return n * multiplier
+}
+
+// NanoCPUs returns the CPU limit in billionths of a CPU, or 0 if unset
+func (c *ContainerConfig) NanoCPUs() int64 {
+	cpus, err := strconv.ParseFloat(c.CPUs, 64)
+	if err != nil {
+		return 0
+	}
+	return int64(cpus * 1e9)
+}
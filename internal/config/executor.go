@@ -0,0 +1,59 @@
+package config
+
+import (
+	"fmt"
+	"sync"
+
+	"gopkg.in/yaml.v2"
+)
+
+// ExecutorConfig runs a command on a custom executor registered under Type.
+// The other keys of the block are the executor's own settings.
+type ExecutorConfig struct {
+	Type     string                 `yaml:"type"`
+	Settings map[string]interface{} `yaml:",inline"`
+}
+
+// Decode unmarshals the executor settings into out, a pointer to the
+// executor's settings struct. Unknown settings are an error.
+func (e *ExecutorConfig) Decode(out interface{}) error {
+	data, err := yaml.Marshal(e.Settings)
+	if err != nil {
+		return err
+	}
+	return yaml.UnmarshalStrict(data, out)
+}
+
+var (
+	executorTypesMu sync.RWMutex
+	executorTypes   = make(map[string]func(cmd *Command) error)
+)
+
+// RegisterExecutorType makes type name valid in executor blocks. validate checks
+// the commands using it when the config is loaded, and may be nil. Registering
+// a name twice panics.
+func RegisterExecutorType(name string, validate func(cmd *Command) error) {
+	executorTypesMu.Lock()
+	defer executorTypesMu.Unlock()
+	if _, dup := executorTypes[name]; dup {
+		panic("config: executor type registered twice: " + name)
+	}
+	executorTypes[name] = validate
+}
+
+// validate checks the type is registered and runs its validation
+func (e *ExecutorConfig) validate(cmd *Command) error {
+	executorTypesMu.RLock()
+	validate, ok := executorTypes[e.Type]
+	executorTypesMu.RUnlock()
+	if !ok {
+		return fmt.Errorf("unknown executor type '%s'", e.Type)
+	}
+	if validate == nil {
+		return nil
+	}
+	if err := validate(cmd); err != nil {
+		return fmt.Errorf("executor %s: %w", e.Type, err)
+	}
+	return nil
+}
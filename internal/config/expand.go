@@ -0,0 +1,206 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// expandConfig expands ${VAR}, ${VAR:-default} and ${file:/path} references
+// in every string field of the configuration. path is used for error messages.
+// Fields tagged `expand:"template"` are rendered as argument templates at call
+// time, so template delimiters in the values substituted into them are escaped
+// (see escapeTemplate). Fields tagged `expand:"-"` hold code, such as scripts and
+// queries, where ${ is common and is left as written.
+func expandConfig(v reflect.Value, path string, template bool) error {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return nil
+		}
+		return expandConfig(v.Elem(), path, template)
+
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < v.NumField(); i++ {
+			field := t.Field(i)
+			if field.PkgPath != "" {
+				continue // unexported
+			}
+			tag := field.Tag.Get("yaml")
+			name := strings.Split(tag, ",")[0]
+			if name == "-" {
+				continue
+			}
+			fieldPath := path
+			if !strings.HasSuffix(tag, ",inline") {
+				if name == "" {
+					name = strings.ToLower(field.Name)
+				}
+				fieldPath = joinPath(path, name)
+			}
+			expand := field.Tag.Get("expand")
+			if expand == "-" {
+				continue
+			}
+			if err := expandConfig(v.Field(i), fieldPath, template || expand == "template"); err != nil {
+				return err
+			}
+		}
+
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			if err := expandConfig(v.Index(i), fmt.Sprintf("%s[%d]", path, i), template); err != nil {
+				return err
+			}
+		}
+
+	case reflect.Map:
+		// Pointer values, such as named credentials, are expanded in place
+		if v.Type().Elem().Kind() == reflect.Ptr {
+			for _, key := range v.MapKeys() {
+				if err := expandConfig(v.MapIndex(key), joinPath(path, fmt.Sprint(key.Interface())), template); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		// Free-form settings, such as custom executor blocks, hold strings, lists and maps
+		if v.Type().Elem().Kind() == reflect.Interface {
+			for _, key := range v.MapKeys() {
+				value := reflect.New(v.Type().Elem()).Elem()
+				value.Set(v.MapIndex(key))
+				if err := expandConfig(value, joinPath(path, fmt.Sprint(key.Interface())), template); err != nil {
+					return err
+				}
+				v.SetMapIndex(key, value)
+			}
+			return nil
+		}
+		if v.Type().Elem().Kind() != reflect.String {
+			return nil
+		}
+		for _, key := range v.MapKeys() {
+			expanded, err := expandString(v.MapIndex(key).String(), template)
+			if err != nil {
+				return fmt.Errorf("%s: %w", joinPath(path, fmt.Sprint(key.Interface())), err)
+			}
+			v.SetMapIndex(key, reflect.ValueOf(expanded).Convert(v.Type().Elem()))
+		}
+
+	case reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		elem := v.Elem()
+		switch elem.Kind() {
+		case reflect.String:
+			expanded, err := expandString(elem.String(), template)
+			if err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+			v.Set(reflect.ValueOf(expanded))
+		case reflect.Slice, reflect.Map:
+			return expandConfig(elem, path, template)
+		}
+
+	case reflect.String:
+		expanded, err := expandString(v.String(), template)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		v.SetString(expanded)
+	}
+
+	return nil
+}
+
+// joinPath appends a field name to a dotted config path
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// expandString replaces references in s:
+//
+//	${VAR}          value of VAR, error if unset
+//	${VAR:-default} value of VAR, or default if VAR is unset or empty
+//	${file:/path}   contents of /path with the trailing newline removed
+//	$${             a literal ${
+//
+// With template set, substituted values are escaped with escapeTemplate.
+func expandString(s string, template bool) (string, error) {
+	if !strings.Contains(s, "${") {
+		return s, nil
+	}
+
+	var b strings.Builder
+	for {
+		i := strings.Index(s, "${")
+		if i < 0 {
+			b.WriteString(s)
+			break
+		}
+
+		// $${ escapes a literal ${
+		if i > 0 && s[i-1] == '$' {
+			b.WriteString(s[:i-1])
+			b.WriteString("${")
+			s = s[i+2:]
+			continue
+		}
+
+		end := strings.Index(s[i:], "}")
+		if end < 0 {
+			return "", fmt.Errorf("unterminated reference in %q", s)
+		}
+
+		value, err := resolveReference(s[i+2 : i+end])
+		if err != nil {
+			return "", err
+		}
+		if template {
+			value = escapeTemplate(value)
+		}
+		b.WriteString(s[:i])
+		b.WriteString(value)
+		s = s[i+end+1:]
+	}
+
+	return b.String(), nil
+}
+
+// escapeTemplate makes a substituted value render as itself in an argument
+// template: each {{ becomes the constant action {{"{{"}}, so a secret or
+// environment value cannot inject template actions
+func escapeTemplate(value string) string {
+	return strings.ReplaceAll(value, "{{", `{{"{{"}}`)
+}
+
+// resolveReference resolves the inside of a ${...} reference
+func resolveReference(ref string) (string, error) {
+	if path, ok := strings.CutPrefix(ref, "file:"); ok {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read secret file: %w", err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	}
+
+	name, def, hasDefault := strings.Cut(ref, ":-")
+	if name == "" {
+		return "", fmt.Errorf("empty variable name in ${%s}", ref)
+	}
+
+	value, ok := os.LookupEnv(name)
+	if hasDefault && value == "" {
+		return def, nil
+	}
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", name)
+	}
+	return value, nil
+}
@@ -0,0 +1,59 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// DefaultJobRetention is how long finished jobs are kept by default
+const DefaultJobRetention = 24 * time.Hour
+
+// JobTools are the tools registered when any command is async
+var JobTools = []string{"job_status", "job_result", "job_cancel"}
+
+// JobsConfig controls where the jobs of async commands are kept and for how long
+type JobsConfig struct {
+	DatabasePath string `yaml:"database_path"` // SQLite file (default: analytics.database_path, or ./analytics.db)
+	Retention    string `yaml:"retention"`     // How long finished jobs are kept (default: 24h)
+
+	// Parsed at load time from Retention
+	RetentionDuration time.Duration `yaml:"-"`
+}
+
+// validate parses the retention period
+func (j *JobsConfig) validate() error {
+	j.RetentionDuration = DefaultJobRetention
+	if j.Retention != "" {
+		d, err := time.ParseDuration(j.Retention)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("invalid retention: %s", j.Retention)
+		}
+		j.RetentionDuration = d
+	}
+	return nil
+}
+
+// HasAsync returns true if any command runs as an async job
+func (c *Config) HasAsync() bool {
+	for _, cmd := range c.Commands {
+		if cmd.Async {
+			return true
+		}
+	}
+	return false
+}
+
+// validateJobTools rejects commands named like the job tools when those are registered
+func (c *Config) validateJobTools() error {
+	if !c.HasAsync() {
+		return nil
+	}
+	for _, cmd := range c.Commands {
+		for _, name := range JobTools {
+			if cmd.Name == name {
+				return fmt.Errorf("command '%s': name is reserved for the job tools of async commands", cmd.Name)
+			}
+		}
+	}
+	return nil
+}
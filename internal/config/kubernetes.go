@@ -0,0 +1,56 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// DefaultKubernetesTTL is how long finished Jobs are kept when they are not deleted
+const DefaultKubernetesTTL = 10 * time.Minute
+
+// numericUser matches uid[:gid], the only user form a pod security context accepts
+var numericUser = regexp.MustCompile(`^[0-9]+(:[0-9]+)?$`)
+
+// KubernetesConfig runs a containerized command as a Kubernetes Job instead of
+// with the local container runtime. Credentials come from a kubeconfig file or,
+// in a pod, from its service account.
+type KubernetesConfig struct {
+	Kubeconfig       string            `yaml:"kubeconfig"`         // Default: $KUBECONFIG or ~/.kube/config
+	Context          string            `yaml:"context"`            // kubeconfig context (default: current-context)
+	InCluster        bool              `yaml:"in_cluster"`         // Use the service account of the pod MCPFier runs in
+	Namespace        string            `yaml:"namespace"`          // Default: the context's namespace, the pod's namespace in-cluster, or default
+	ServiceAccount   string            `yaml:"service_account"`    // Service account the Job's pod runs as
+	Labels           map[string]string `yaml:"labels"`             // Extra labels for the Job and its pod
+	TTLAfterFinished string            `yaml:"ttl_after_finished"` // ttlSecondsAfterFinished for Jobs left behind (default: 10m)
+	KeepFinished     bool              `yaml:"keep_finished"`      // Leave finished Jobs to the TTL instead of deleting them
+
+	// Parsed at load time from TTLAfterFinished
+	TTLAfterFinishedDuration time.Duration `yaml:"-"`
+}
+
+// validate checks the Job settings against the command's container settings
+func (k *KubernetesConfig) validate(container *ContainerConfig) error {
+	if k.InCluster && (k.Kubeconfig != "" || k.Context != "") {
+		return fmt.Errorf("kubernetes in_cluster cannot be combined with kubeconfig or context")
+	}
+	k.TTLAfterFinishedDuration = DefaultKubernetesTTL
+	if k.TTLAfterFinished != "" {
+		d, err := time.ParseDuration(k.TTLAfterFinished)
+		if err != nil || d < 0 {
+			return fmt.Errorf("invalid kubernetes ttl_after_finished: %s", k.TTLAfterFinished)
+		}
+		k.TTLAfterFinishedDuration = d
+	}
+
+	if container == nil {
+		return nil
+	}
+	if container.Network != "" || len(container.Mounts) > 0 || container.PidsLimit > 0 {
+		return fmt.Errorf("kubernetes does not support container network, mounts or pids_limit")
+	}
+	if container.User != "" && !numericUser.MatchString(container.User) {
+		return fmt.Errorf("kubernetes requires a numeric container user, got '%s'", container.User)
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// Supported parameter types
+const (
+	ParamString  = "string"
+	ParamNumber  = "number"
+	ParamBoolean = "boolean"
+	ParamArray   = "array"
+	ParamEnum    = "enum"
+)
+
+// Parameter describes a typed tool input exposed in the MCP inputSchema
+type Parameter struct {
+	Name        string      `yaml:"name"`
+	Type        string      `yaml:"type"` // string, number, boolean, array, enum
+	Required    bool        `yaml:"required"`
+	Default     interface{} `yaml:"default,omitempty"`
+	Description string      `yaml:"description"`
+	Values      []string    `yaml:"values,omitempty"` // Allowed values for enum parameters
+	Items       string      `yaml:"items,omitempty"`  // Element type for arrays: string, number, boolean (default: string)
+}
+
+// GetType returns the parameter type, defaulting to string
+func (p Parameter) GetType() string {
+	if p.Type == "" {
+		return ParamString
+	}
+	return p.Type
+}
+
+// GetItems returns the array element type, defaulting to string
+func (p Parameter) GetItems() string {
+	if p.Items == "" {
+		return ParamString
+	}
+	return p.Items
+}
+
+// validate checks that a parameter definition is well formed
+func (p Parameter) validate() error {
+	if p.Name == "" {
+		return fmt.Errorf("parameter name is required")
+	}
+
+	switch p.GetType() {
+	case ParamString, ParamNumber, ParamBoolean:
+	case ParamArray:
+		switch p.GetItems() {
+		case ParamString, ParamNumber, ParamBoolean:
+		default:
+			return fmt.Errorf("parameter '%s': unsupported array item type: %s", p.Name, p.Items)
+		}
+	case ParamEnum:
+		if len(p.Values) == 0 {
+			return fmt.Errorf("parameter '%s': enum requires at least one value", p.Name)
+		}
+	default:
+		return fmt.Errorf("parameter '%s': unsupported type: %s", p.Name, p.Type)
+	}
+
+	if p.Default != nil {
+		if _, err := p.coerce(p.Default); err != nil {
+			return fmt.Errorf("parameter '%s': invalid default: %w", p.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// coerce checks a value against the parameter type and normalizes it
+// (numbers become float64, arrays become []interface{})
+func (p Parameter) coerce(value interface{}) (interface{}, error) {
+	switch p.GetType() {
+	case ParamString:
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected string, got %T", value)
+		}
+		return s, nil
+
+	case ParamNumber:
+		return coerceNumber(value)
+
+	case ParamBoolean:
+		b, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("expected boolean, got %T", value)
+		}
+		return b, nil
+
+	case ParamEnum:
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected string, got %T", value)
+		}
+		for _, allowed := range p.Values {
+			if s == allowed {
+				return s, nil
+			}
+		}
+		return nil, fmt.Errorf("value '%s' is not one of %v", s, p.Values)
+
+	case ParamArray:
+		items, ok := value.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected array, got %T", value)
+		}
+		itemParam := Parameter{Name: p.Name, Type: p.GetItems()}
+		result := make([]interface{}, 0, len(items))
+		for i, item := range items {
+			v, err := itemParam.coerce(item)
+			if err != nil {
+				return nil, fmt.Errorf("item %d: %w", i, err)
+			}
+			result = append(result, v)
+		}
+		return result, nil
+	}
+
+	return nil, fmt.Errorf("unsupported type: %s", p.Type)
+}
+
+// coerceNumber accepts JSON numbers (float64) and YAML integers
+func coerceNumber(value interface{}) (float64, error) {
+	var f float64
+	switch v := value.(type) {
+	case float64:
+		f = v
+	case float32:
+		f = float64(v)
+	case int:
+		f = float64(v)
+	case int64:
+		f = float64(v)
+	default:
+		return 0, fmt.Errorf("expected number, got %T", value)
+	}
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, fmt.Errorf("expected finite number, got %s", strconv.FormatFloat(f, 'g', -1, 64))
+	}
+	return f, nil
+}
+
+// ResolveArguments validates caller-supplied arguments against the command's
+// parameters and fills in defaults. Unknown arguments are rejected.
+func (c Command) ResolveArguments(args map[string]interface{}) (map[string]interface{}, error) {
+	resolved := make(map[string]interface{}, len(c.Parameters))
+
+	known := make(map[string]bool, len(c.Parameters))
+	for _, param := range c.Parameters {
+		known[param.Name] = true
+	}
+	for name := range args {
+		if !known[name] {
+			return nil, fmt.Errorf("unknown argument '%s'", name)
+		}
+	}
+
+	for _, param := range c.Parameters {
+		value, ok := args[param.Name]
+		if !ok || value == nil {
+			if param.Default != nil {
+				value = param.Default
+			} else if param.Required {
+				return nil, fmt.Errorf("missing required argument '%s'", param.Name)
+			} else {
+				continue
+			}
+		}
+
+		v, err := param.coerce(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid argument '%s': %w", param.Name, err)
+		}
+		resolved[param.Name] = v
+	}
+
+	return resolved, nil
+}
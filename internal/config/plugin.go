@@ -0,0 +1,64 @@
+package config
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"time"
+)
+
+// Plugin defaults
+const (
+	DefaultPluginDir             = "plugins" // Relative to the config file
+	DefaultPluginDescribeTimeout = 10 * time.Second
+)
+
+// PluginsConfig locates the external executor plugins commands refer to by name
+type PluginsConfig struct {
+	Dir             string `yaml:"dir"`              // Plugin binaries; relative paths are relative to the config file (default: plugins)
+	DescribeTimeout string `yaml:"describe_timeout"` // How long a plugin gets to describe itself at startup (default: 10s)
+
+	// Parsed at load time from DescribeTimeout
+	DescribeTimeoutDuration time.Duration `yaml:"-"`
+}
+
+// validate parses the describe timeout
+func (p *PluginsConfig) validate() error {
+	p.DescribeTimeoutDuration = DefaultPluginDescribeTimeout
+	if p.DescribeTimeout != "" {
+		d, err := time.ParseDuration(p.DescribeTimeout)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("invalid describe_timeout: %s", p.DescribeTimeout)
+		}
+		p.DescribeTimeoutDuration = d
+	}
+	return nil
+}
+
+// resolve finds the executable of plugin name in the plugin directory
+func (p *PluginsConfig) resolve(name string) (string, error) {
+	if name != filepath.Base(name) || name == "." || name == ".." {
+		return "", fmt.Errorf("plugin '%s' must be a file name in the plugin directory", name)
+	}
+	// LookPath checks the file is executable, and adds .exe on Windows
+	path, err := exec.LookPath(filepath.Join(p.Dir, name))
+	if err != nil {
+		return "", fmt.Errorf("plugin '%s' not found in %s", name, p.Dir)
+	}
+	return path, nil
+}
+
+// ApplyPluginDescription applies what a plugin advertises about itself to a
+// command that does not declare its own description or parameters
+func (c *Command) ApplyPluginDescription(description string, params []Parameter) error {
+	if len(c.Parameters) == 0 {
+		if err := validateParameters(params); err != nil {
+			return fmt.Errorf("plugin '%s': %w", c.Plugin, err)
+		}
+		c.Parameters = params
+	}
+	if c.Description == "" {
+		c.Description = description
+	}
+	return nil
+}
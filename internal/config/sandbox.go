@@ -0,0 +1,41 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// SandboxConfig runs a local command in a Linux sandbox: user, mount, PID and
+// network namespaces, a landlock filesystem allowlist, rlimits and a seccomp
+// profile. System directories (/usr, /bin, /lib, /etc, ...) are always readable.
+type SandboxConfig struct {
+	Read       []string `yaml:"read"`        // Paths the command may read and execute
+	Write      []string `yaml:"write"`       // Paths the command may create, modify and delete files under
+	Network    bool     `yaml:"network"`     // Keep host network access (default: no network)
+	CPU        int      `yaml:"cpu"`         // CPU time limit in seconds
+	Memory     string   `yaml:"memory"`      // Address space limit, e.g. 512m
+	OpenFiles  int      `yaml:"open_files"`  // Maximum open file descriptors
+	Processes  int      `yaml:"processes"`   // Maximum processes and threads
+	InheritEnv []string `yaml:"inherit_env"` // Server environment variables passed on besides PATH, HOME, LANG and TMPDIR
+}
+
+// validate checks sandbox paths and limits
+func (s *SandboxConfig) validate() error {
+	for _, path := range append(append([]string(nil), s.Read...), s.Write...) {
+		if !filepath.IsAbs(path) {
+			return fmt.Errorf("sandbox path '%s' must be absolute", path)
+		}
+	}
+	if s.Memory != "" && !memoryPattern.MatchString(s.Memory) {
+		return fmt.Errorf("invalid sandbox memory '%s'", s.Memory)
+	}
+	if s.CPU < 0 || s.OpenFiles < 0 || s.Processes < 0 {
+		return fmt.Errorf("sandbox cpu, open_files and processes must not be negative")
+	}
+	return nil
+}
+
+// MemoryBytes returns the address space limit in bytes, or 0 if unset
+func (s *SandboxConfig) MemoryBytes() int64 {
+	return parseMemory(s.Memory)
+}
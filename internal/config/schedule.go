@@ -0,0 +1,97 @@
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gleicon/mcpfier/internal/cron"
+)
+
+// Overlap policies for a scheduled run that is due while the previous one still runs
+const (
+	OverlapSkip  = "skip"  // Skip the run (default)
+	OverlapQueue = "queue" // Run once the previous run finished; at most one run waits
+	OverlapAllow = "allow" // Run alongside the previous run
+)
+
+// ScheduleConfig runs a command on a cron schedule, without any client calling it
+type ScheduleConfig struct {
+	Cron     string                 `yaml:"cron"`     // Five-field cron expression or @hourly, @daily, ...
+	Overlap  string                 `yaml:"overlap"`  // skip (default), queue or allow
+	Jitter   string                 `yaml:"jitter"`   // Random delay of up to this duration added to each run
+	Timezone string                 `yaml:"timezone"` // IANA time zone of the cron expression (default: local time)
+	Params   map[string]interface{} `yaml:"params"`   // Arguments of the scheduled runs
+
+	// Parsed at load time from Cron, Jitter and Timezone
+	CronSchedule   *cron.Schedule `yaml:"-"`
+	JitterDuration time.Duration  `yaml:"-"`
+	Location       *time.Location `yaml:"-"`
+}
+
+// UnmarshalYAML accepts both `schedule: "*/15 * * * *"` and a `schedule:` block
+func (s *ScheduleConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var expr string
+	if err := unmarshal(&expr); err == nil {
+		*s = ScheduleConfig{Cron: expr}
+		return nil
+	}
+
+	type plain ScheduleConfig
+	return unmarshal((*plain)(s))
+}
+
+// validate parses the schedule and checks its params against the command's parameters
+func (s *ScheduleConfig) validate(cmd *Command) error {
+	if s.Cron == "" {
+		return fmt.Errorf("schedule cron expression is required")
+	}
+	schedule, err := cron.Parse(s.Cron)
+	if err != nil {
+		return fmt.Errorf("schedule: %w", err)
+	}
+	s.CronSchedule = schedule
+
+	switch s.Overlap {
+	case "":
+		s.Overlap = OverlapSkip
+	case OverlapSkip, OverlapQueue, OverlapAllow:
+	default:
+		return fmt.Errorf("schedule overlap must be skip, queue or allow, got '%s'", s.Overlap)
+	}
+
+	if s.Jitter != "" {
+		d, err := time.ParseDuration(s.Jitter)
+		if err != nil || d < 0 {
+			return fmt.Errorf("invalid schedule jitter: %s", s.Jitter)
+		}
+		s.JitterDuration = d
+	}
+
+	s.Location = time.Local
+	if s.Timezone != "" {
+		loc, err := time.LoadLocation(s.Timezone)
+		if err != nil {
+			return fmt.Errorf("invalid schedule timezone '%s': %w", s.Timezone, err)
+		}
+		s.Location = loc
+	}
+
+	// Plugins may only advertise their parameters at startup
+	if cmd.IsPlugin() && len(cmd.Parameters) == 0 {
+		return nil
+	}
+	if _, err := cmd.ResolveArguments(s.Params); err != nil {
+		return fmt.Errorf("schedule params: %w", err)
+	}
+	return nil
+}
+
+// HasSchedules returns true if any command runs on a schedule
+func (c *Config) HasSchedules() bool {
+	for _, cmd := range c.Commands {
+		if cmd.Schedule != nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,77 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// SQL result formats
+const (
+	SQLFormatTable = "table" // Markdown table
+	SQLFormatCSV   = "csv"
+	SQLFormatJSON  = "json" // Array of row objects
+)
+
+// DefaultSQLDriver is the database/sql driver used when none is configured
+const DefaultSQLDriver = "sqlite"
+
+// DefaultSQLMaxRows bounds the rows returned by a query
+const DefaultSQLMaxRows = 1000
+
+// SQLConfig runs a parameterized query against a database/sql data source.
+// Tool parameters are bound to placeholders, never interpolated into the query.
+type SQLConfig struct {
+	Driver   string   `yaml:"driver"`           // database/sql driver name (default: sqlite)
+	DSN      string   `yaml:"dsn"`              // Data source name, e.g. /var/lib/app.db
+	Query    string   `yaml:"query" expand:"-"` // Query using named placeholders, e.g. :user, or positional ones with bind; not expanded
+	Bind     []string `yaml:"bind"`             // Parameters bound in order to positional placeholders (? or $1)
+	Format   string   `yaml:"format"`           // table (default), csv or json
+	ReadOnly bool     `yaml:"read_only"`        // Run the query in a read-only connection and transaction
+	MaxRows  int      `yaml:"max_rows"`         // Rows returned before the result is cut off (default: 1000)
+	Timeout  string   `yaml:"timeout"`          // Query timeout, e.g. 5s (default: the command timeout)
+
+	// Parsed at load time from Timeout
+	TimeoutDuration time.Duration `yaml:"-"`
+}
+
+// validate checks the query settings against the command's parameters and applies defaults
+func (s *SQLConfig) validate(params []Parameter) error {
+	if s.DSN == "" || s.Query == "" {
+		return fmt.Errorf("sql requires a dsn and a query")
+	}
+	if s.Driver == "" {
+		s.Driver = DefaultSQLDriver
+	}
+	if s.Format == "" {
+		s.Format = SQLFormatTable
+	}
+	switch s.Format {
+	case SQLFormatTable, SQLFormatCSV, SQLFormatJSON:
+	default:
+		return fmt.Errorf("invalid sql format '%s', expected table, csv or json", s.Format)
+	}
+	if s.MaxRows < 0 {
+		return fmt.Errorf("invalid sql max_rows: %d", s.MaxRows)
+	}
+	if s.MaxRows == 0 {
+		s.MaxRows = DefaultSQLMaxRows
+	}
+	if s.Timeout != "" {
+		d, err := time.ParseDuration(s.Timeout)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("invalid sql timeout: %s", s.Timeout)
+		}
+		s.TimeoutDuration = d
+	}
+
+	declared := make(map[string]bool, len(params))
+	for _, param := range params {
+		declared[param.Name] = true
+	}
+	for _, name := range s.Bind {
+		if !declared[name] {
+			return fmt.Errorf("sql bind '%s' is not a declared parameter", name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// DefaultSSHPort is the port used when an ssh block does not set one
+const DefaultSSHPort = 22
+
+// DefaultSSHConnectTimeout bounds connecting and authenticating to a host
+const DefaultSSHConnectTimeout = 10 * time.Second
+
+// SSHConfig runs a command's script on a remote host. The host key is always
+// verified, against host_key when pinned or against a known_hosts file.
+type SSHConfig struct {
+	Host           string     `yaml:"host"`
+	Port           int        `yaml:"port"` // Default: 22
+	User           string     `yaml:"user"`
+	KeyFile        string     `yaml:"key_file"`        // Private key file
+	Passphrase     string     `yaml:"passphrase"`      // Passphrase for an encrypted key file
+	Agent          bool       `yaml:"agent"`           // Authenticate with the keys in $SSH_AUTH_SOCK
+	KnownHosts     string     `yaml:"known_hosts"`     // known_hosts file (default: ~/.ssh/known_hosts)
+	HostKey        string     `yaml:"host_key"`        // Pinned host key, e.g. "ssh-ed25519 AAAA...", instead of known_hosts
+	ConnectTimeout string     `yaml:"connect_timeout"` // Default: 10s
+	Jump           *SSHConfig `yaml:"jump,omitempty"`  // Jump host the connection is tunneled through
+
+	// Parsed at load time from ConnectTimeout
+	ConnectTimeoutDuration time.Duration `yaml:"-"`
+}
+
+// validate checks the connection settings, including those of jump hosts
+func (s *SSHConfig) validate() error {
+	if s.Host == "" || s.User == "" {
+		return fmt.Errorf("ssh requires a host and a user")
+	}
+	if s.KeyFile == "" && !s.Agent {
+		return fmt.Errorf("ssh host '%s' requires key_file or agent", s.Host)
+	}
+	if s.HostKey != "" && s.KnownHosts != "" {
+		return fmt.Errorf("ssh host '%s': host_key and known_hosts are mutually exclusive", s.Host)
+	}
+	if s.Port == 0 {
+		s.Port = DefaultSSHPort
+	}
+	if s.Port < 0 || s.Port > 65535 {
+		return fmt.Errorf("ssh host '%s': invalid port %d", s.Host, s.Port)
+	}
+	s.ConnectTimeoutDuration = DefaultSSHConnectTimeout
+	if s.ConnectTimeout != "" {
+		d, err := time.ParseDuration(s.ConnectTimeout)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("ssh host '%s': invalid connect_timeout: %s", s.Host, s.ConnectTimeout)
+		}
+		s.ConnectTimeoutDuration = d
+	}
+	if s.Jump != nil {
+		if err := s.Jump.validate(); err != nil {
+			return fmt.Errorf("jump: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package config
+
+import "fmt"
+
+// DefaultStarlarkMaxSteps bounds the work a Starlark script can do per call
+const DefaultStarlarkMaxSteps = 10_000_000
+
+// StarlarkConfig runs a Starlark script as the tool body. Scripts cannot reach
+// the filesystem or start processes; they only see the http, json and run built-ins.
+type StarlarkConfig struct {
+	Source   string                  `yaml:"source" expand:"-"`  // Inline script defining main(params); not expanded
+	File     string                  `yaml:"file"`               // Path to the script, instead of source
+	MaxSteps uint64                  `yaml:"max_steps"`          // Execution step limit (default: 10,000,000)
+	Auth     map[string]*WebhookAuth `yaml:"auth,omitempty"`     // Credentials for http calls, selected with auth="name"
+	Retry    *WebhookRetry           `yaml:"retry,omitempty"`    // Retry policy for http calls
+	Client   *WebhookClient          `yaml:"client,omitempty"`   // HTTP client tuning for http calls
+	Commands []string                `yaml:"commands,omitempty"` // Commands the script may call with run()
+
+	// Resolved at load time from Commands
+	CommandRefs map[string]*Command `yaml:"-"`
+}
+
+// validate checks the script source and step limit
+func (s *StarlarkConfig) validate() error {
+	if (s.Source == "") == (s.File == "") {
+		return fmt.Errorf("starlark requires exactly one of source or file")
+	}
+	if s.MaxSteps == 0 {
+		s.MaxSteps = DefaultStarlarkMaxSteps
+	}
+	for name, auth := range s.Auth {
+		if auth == nil || auth.Type == "" {
+			return fmt.Errorf("starlark auth '%s' requires a type", name)
+		}
+	}
+	return nil
+}
+
+// validateStarlark resolves the commands a Starlark script may run
+func (c *Config) validateStarlark(cmd *Command) error {
+	cmd.Starlark.CommandRefs = make(map[string]*Command, len(cmd.Starlark.Commands))
+	for _, name := range cmd.Starlark.Commands {
+		if name == cmd.Name {
+			return fmt.Errorf("starlark command '%s' cannot run itself", name)
+		}
+		for i := range c.Commands {
+			if c.Commands[i].Name == name {
+				cmd.Starlark.CommandRefs[name] = &c.Commands[i]
+			}
+		}
+		if cmd.Starlark.CommandRefs[name] == nil {
+			return fmt.Errorf("starlark command '%s' not found", name)
+		}
+	}
+	return nil
+}
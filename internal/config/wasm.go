@@ -0,0 +1,42 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+)
+
+// WasmConfig runs a WASI module in-process. The module only sees the mounts,
+// environment and stdin configured here. Memory is the only resource limit set
+// here: wazero does not meter instructions, so there is no fuel limit, and the
+// command's timeout is what stops a module that runs too long.
+type WasmConfig struct {
+	Module string  `yaml:"module"`                  // Path to the .wasm file
+	Mounts []Mount `yaml:"mounts"`                  // Host directories preopened for the module at Target
+	Stdin  string  `yaml:"stdin" expand:"template"` // Template written to the module's stdin, e.g. '{{json .params}}'
+	Memory string  `yaml:"memory"`                  // Linear memory limit, e.g. 64m (default: the module's own maximum)
+}
+
+// validate checks the module path, mounts and memory limit
+func (w *WasmConfig) validate() error {
+	if w.Module == "" {
+		return fmt.Errorf("wasm module is required")
+	}
+	for _, mount := range w.Mounts {
+		if !filepath.IsAbs(mount.Source) {
+			return fmt.Errorf("wasm mount source '%s' must be absolute", mount.Source)
+		}
+		if !path.IsAbs(mount.Target) {
+			return fmt.Errorf("wasm mount target '%s' must be absolute", mount.Target)
+		}
+	}
+	if w.Memory != "" && !memoryPattern.MatchString(w.Memory) {
+		return fmt.Errorf("invalid wasm memory '%s'", w.Memory)
+	}
+	return nil
+}
+
+// MemoryBytes returns the linear memory limit in bytes, or 0 if unset
+func (w *WasmConfig) MemoryBytes() int64 {
+	return parseMemory(w.Memory)
+}
@@ -0,0 +1,192 @@
+package config
+
+import "fmt"
+
+// Step failure policies
+const (
+	OnFailureAbort      = "abort"      // Stop the workflow and fail it (default)
+	OnFailureContinue   = "continue"   // Record the failure and run the next steps
+	OnFailureCompensate = "compensate" // Run the step's compensating command, then fail the workflow
+)
+
+// WorkflowConfig chains other configured commands into a single tool
+type WorkflowConfig struct {
+	Steps  []WorkflowStep `yaml:"steps"`
+	Output string         `yaml:"output" expand:"template"` // Template for the tool output (default: the last step's stdout)
+}
+
+// WorkflowStep runs a configured command, or a group of steps in parallel.
+// String params are templates over the tool's params and earlier steps' results.
+type WorkflowStep struct {
+	ID         string                 `yaml:"id"`                       // Name later steps use to refer to the result (default: the command name)
+	Command    string                 `yaml:"command"`                  // Configured command to run
+	Params     map[string]interface{} `yaml:"params" expand:"template"` // Arguments for the command
+	Parallel   []WorkflowStep         `yaml:"parallel"`                 // Steps that run concurrently, instead of a command
+	OnFailure  string                 `yaml:"on_failure"`               // abort (default), continue or compensate
+	Compensate *WorkflowStep          `yaml:"compensate"`               // Step run when this one fails with on_failure: compensate
+
+	// Resolved at load time from Command
+	CommandRef *Command `yaml:"-"`
+}
+
+// validateWorkflow checks the steps and resolves the commands they run
+func (c *Config) validateWorkflow(cmd *Command) error {
+	if len(cmd.Workflow.Steps) == 0 {
+		return fmt.Errorf("workflow requires at least one step")
+	}
+	ids := make(map[string]bool)
+	for i := range cmd.Workflow.Steps {
+		step := &cmd.Workflow.Steps[i]
+		if len(step.Parallel) > 0 {
+			if step.Command != "" || step.ID != "" || len(step.Params) > 0 || step.Compensate != nil {
+				return fmt.Errorf("workflow step %d: a parallel group cannot set command, id, params or compensate", i+1)
+			}
+			if step.OnFailure != "" {
+				return fmt.Errorf("workflow step %d: set on_failure on the steps of a parallel group", i+1)
+			}
+			for j := range step.Parallel {
+				if len(step.Parallel[j].Parallel) > 0 {
+					return fmt.Errorf("workflow step %d: parallel groups cannot be nested", i+1)
+				}
+				if err := c.validateStep(cmd, &step.Parallel[j], ids); err != nil {
+					return fmt.Errorf("workflow step %d.%d: %w", i+1, j+1, err)
+				}
+			}
+			continue
+		}
+		if err := c.validateStep(cmd, step, ids); err != nil {
+			return fmt.Errorf("workflow step %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// validateStep checks a command step and its compensating step
+func (c *Config) validateStep(cmd *Command, step *WorkflowStep, ids map[string]bool) error {
+	if err := c.resolveStep(cmd, step); err != nil {
+		return err
+	}
+	if step.ID == "" {
+		step.ID = step.Command
+	}
+	if ids[step.ID] {
+		return fmt.Errorf("duplicate step id '%s'", step.ID)
+	}
+	ids[step.ID] = true
+
+	switch step.OnFailure {
+	case "":
+		step.OnFailure = OnFailureAbort
+	case OnFailureAbort, OnFailureContinue, OnFailureCompensate:
+	default:
+		return fmt.Errorf("unknown on_failure '%s', expected abort, continue or compensate", step.OnFailure)
+	}
+	if step.OnFailure == OnFailureCompensate && step.Compensate == nil {
+		return fmt.Errorf("on_failure: compensate requires a compensate step")
+	}
+	if step.Compensate != nil {
+		if step.OnFailure != OnFailureCompensate {
+			return fmt.Errorf("compensate requires on_failure: compensate")
+		}
+		compensate := step.Compensate
+		if len(compensate.Parallel) > 0 || compensate.Compensate != nil || compensate.OnFailure != "" {
+			return fmt.Errorf("compensate must be a single command step")
+		}
+		if err := c.resolveStep(cmd, compensate); err != nil {
+			return fmt.Errorf("compensate: %w", err)
+		}
+		if compensate.ID == "" {
+			compensate.ID = compensate.Command
+		}
+	}
+	return nil
+}
+
+// resolveStep finds the command a step runs and checks the params it passes
+func (c *Config) resolveStep(cmd *Command, step *WorkflowStep) error {
+	if step.Command == "" {
+		return fmt.Errorf("command is required")
+	}
+	if step.Command == cmd.Name {
+		return fmt.Errorf("workflow command '%s' cannot run itself", step.Command)
+	}
+	for i := range c.Commands {
+		if c.Commands[i].Name == step.Command {
+			step.CommandRef = &c.Commands[i]
+		}
+	}
+	if step.CommandRef == nil {
+		return fmt.Errorf("command '%s' not found", step.Command)
+	}
+	// Plugins may only advertise their parameters at startup
+	if step.CommandRef.IsPlugin() && len(step.CommandRef.Parameters) == 0 {
+		return nil
+	}
+	for name := range step.Params {
+		found := false
+		for _, param := range step.CommandRef.Parameters {
+			found = found || param.Name == name
+		}
+		if !found {
+			return fmt.Errorf("command '%s' has no parameter '%s'", step.Command, name)
+		}
+	}
+	return nil
+}
+
+// checkWorkflowCycles rejects workflows that run themselves through other workflows
+func (c *Config) checkWorkflowCycles() error {
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := make(map[*Command]int)
+	var visit func(cmd *Command) error
+	visit = func(cmd *Command) error {
+		switch state[cmd] {
+		case visiting:
+			return fmt.Errorf("command '%s': workflow runs itself through another workflow", cmd.Name)
+		case done:
+			return nil
+		}
+		state[cmd] = visiting
+		for _, step := range cmd.Workflow.allSteps() {
+			if step.CommandRef.IsWorkflow() {
+				if err := visit(step.CommandRef); err != nil {
+					return err
+				}
+			}
+		}
+		state[cmd] = done
+		return nil
+	}
+	for i := range c.Commands {
+		if c.Commands[i].IsWorkflow() {
+			if err := visit(&c.Commands[i]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// allSteps returns every command step, including parallel and compensating ones
+func (w *WorkflowConfig) allSteps() []*WorkflowStep {
+	var steps []*WorkflowStep
+	var add func(step *WorkflowStep)
+	add = func(step *WorkflowStep) {
+		if len(step.Parallel) == 0 {
+			steps = append(steps, step)
+		}
+		for i := range step.Parallel {
+			add(&step.Parallel[i])
+		}
+		if step.Compensate != nil {
+			add(step.Compensate)
+		}
+	}
+	for i := range w.Steps {
+		add(&w.Steps[i])
+	}
+	return steps
+}
@@ -0,0 +1,190 @@
+// Package cron parses standard five-field cron expressions and computes when
+// they next fire.
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// field is the valid range of a cron field and the names its values may use
+type field struct {
+	name     string
+	min, max int
+	names    map[string]int
+}
+
+var fields = []field{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}},
+	// 7 is Sunday too
+	{name: "day of week", min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}},
+}
+
+// macros are the supported @ shorthands
+var macros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// maxSearch bounds the search for the next activation, for expressions such
+// as "0 0 30 2 *" that never fire
+const maxSearch = 5 * 366 * 24 * time.Hour
+
+// Schedule is a parsed cron expression
+type Schedule struct {
+	minute, hour, dom, month, dow uint64 // Bit n is set when value n matches
+
+	// Whether day of month and day of week are "*". When both are restricted,
+	// a day matches if either does, as in Vixie cron.
+	domStar, dowStar bool
+}
+
+// Parse parses a cron expression with minute, hour, day of month, month and
+// day of week fields, or one of @yearly, @monthly, @weekly, @daily and @hourly.
+// Fields accept *, values, ranges (1-5), steps (*/15, 0-30/10) and lists, and
+// months and weekdays accept three-letter names.
+func Parse(expr string) (*Schedule, error) {
+	expr = strings.TrimSpace(expr)
+	if macro, ok := macros[strings.ToLower(expr)]; ok {
+		expr = macro
+	}
+	parts := strings.Fields(expr)
+	if len(parts) != len(fields) {
+		return nil, fmt.Errorf("cron expression '%s' must have 5 fields, got %d", expr, len(parts))
+	}
+
+	var bits [5]uint64
+	for i, part := range parts {
+		b, err := fields[i].parse(part)
+		if err != nil {
+			return nil, fmt.Errorf("cron expression '%s': %s: %w", expr, fields[i].name, err)
+		}
+		bits[i] = b
+	}
+	// Sunday may be written as 0 or 7
+	if bits[4]&(1<<7) != 0 {
+		bits[4] |= 1
+	}
+
+	return &Schedule{
+		minute:  bits[0],
+		hour:    bits[1],
+		dom:     bits[2],
+		month:   bits[3],
+		dow:     bits[4],
+		domStar: parts[2] == "*",
+		dowStar: parts[4] == "*",
+	}, nil
+}
+
+// parse returns the bitset of values a field expression matches
+func (f field) parse(expr string) (uint64, error) {
+	var bits uint64
+	for _, item := range strings.Split(expr, ",") {
+		rangeExpr, stepExpr, hasStep := strings.Cut(item, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepExpr)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step '%s'", stepExpr)
+			}
+			step = n
+		}
+
+		var low, high int
+		switch lowExpr, highExpr, isRange := strings.Cut(rangeExpr, "-"); {
+		case rangeExpr == "*":
+			low, high = f.min, f.max
+		case isRange:
+			var err error
+			if low, err = f.value(lowExpr); err != nil {
+				return 0, err
+			}
+			if high, err = f.value(highExpr); err != nil {
+				return 0, err
+			}
+			if low > high {
+				return 0, fmt.Errorf("invalid range '%s'", rangeExpr)
+			}
+		default:
+			var err error
+			if low, err = f.value(rangeExpr); err != nil {
+				return 0, err
+			}
+			high = low
+			// "5/15" means from 5 to the end of the range every 15
+			if hasStep {
+				high = f.max
+			}
+		}
+
+		for v := low; v <= high; v += step {
+			bits |= 1 << v
+		}
+	}
+	return bits, nil
+}
+
+// value parses a single number or name within the field's range
+func (f field) value(s string) (int, error) {
+	if v, ok := f.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value '%s'", s)
+	}
+	if v < f.min || v > f.max {
+		return 0, fmt.Errorf("value %d out of range %d-%d", v, f.min, f.max)
+	}
+	return v, nil
+}
+
+// Next returns the first activation after t, in t's location, or the zero
+// time if the schedule never fires
+func (s *Schedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute()+1, 0, 0, loc)
+	limit := t.Add(maxSearch)
+
+	for t.Before(limit) {
+		switch {
+		case s.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		case s.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+		case s.minute&(1<<uint(t.Minute())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute()+1, 0, 0, loc)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// dayMatches applies the day of month and day of week fields
+func (s *Schedule) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domStar || s.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
@@ -0,0 +1,87 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNext(t *testing.T) {
+	// Friday
+	from := time.Date(2026, time.January, 16, 10, 7, 30, 0, time.UTC)
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2026, 1, 16, 10, 8, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2026, 1, 16, 10, 15, 0, 0, time.UTC)},
+		{"5/20 * * * *", time.Date(2026, 1, 16, 10, 25, 0, 0, time.UTC)},
+		{"0 9-17/4 * * *", time.Date(2026, 1, 16, 13, 0, 0, 0, time.UTC)},
+		{"30 2 * * *", time.Date(2026, 1, 17, 2, 30, 0, 0, time.UTC)},
+		{"0 0 1,15 * *", time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 12 * * mon-wed", time.Date(2026, 1, 19, 12, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2026, 1, 18, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * JUN *", time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		// Day of month or day of week when both are restricted
+		{"0 0 20 * sat", time.Date(2026, 1, 17, 0, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2026, 1, 16, 11, 0, 0, 0, time.UTC)},
+		{"@weekly", time.Date(2026, 1, 18, 0, 0, 0, 0, time.UTC)},
+		{"@yearly", time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			schedule, err := Parse(tt.expr)
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+			if got := schedule.Next(from); !got.Equal(tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestNextLocation(t *testing.T) {
+	loc := time.FixedZone("UTC+2", 2*60*60)
+	schedule, err := Parse("0 9 * * *")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	got := schedule.Next(time.Date(2026, 1, 16, 8, 0, 0, 0, time.UTC).In(loc))
+	if want := time.Date(2026, 1, 17, 7, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestNextNever(t *testing.T) {
+	schedule, err := Parse("0 0 30 2 *")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if got := schedule.Next(time.Now()); !got.IsZero() {
+		t.Errorf("Expected no activation, got %v", got)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	invalid := []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+		"* * * * funday",
+		"@reboot",
+	}
+	for _, expr := range invalid {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("Expected error for '%s'", expr)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package executor
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gleicon/mcpfier/internal/config"
+)
+
+// maxArtifactSize is the largest file returned as an artifact
+const maxArtifactSize = 10 * 1024 * 1024
+
+// containerOutputDir is where the run's output directory is mounted inside containers
+const containerOutputDir = "/mcpfier/output"
+
+// outputDirEnv tells commands where to write their artifacts
+const outputDirEnv = "MCPFIER_OUTPUT_DIR"
+
+// Artifact is a binary output of a command, such as an image or a PDF
+type Artifact struct {
+	Name     string
+	MIMEType string
+	Data     []byte
+}
+
+// newOutputDir creates a private output directory for a run of a command that
+// declares artifacts. It returns "" if the command has none.
+func newOutputDir(cmd *config.Command) (string, error) {
+	if len(cmd.Artifacts) == 0 {
+		return "", nil
+	}
+	dir, err := os.MkdirTemp("", "mcpfier-output-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create output directory: %w", err)
+	}
+	return dir, nil
+}
+
+// collectArtifacts reads the declared artifacts from dir. Every pattern must match at least one file.
+func collectArtifacts(dir string, specs []config.Artifact) ([]Artifact, error) {
+	var artifacts []Artifact
+	for _, spec := range specs {
+		matches, err := filepath.Glob(filepath.Join(dir, spec.Path))
+		if err != nil {
+			return artifacts, fmt.Errorf("invalid artifact pattern '%s': %w", spec.Path, err)
+		}
+		if len(matches) == 0 {
+			return artifacts, fmt.Errorf("artifact '%s' was not produced", spec.Path)
+		}
+
+		for _, path := range matches {
+			info, err := os.Stat(path)
+			if err != nil {
+				return artifacts, fmt.Errorf("failed to read artifact: %w", err)
+			}
+			if info.IsDir() {
+				continue
+			}
+			if info.Size() > maxArtifactSize {
+				return artifacts, fmt.Errorf("artifact '%s' is %d bytes, larger than %d", info.Name(), info.Size(), maxArtifactSize)
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return artifacts, fmt.Errorf("failed to read artifact: %w", err)
+			}
+			mimeType := spec.MIMEType
+			if mimeType == "" {
+				mimeType = detectMIMEType(path, data)
+			}
+			name, _ := filepath.Rel(dir, path)
+			artifacts = append(artifacts, Artifact{
+				Name:     filepath.ToSlash(name),
+				MIMEType: mimeType,
+				Data:     data,
+			})
+		}
+	}
+	return artifacts, nil
+}
+
+// detectMIMEType sniffs the content type from the bytes, falling back to the file
+// extension when sniffing only finds generic text or binary data
+func detectMIMEType(name string, data []byte) string {
+	detected := mediaType(http.DetectContentType(data))
+	if detected != "application/octet-stream" && detected != "text/plain" {
+		return detected
+	}
+	if byExtension := mediaType(mime.TypeByExtension(filepath.Ext(name))); byExtension != "" {
+		return byExtension
+	}
+	return detected
+}
+
+// mediaType strips parameters such as charset from a content type
+func mediaType(contentType string) string {
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		return mediaType
+	}
+	return strings.TrimSpace(strings.Split(contentType, ";")[0])
+}
+
+// isTextual reports whether a response content type should be returned as text
+func isTextual(mediaType string) bool {
+	switch {
+	case mediaType == "",
+		strings.HasPrefix(mediaType, "text/"),
+		strings.HasSuffix(mediaType, "+json"),
+		strings.HasSuffix(mediaType, "+xml"):
+		return true
+	}
+	switch mediaType {
+	case "application/json", "application/xml", "application/javascript",
+		"application/x-www-form-urlencoded", "application/x-ndjson", "application/yaml":
+		return true
+	}
+	return false
+}
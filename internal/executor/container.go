@@ -2,46 +2,157 @@ package executor
 
 import (
 	"context"
+	"crypto/rand"
 	"fmt"
-	"os/exec"
+	"os"
+	"sort"
+	"sync"
 
 	"github.com/gleicon/mcpfier/internal/config"
 )
 
-// ContainerExecutor executes commands in Docker containers
+// ContainerExecutor executes commands in containers using a ContainerRuntime
 type ContainerExecutor struct {
 	localExecutor *LocalExecutor
+	runtime       ContainerRuntime
+
+	mu    sync.Mutex
+	pools map[string]*containerPool // Warm pools by command name
 }
 
-// NewContainerExecutor creates a new container executor
+// NewContainerExecutor creates a new container executor using the docker CLI
 func NewContainerExecutor() *ContainerExecutor {
 	return &ContainerExecutor{
 		localExecutor: NewLocalExecutor(),
+		runtime:       NewCLIRuntime(config.RuntimeDocker),
 	}
 }
 
-// Execute runs a command in a Docker container, with fallback to local execution
-func (e *ContainerExecutor) Execute(ctx context.Context, cmd *config.Command) (string, error) {
+// Execute runs a command in its container; commands without one run locally.
+// Fallback policies for failed container runs are applied by Service.
+func (e *ContainerExecutor) Execute(ctx context.Context, cmd *config.Command, params map[string]interface{}) (*Result, error) {
 	if cmd.Container == "" {
-		return e.localExecutor.Execute(ctx, cmd)
-	}
-	
-	// Build docker run command
-	dockerArgs := []string{"run", "--rm"}
-	
-	// Add environment variables
-	for k, v := range cmd.Env {
-		dockerArgs = append(dockerArgs, "-e", fmt.Sprintf("%s=%s", k, v))
-	}
-	
-	// Add container image
-	dockerArgs = append(dockerArgs, cmd.Container)
-	
-	// Add script and args
-	dockerArgs = append(dockerArgs, cmd.Script)
-	dockerArgs = append(dockerArgs, cmd.Args...)
-	
-	execCmd := exec.CommandContext(ctx, "docker", dockerArgs...)
-	output, err := execCmd.CombinedOutput()
-	return string(output), err
-}
\ No newline at end of file
+		return e.localExecutor.Execute(ctx, cmd, params)
+	}
+
+	e.mu.Lock()
+	pool := e.pools[cmd.Name]
+	e.mu.Unlock()
+	if pool != nil {
+		return pool.execute(ctx, params)
+	}
+
+	outputDir, err := newOutputDir(cmd)
+	if err != nil {
+		return nil, err
+	}
+	if outputDir != "" {
+		defer os.RemoveAll(outputDir)
+		// The container user is not necessarily the server's user
+		os.Chmod(outputDir, 0777)
+	}
+
+	data := templateData(params)
+	if outputDir != "" {
+		data["output_dir"] = containerOutputDir
+	}
+	args, err := renderArgs(cmd.Args, data)
+	if err != nil {
+		return nil, err
+	}
+	env, err := renderEnv(cmd.Env, data)
+	if err != nil {
+		return nil, err
+	}
+
+	spec := &ContainerSpec{
+		// Name the container so it can be removed if the deadline expires or the call is cancelled
+		Name:      containerName(),
+		Image:     cmd.Container,
+		Env:       env,
+		KillGrace: cmd.GetKillGrace(),
+	}
+	if cmd.ContainerOptions != nil {
+		spec.Options = *cmd.ContainerOptions
+	}
+
+	// Mount the output directory for artifacts
+	if outputDir != "" {
+		spec.Options.Mounts = append(append([]config.Mount(nil), spec.Options.Mounts...),
+			config.Mount{Source: outputDir, Target: containerOutputDir})
+		spec.Env[outputDirEnv] = containerOutputDir
+	}
+
+	// Without a script the image entrypoint/command runs
+	if cmd.Script != "" {
+		spec.Command = append(spec.Command, cmd.Script)
+	}
+	spec.Command = append(spec.Command, args...)
+
+	result, err := e.runtime.Run(ctx, spec)
+	if err == nil && outputDir != "" {
+		result.Artifacts, err = collectArtifacts(outputDir, cmd.Artifacts)
+	}
+	return result, err
+}
+
+// StartPools starts warm pools for the commands that configure one
+func (e *ContainerExecutor) StartPools(commands []config.Command) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, cmd := range commands {
+		if cmd.Pool == nil || cmd.Container == "" || e.pools[cmd.Name] != nil {
+			continue
+		}
+		runtime, ok := e.runtime.(PooledRuntime)
+		if !ok {
+			return fmt.Errorf("command '%s': container runtime does not support pools", cmd.Name)
+		}
+		if e.pools == nil {
+			e.pools = make(map[string]*containerPool)
+		}
+		e.pools[cmd.Name] = newContainerPool(runtime, cmd)
+	}
+	return nil
+}
+
+// PoolStatus reports the state of every warm pool
+func (e *ContainerExecutor) PoolStatus() []PoolStatus {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	statuses := make([]PoolStatus, 0, len(e.pools))
+	for _, pool := range e.pools {
+		statuses = append(statuses, pool.status())
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Command < statuses[j].Command
+	})
+	return statuses
+}
+
+// Close drains all warm pools, removing their containers
+func (e *ContainerExecutor) Close() {
+	e.mu.Lock()
+	pools := e.pools
+	e.pools = nil
+	e.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, pool := range pools {
+		wg.Add(1)
+		go func(pool *containerPool) {
+			defer wg.Done()
+			pool.close()
+		}(pool)
+	}
+	wg.Wait()
+}
+
+// containerName generates a unique name for a container run
+func containerName() string {
+	b := make([]byte, 6)
+	rand.Read(b)
+	return fmt.Sprintf("mcpfier-%x", b)
+}
@@ -3,34 +3,88 @@ package executor
 import (
 	"context"
 	"crypto/rand"
+	"errors"
 	"fmt"
+	"log"
 	"time"
 
 	"github.com/gleicon/mcpfier/internal/analytics"
 	"github.com/gleicon/mcpfier/internal/config"
 )
 
-// Executor defines the interface for command execution
+// Executor defines the interface for command execution; custom executors are added with Register.
+// params holds the validated tool arguments (see config.Command.ResolveArguments).
 type Executor interface {
-	Execute(ctx context.Context, cmd *config.Command) (string, error)
+	Execute(ctx context.Context, cmd *config.Command, params map[string]interface{}) (*Result, error)
 }
 
 // Service handles command execution with fallback strategies
 type Service struct {
-	local     *LocalExecutor
+	executors map[string]Executor // Built-in executors by mode
 	container *ContainerExecutor
+	sql       *SQLExecutor
 	webhook   *WebhookExecutor
 	analytics analytics.Analytics
 }
 
-// New creates a new executor service
+// New creates a new executor service with its own instance of each built-in executor.
+// Commands run from Starlark scripts and workflows go through Execute for timeouts,
+// fallback and analytics.
 func New() *Service {
-	return &Service{
-		local:     NewLocalExecutor(),
+	s := &Service{
+		executors: make(map[string]Executor, len(builtins)),
 		container: NewContainerExecutor(),
+		sql:       NewSQLExecutor(),
 		webhook:   NewWebhookExecutor(),
 		analytics: &analytics.NoOpAnalytics{},
 	}
+	for _, b := range builtins {
+		s.executors[b.name] = b.newExecutor(s)
+	}
+	return s
+}
+
+// NewFromConfig creates the executor service for a configuration: it selects
+// the container runtime and has plugins describe themselves. Long-running
+// servers also start the warm container pools; one-off runs leave them stopped.
+func NewFromConfig(cfg *config.Config, analyticsService analytics.Analytics, startPools bool) *Service {
+	s := New().WithAnalytics(analyticsService)
+	if runtime, err := NewContainerRuntime(cfg.ContainerRuntime); err == nil {
+		s.WithContainerRuntime(runtime)
+	} else {
+		log.Printf("Container runtime unavailable, falling back to the docker CLI: %v", err)
+	}
+	if startPools {
+		if err := s.StartPools(cfg.Commands); err != nil {
+			log.Printf("Warm container pools disabled: %v", err)
+		}
+	}
+	if err := DescribePlugins(cfg); err != nil {
+		log.Printf("Plugins failed to describe themselves: %v", err)
+	}
+	return s
+}
+
+// WithContainerRuntime sets the runtime used for containerized commands
+func (s *Service) WithContainerRuntime(runtime ContainerRuntime) *Service {
+	s.container.runtime = runtime
+	return s
+}
+
+// StartPools starts warm container pools for commands that configure one
+func (s *Service) StartPools(commands []config.Command) error {
+	return s.container.StartPools(commands)
+}
+
+// PoolStatus reports the state of the warm container pools
+func (s *Service) PoolStatus() []PoolStatus {
+	return s.container.PoolStatus()
+}
+
+// Close drains the warm container pools and closes database connections
+func (s *Service) Close() {
+	s.container.Close()
+	s.sql.Close()
 }
 
 // WithAnalytics sets the analytics instance
@@ -39,38 +93,140 @@ func (s *Service) WithAnalytics(a analytics.Analytics) *Service {
 	return s
 }
 
-// Execute runs a command using the appropriate executor
-func (s *Service) Execute(ctx context.Context, cmd *config.Command) (string, error) {
+// Execute runs a command using the appropriate executor with already validated arguments.
+// Containerized commands with a fallback policy are re-run locally or as another
+// command when the container fails to run for one of the configured reasons.
+func (s *Service) Execute(ctx context.Context, cmd *config.Command, params map[string]interface{}) (*Result, error) {
 	sessionID := getSessionID(ctx)
 	start := time.Now()
-	
-	var output string
-	var err error
-	
-	if cmd.IsWebhook() {
-		output, err = s.webhook.Execute(ctx, cmd)
-	} else if cmd.IsContainerized() {
-		output, err = s.container.Execute(ctx, cmd)
-	} else {
-		output, err = s.local.Execute(ctx, cmd)
+
+	// Commands run by this one, from a workflow or Starlark script, record it as their parent
+	runID := newRunID()
+	parentRunID, _ := ctx.Value(runIDKey{}).(string)
+	ctx = context.WithValue(ctx, runIDKey{}, runID)
+
+	mode := executionMode(cmd)
+	result, err := s.run(ctx, cmd, mode, params)
+
+	fallback := ""
+	if failure := failureClass(err); failure != "" && cmd.FallsBackOn(failure) {
+		target, targetParams, ferr := fallbackTarget(cmd, params)
+		if ferr != nil {
+			err = fmt.Errorf("%w; fallback to %s failed: %v", err, cmd.Fallback, ferr)
+		} else {
+			log.Printf("Command %s: %v, falling back to %s", cmd.Name, err, cmd.Fallback)
+			fallback = failure
+			mode = executionMode(target)
+			result, err = s.run(ctx, target, mode, targetParams)
+		}
 	}
+
+	// Callers always get a result, even when execution failed before starting
+	if result == nil {
+		result = &Result{ExitCode: -1}
+	}
+	result.Duration = time.Since(start)
+
+	cancelled := errors.Is(err, ErrCancelled)
 	
 	// Record analytics
 	s.analytics.RecordCommand(ctx, analytics.CommandEvent{
 		SessionID:     sessionID,
 		CommandName:   cmd.Name,
-		Duration:      time.Since(start),
+		Duration:      result.Duration,
 		Success:       err == nil,
-		OutputSize:    int64(len(output)),
-		ExecutionMode: getExecutionMode(cmd),
+		OutputSize:    result.Size(),
+		ExecutionMode: mode,
+		ExitCode:      result.ExitCode,
+		Cancelled:     cancelled,
+		Fallback:      fallback,
 		Error:         getErrorString(err),
+		RunID:         runID,
+		ParentRunID:   parentRunID,
 	})
 	
-	return output, err
+	return result, err
+}
+
+// run executes a command once on the executor for mode with its timeout applied,
+// without analytics or fallback
+func (s *Service) run(ctx context.Context, cmd *config.Command, mode string, params map[string]interface{}) (*Result, error) {
+	executor, err := s.executor(mode)
+	if err != nil {
+		return nil, err
+	}
+
+	// Apply the configured timeout as a deadline for every executor
+	runCtx := ctx
+	if cmd.TimeoutDuration > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(ctx, cmd.TimeoutDuration)
+		defer cancel()
+	}
+	
+	result, err := executor.Execute(runCtx, cmd, params)
+
+	if err != nil && errors.Is(ctx.Err(), context.Canceled) {
+		err = ErrCancelled
+	} else if err != nil && cmd.TimeoutDuration > 0 && errors.Is(runCtx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("%w after %s", ErrTimeout, cmd.TimeoutDuration)
+	}
+	return result, err
+}
+
+// failureClass maps a container failure to the fallback_on class it belongs to
+func failureClass(err error) string {
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, ErrTimeout):
+		return config.FailureTimeout
+	case errors.Is(err, ErrRuntimeUnavailable):
+		return config.FailureUnavailable
+	case errors.Is(err, ErrImagePull):
+		return config.FailurePull
+	}
+	return ""
+}
+
+// fallbackTarget returns the command to run instead of cmd and its arguments.
+// A fallback command's own fallback policy is not applied.
+func fallbackTarget(cmd *config.Command, params map[string]interface{}) (*config.Command, map[string]interface{}, error) {
+	if cmd.Fallback == config.FallbackLocal {
+		local := *cmd
+		local.Container = ""
+		local.ContainerOptions = nil
+		local.Pool = nil
+		local.Kubernetes = nil
+		local.Fallback = ""
+		return &local, params, nil
+	}
+
+	if cmd.FallbackCommand == nil {
+		return nil, nil, fmt.Errorf("fallback command '%s' not found", cmd.Fallback)
+	}
+	target := *cmd.FallbackCommand
+	target.Fallback = ""
+	targetParams, err := target.ResolveArguments(params)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &target, targetParams, nil
+}
+
+// sessionIDKey is the context key for the analytics session of a call
+type sessionIDKey struct{}
+
+// WithSessionID returns a context whose executions are recorded under session id
+func WithSessionID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, sessionIDKey{}, id)
 }
 
 // getSessionID gets or creates a session ID from context
 func getSessionID(ctx context.Context) string {
+	if sessionID, ok := ctx.Value(sessionIDKey{}).(string); ok {
+		return sessionID
+	}
 	if sessionID, ok := ctx.Value("session_id").(string); ok {
 		return sessionID
 	}
@@ -81,14 +237,14 @@ func getSessionID(ctx context.Context) string {
 	return fmt.Sprintf("%x", b)
 }
 
-// getExecutionMode returns the execution mode string
-func getExecutionMode(cmd *config.Command) string {
-	if cmd.IsWebhook() {
-		return "webhook"
-	} else if cmd.IsContainerized() {
-		return "container"
-	}
-	return "local"
+// runIDKey holds the run ID of the command executing in the context
+type runIDKey struct{}
+
+// newRunID returns a random identifier for one execution of a command
+func newRunID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return fmt.Sprintf("%x", b)
 }
 
 // getErrorString safely converts error to string
@@ -99,8 +255,8 @@ func getErrorString(err error) string {
 	return err.Error()
 }
 
-// ExecuteByName finds and executes a command by name from config
-func (s *Service) ExecuteByName(ctx context.Context, cfg *config.Config, commandName string) (string, error) {
+// ExecuteByName finds a command by name from config, validates the arguments and executes it
+func (s *Service) ExecuteByName(ctx context.Context, cfg *config.Config, commandName string, arguments map[string]interface{}) (*Result, error) {
 	var foundCmd *config.Command
 	for _, cmd := range cfg.Commands {
 		if cmd.Name == commandName {
@@ -110,8 +266,13 @@ func (s *Service) ExecuteByName(ctx context.Context, cfg *config.Config, command
 	}
 
 	if foundCmd == nil {
-		return "", fmt.Errorf("command '%s' not found", commandName)
+		return nil, fmt.Errorf("command '%s' not found", commandName)
+	}
+
+	params, err := foundCmd.ResolveArguments(arguments)
+	if err != nil {
+		return nil, err
 	}
 
-	return s.Execute(ctx, foundCmd)
+	return s.Execute(ctx, foundCmd, params)
 }
\ No newline at end of file
@@ -2,14 +2,46 @@ package executor
 
 import (
 	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/gleicon/mcpfier/internal/analytics"
 	"github.com/gleicon/mcpfier/internal/config"
+	"github.com/gleicon/mcpfier/internal/sandbox"
 )
 
+func TestMain(m *testing.M) {
+	// Plugin tests run the test binary as the plugin
+	if os.Getenv(testPluginEnv) != "" {
+		runTestPlugin()
+		os.Exit(0)
+	}
+	// Sandboxed tests re-execute the test binary as the sandbox stages
+	sandbox.Init()
+	os.Exit(m.Run())
+}
+
 func TestLocalExecutor(t *testing.T) {
 	executor := NewLocalExecutor()
-	
+
 	cmd := &config.Command{
 		Name:   "echo-test",
 		Script: "echo",
@@ -17,21 +49,21 @@ func TestLocalExecutor(t *testing.T) {
 	}
 
 	ctx := context.Background()
-	output, err := executor.Execute(ctx, cmd)
-	
+	output, err := executor.Execute(ctx, cmd, nil)
+
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
 	}
 
 	expectedOutput := "Hello, World!\n"
-	if output != expectedOutput {
-		t.Errorf("Expected output '%s', got '%s'", expectedOutput, output)
+	if output.Stdout != expectedOutput {
+		t.Errorf("Expected output '%s', got '%s'", expectedOutput, output.Stdout)
 	}
 }
 
 func TestExecutorService(t *testing.T) {
 	service := New()
-	
+
 	cfg := &config.Config{
 		Commands: []config.Command{
 			{
@@ -43,21 +75,1689 @@ func TestExecutorService(t *testing.T) {
 	}
 
 	ctx := context.Background()
-	
+
 	// Test ExecuteByName
-	output, err := service.ExecuteByName(ctx, cfg, "test-echo")
+	output, err := service.ExecuteByName(ctx, cfg, "test-echo", nil)
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
 	}
-	
+
 	expectedOutput := "test output\n"
-	if output != expectedOutput {
-		t.Errorf("Expected output '%s', got '%s'", expectedOutput, output)
+	if output.Stdout != expectedOutput {
+		t.Errorf("Expected output '%s', got '%s'", expectedOutput, output.Stdout)
 	}
-	
+
 	// Test command not found
-	_, err = service.ExecuteByName(ctx, cfg, "nonexistent")
+	_, err = service.ExecuteByName(ctx, cfg, "nonexistent", nil)
 	if err == nil {
 		t.Error("Expected error for nonexistent command")
 	}
-}
\ No newline at end of file
+}
+
+func TestLocalExecutorTemplating(t *testing.T) {
+	executor := NewLocalExecutor()
+
+	cmd := &config.Command{
+		Name:   "echo-param",
+		Script: "echo",
+		Args:   []string{"{{.params.greeting}}; rm -rf /", "{{join .params.names \" \"}}"},
+	}
+
+	params := map[string]interface{}{
+		"greeting": "hello $(whoami)",
+		"names":    []interface{}{"ana", "bo"},
+	}
+	output, err := executor.Execute(context.Background(), cmd, params)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expectedOutput := "hello $(whoami); rm -rf / ana bo\n"
+	if output.Stdout != expectedOutput {
+		t.Errorf("Expected output '%s', got '%s'", expectedOutput, output.Stdout)
+	}
+
+	// Config values substituted from ${VAR} are escaped at load and render as themselves
+	cmd.Args = []string{`{{"{{"}}.params.greeting}}`}
+	output, err = executor.Execute(context.Background(), cmd, params)
+	if err != nil || output.Stdout != "{{.params.greeting}}\n" {
+		t.Errorf("Expected escaped template to render literally, got '%s', %v", output.Stdout, err)
+	}
+	if got, err := renderTemplate("url", `https://example.com/{{"{{"}}x}}`, templateData(nil), urlEscape); err != nil || got != "https://example.com/{{x}}" {
+		t.Errorf("Expected constant action to be inserted unescaped, got '%s', %v", got, err)
+	}
+}
+
+func TestWebhookExecutorTemplating(t *testing.T) {
+	var gotPath, gotQuery, gotBody, gotHeader string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		gotPath = r.URL.EscapedPath()
+		gotQuery = r.URL.Query().Get("q")
+		gotBody = string(body)
+		gotHeader = r.Header.Get("X-City")
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	executor := NewWebhookExecutor()
+	cmd := &config.Command{
+		Name: "templated",
+		Webhook: &config.WebhookConfig{
+			URL:        server.URL + "/cities/{{.params.city}}?q={{.params.city}}",
+			Method:     "POST",
+			Headers:    map[string]string{"X-City": "{{.params.city}}"},
+			Body:       `{"city": "{{.params.city}}", "tags": {{json .params.tags}}}`,
+			BodyFormat: "json",
+		},
+	}
+
+	params := map[string]interface{}{
+		"city": `São "Paulo"&x=1`,
+		"tags": []interface{}{"a", "b"},
+	}
+	if _, err := executor.Execute(context.Background(), cmd, params); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if gotPath != "/cities/S%C3%A3o%20%22Paulo%22%26x%3D1" {
+		t.Errorf("Unexpected escaped path: %s", gotPath)
+	}
+	if gotQuery != `São "Paulo"&x=1` {
+		t.Errorf("Unexpected query value: %s", gotQuery)
+	}
+	if gotHeader != `São "Paulo"&x=1` {
+		t.Errorf("Unexpected header value: %s", gotHeader)
+	}
+	if gotBody != `{"city": "São \"Paulo\"&x=1", "tags": ["a","b"]}` {
+		t.Errorf("Unexpected body: %s", gotBody)
+	}
+
+	// Header values must not inject extra headers
+	params["city"] = "x\r\nX-Evil: 1"
+	if _, err := executor.Execute(context.Background(), cmd, params); err == nil {
+		t.Error("Expected error for header value with line break")
+	}
+
+	// A JSON template that renders to invalid JSON is rejected before sending
+	cmd.Webhook.Body = `{"city": {{.params.city}}}`
+	params["city"] = "unquoted"
+	if _, err := executor.Execute(context.Background(), cmd, params); err == nil {
+		t.Error("Expected error for body that renders to invalid JSON")
+	}
+}
+
+func TestWebhookExecutorErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "no such city", http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	cmd := &config.Command{Name: "missing", Webhook: &config.WebhookConfig{URL: server.URL}}
+	result, err := NewWebhookExecutor().Execute(context.Background(), cmd, nil)
+	if err == nil {
+		t.Fatal("Expected error for HTTP 404")
+	}
+	if result.HTTPStatus != http.StatusNotFound || result.ExitCode == 0 || result.Stdout != "no such city\n" {
+		t.Errorf("Expected failed result with status and body, got %+v", result)
+	}
+}
+
+func TestServiceTimeoutKillsProcessGroup(t *testing.T) {
+	service := New()
+
+	// The shell ignores SIGTERM, so only the SIGKILL escalation can stop it
+	cmd := &config.Command{
+		Name:              "hang",
+		Script:            "sh",
+		Args:              []string{"-c", "trap '' TERM; echo started; sleep 30 & wait"},
+		TimeoutDuration:   200 * time.Millisecond,
+		KillGraceDuration: 200 * time.Millisecond,
+	}
+
+	start := time.Now()
+	output, err := service.Execute(context.Background(), cmd, nil)
+	if !errors.Is(err, ErrTimeout) {
+		t.Fatalf("Expected timeout error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected process to be killed promptly, took %s", elapsed)
+	}
+	if output.Stdout != "started\n" {
+		t.Errorf("Expected partial output 'started\\n', got '%s'", output.Stdout)
+	}
+	if output.Signal != "SIGKILL" {
+		t.Errorf("Expected SIGKILL, got '%s'", output.Signal)
+	}
+}
+
+func TestWebhookExecutorPerCommandClients(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			time.Sleep(300 * time.Millisecond)
+		}
+		w.Write([]byte(strings.Repeat("x", 64)))
+	}))
+	defer server.Close()
+
+	executor := NewWebhookExecutor()
+	noRetry := &config.WebhookRetry{MaxRetries: 1, Delay: "1ms", StatusCodes: []int{599}}
+
+	short := &config.Command{
+		Name: "short",
+		Webhook: &config.WebhookConfig{
+			URL:    server.URL + "/slow",
+			Retry:  noRetry,
+			Client: &config.WebhookClient{TimeoutDuration: 50 * time.Millisecond},
+		},
+	}
+	long := &config.Command{
+		Name: "long",
+		Webhook: &config.WebhookConfig{
+			URL:   server.URL + "/slow",
+			Retry: noRetry,
+		},
+	}
+	limited := &config.Command{
+		Name: "limited",
+		Webhook: &config.WebhookConfig{
+			URL:    server.URL,
+			Client: &config.WebhookClient{MaxResponseSize: 16},
+		},
+	}
+
+	var wg sync.WaitGroup
+	errs := make(map[string]error)
+	var mu sync.Mutex
+	for _, cmd := range []*config.Command{short, long} {
+		wg.Add(1)
+		go func(cmd *config.Command) {
+			defer wg.Done()
+			_, err := executor.Execute(context.Background(), cmd, nil)
+			mu.Lock()
+			errs[cmd.Name] = err
+			mu.Unlock()
+		}(cmd)
+	}
+	wg.Wait()
+
+	if errs["short"] == nil {
+		t.Error("Expected short client timeout to fail the slow request")
+	}
+	if errs["long"] != nil {
+		t.Errorf("Expected default client to be unaffected by other commands, got %v", errs["long"])
+	}
+	if executor.clientFor(short.Name, short.Webhook.Client) == executor.clientFor(long.Name, long.Webhook.Client) {
+		t.Error("Expected separate clients per command")
+	}
+
+	output, err := executor.Execute(context.Background(), limited, nil)
+	if err == nil || len(output.Stdout) != 16 || !output.StdoutTruncated {
+		t.Errorf("Expected response size error with 16 truncated bytes, got %d bytes, err %v", len(output.Stdout), err)
+	}
+}
+
+func TestLocalExecutorSeparatesStreams(t *testing.T) {
+	executor := NewLocalExecutor()
+
+	cmd := &config.Command{
+		Name:   "streams",
+		Script: "sh",
+		Args:   []string{"-c", "echo out; echo err >&2; exit 3"},
+	}
+
+	result, err := executor.Execute(context.Background(), cmd, nil)
+	if err == nil {
+		t.Error("Expected error for non-zero exit code")
+	}
+	if result.Stdout != "out\n" || result.Stderr != "err\n" {
+		t.Errorf("Expected separate streams, got stdout '%s' stderr '%s'", result.Stdout, result.Stderr)
+	}
+	if result.ExitCode != 3 {
+		t.Errorf("Expected exit code 3, got %d", result.ExitCode)
+	}
+}
+
+func TestLocalExecutorStreamsOutput(t *testing.T) {
+	executor := NewLocalExecutor()
+
+	cmd := &config.Command{
+		Name:   "stream",
+		Script: "sh",
+		Args:   []string{"-c", "echo first; echo '::progress 40/100 compiling'; printf partial"},
+	}
+
+	var mu sync.Mutex
+	var lines []OutputLine
+	ctx := WithOutput(context.Background(), func(line OutputLine) {
+		mu.Lock()
+		defer mu.Unlock()
+		lines = append(lines, line)
+	})
+
+	result, err := executor.Execute(ctx, cmd, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if result.Stdout != "first\n::progress 40/100 compiling\npartial" {
+		t.Errorf("Expected full output to be captured, got '%s'", result.Stdout)
+	}
+	if len(lines) != 3 {
+		t.Fatalf("Expected 3 streamed lines, got %d: %v", len(lines), lines)
+	}
+	if lines[0].Text != "first" || lines[0].Stream != "stdout" || lines[0].Progress != nil {
+		t.Errorf("Unexpected first line: %+v", lines[0])
+	}
+	progress := lines[1].Progress
+	if progress == nil || progress.Current != 40 || progress.Total != 100 || progress.Message != "compiling" {
+		t.Errorf("Expected parsed progress marker, got %+v", progress)
+	}
+	if lines[2].Text != "partial" {
+		t.Errorf("Expected unterminated line to be flushed, got '%s'", lines[2].Text)
+	}
+}
+
+func TestServiceCancellation(t *testing.T) {
+	service := New()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	commands := []*config.Command{
+		{
+			Name:              "sleep",
+			Script:            "sleep",
+			Args:              []string{"30"},
+			KillGraceDuration: 200 * time.Millisecond,
+		},
+		{
+			// The retry backoff is far longer than the test; cancellation must interrupt it
+			Name: "flaky",
+			Webhook: &config.WebhookConfig{
+				URL:   server.URL,
+				Retry: &config.WebhookRetry{MaxRetries: 3, Delay: "30s", Backoff: "linear"},
+			},
+		},
+	}
+
+	for _, cmd := range commands {
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(200*time.Millisecond, cancel)
+
+		start := time.Now()
+		_, err := service.Execute(ctx, cmd, nil)
+		if !errors.Is(err, ErrCancelled) {
+			t.Errorf("%s: expected cancellation error, got %v", cmd.Name, err)
+		}
+		if elapsed := time.Since(start); elapsed > 5*time.Second {
+			t.Errorf("%s: expected prompt return after cancellation, took %s", cmd.Name, elapsed)
+		}
+		cancel()
+	}
+}
+
+func TestExecutorArtifacts(t *testing.T) {
+	png := "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"
+
+	cmd := &config.Command{
+		Name:   "render",
+		Script: "sh",
+		Args:   []string{"-c", `printf '` + `\211PNG\r\n\032\n\000\000\000\rIHDR` + `' > "$1/chart.png"; echo '{}' > "$MCPFIER_OUTPUT_DIR/data.json"`, "sh", "{{.output_dir}}"},
+		Artifacts: []config.Artifact{
+			{Path: "*.png"},
+			{Path: "data.json", MIMEType: "application/json"},
+		},
+	}
+
+	result, err := NewLocalExecutor().Execute(context.Background(), cmd, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(result.Artifacts) != 2 {
+		t.Fatalf("Expected 2 artifacts, got %d", len(result.Artifacts))
+	}
+	if a := result.Artifacts[0]; a.Name != "chart.png" || a.MIMEType != "image/png" || string(a.Data) != png {
+		t.Errorf("Unexpected image artifact: %s %s %q", a.Name, a.MIMEType, a.Data)
+	}
+	if a := result.Artifacts[1]; a.MIMEType != "application/json" {
+		t.Errorf("Expected configured MIME type, got %s", a.MIMEType)
+	}
+
+	// A declared artifact that was not written is an error
+	cmd.Artifacts = []config.Artifact{{Path: "missing.pdf"}}
+	if _, err := NewLocalExecutor().Execute(context.Background(), cmd, nil); err == nil {
+		t.Error("Expected error for missing artifact")
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write([]byte(png))
+	}))
+	defer server.Close()
+
+	webhook := &config.Command{Name: "chart-api", Webhook: &config.WebhookConfig{URL: server.URL}}
+	result, err = NewWebhookExecutor().Execute(context.Background(), webhook, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if result.Stdout != "" || len(result.Artifacts) != 1 || result.Artifacts[0].MIMEType != "image/png" {
+		t.Errorf("Expected binary response as image artifact, got stdout %q, artifacts %+v", result.Stdout, result.Artifacts)
+	}
+}
+
+func TestContainerFlags(t *testing.T) {
+	flags := containerFlags(&config.ContainerConfig{
+		Image:      "alpine",
+		Memory:     "128m",
+		CPUs:       "1",
+		PidsLimit:  64,
+		Network:    "none",
+		User:       "nobody",
+		ReadOnly:   true,
+		CapDrop:    []string{"ALL"},
+		Workdir:    "/work",
+		Entrypoint: "/bin/sh",
+		Mounts:     []config.Mount{{Source: "/srv/data", Target: "/data", ReadOnly: true}},
+		Tmpfs:      []string{"/tmp"},
+	})
+
+	expected := "--memory 128m --cpus 1 --pids-limit 64 --network none --user nobody --read-only " +
+		"--cap-drop ALL --workdir /work --entrypoint /bin/sh -v /srv/data:/data:ro --tmpfs /tmp"
+	if got := strings.Join(flags, " "); got != expected {
+		t.Errorf("Unexpected flags:\n got %s\nwant %s", got, expected)
+	}
+}
+
+// fakeEngine is a minimal Docker Engine API served over a unix socket
+type fakeEngine struct {
+	mu       sync.Mutex
+	calls    []string
+	create   engineCreateRequest
+	pulled   bool
+	exitCode int
+	block    bool // wait blocks until the container is stopped
+	stopped  chan struct{}
+}
+
+func (f *fakeEngine) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	f.calls = append(f.calls, r.Method+" "+r.URL.Path)
+	f.mu.Unlock()
+
+	switch {
+	case r.URL.Path == "/containers/create":
+		if !f.pulled {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message": "No such image"}`))
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&f.create)
+		w.Write([]byte(`{"Id": "abc123"}`))
+	case r.URL.Path == "/images/create":
+		f.pulled = true
+		w.Write([]byte(`{"status": "Pulling"}` + "\n" + `{"status": "Done"}`))
+	case strings.HasSuffix(r.URL.Path, "/logs"):
+		frame := func(stream byte, text string) []byte {
+			header := []byte{stream, 0, 0, 0, 0, 0, 0, byte(len(text))}
+			return append(header, text...)
+		}
+		w.Write(frame(1, "hello\n"))
+		w.Write(frame(2, "warning\n"))
+	case strings.HasSuffix(r.URL.Path, "/wait"):
+		if f.block {
+			select {
+			case <-f.stopped:
+			case <-r.Context().Done():
+				return
+			}
+		}
+		fmt.Fprintf(w, `{"StatusCode": %d}`, f.exitCode)
+	case strings.HasSuffix(r.URL.Path, "/stop"):
+		close(f.stopped)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func TestEngineRuntime(t *testing.T) {
+	dir, err := os.MkdirTemp("", "engine")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	socket := filepath.Join(dir, "docker.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+	engine := &fakeEngine{exitCode: 3, stopped: make(chan struct{})}
+	server := httptest.NewUnstartedServer(engine)
+	server.Listener = listener
+	server.Start()
+	defer server.Close()
+
+	runtime, err := NewEngineRuntime("unix://" + socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	spec := &ContainerSpec{
+		Name:      "mcpfier-test",
+		Image:     "alpine:3",
+		Command:   []string{"sh", "-c", "echo hello"},
+		Env:       map[string]string{"A": "1"},
+		Options:   config.ContainerConfig{Memory: "64m", CPUs: "0.5", Network: "none", Mounts: []config.Mount{{Source: "/src", Target: "/dst", ReadOnly: true}}},
+		KillGrace: time.Second,
+	}
+	result, err := runtime.Run(context.Background(), spec)
+	if err == nil {
+		t.Error("Expected error for non-zero exit status")
+	}
+	if result.Stdout != "hello\n" || result.Stderr != "warning\n" || result.ExitCode != 3 {
+		t.Errorf("Unexpected result: stdout %q stderr %q exit %d", result.Stdout, result.Stderr, result.ExitCode)
+	}
+
+	host := engine.create.HostConfig
+	if host.Memory != 64<<20 || host.NanoCpus != 5e8 || host.NetworkMode != "none" || len(host.Binds) != 1 || host.Binds[0] != "/src:/dst:ro" {
+		t.Errorf("Unexpected host config: %+v", host)
+	}
+	if !engine.pulled {
+		t.Error("Expected missing image to be pulled")
+	}
+	if last := engine.calls[len(engine.calls)-1]; last != "DELETE /containers/mcpfier-test" {
+		t.Errorf("Expected container to be removed, last call was %s", last)
+	}
+
+	// Cancelling the run stops the container instead of waiting for it
+	engine.block = true
+	engine.exitCode = 143
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+	if _, err := runtime.Run(ctx, spec); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected cancellation error, got %v", err)
+	}
+	select {
+	case <-engine.stopped:
+	default:
+		t.Error("Expected container to be stopped on cancellation")
+	}
+}
+
+// fakePooledRuntime records container lifecycle calls made by a warm pool
+type fakePooledRuntime struct {
+	mu        sync.Mutex
+	running   map[string]bool
+	started   int
+	execs     map[string]int
+	startErrs int   // Number of starts that fail before one succeeds
+	execErr   error // Error returned by every exec
+}
+
+func (f *fakePooledRuntime) Run(ctx context.Context, spec *ContainerSpec) (*Result, error) {
+	return nil, errors.New("pooled commands must not use Run")
+}
+
+func (f *fakePooledRuntime) Start(ctx context.Context, spec *ContainerSpec) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.startErrs > 0 {
+		f.startErrs--
+		return fmt.Errorf("%w: daemon restarting", ErrRuntimeUnavailable)
+	}
+	f.running[spec.Name] = true
+	f.started++
+	return nil
+}
+
+func (f *fakePooledRuntime) Exec(ctx context.Context, container string, spec *ExecSpec) (*Result, error) {
+	f.mu.Lock()
+	if !f.running[container] {
+		f.mu.Unlock()
+		return nil, fmt.Errorf("container %s is not running", container)
+	}
+	f.execs[container]++
+	f.mu.Unlock()
+
+	if f.execErr != nil {
+		return &Result{ExitCode: -1}, f.execErr
+	}
+	if len(spec.Command) > 0 && spec.Command[0] == "block" {
+		<-ctx.Done()
+		return &Result{}, ctx.Err()
+	}
+	return &Result{Stdout: container}, nil
+}
+
+func (f *fakePooledRuntime) Remove(container string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.running, container)
+}
+
+func (f *fakePooledRuntime) count() (running, started int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.running), f.started
+}
+
+func TestContainerPool(t *testing.T) {
+	runtime := &fakePooledRuntime{running: map[string]bool{}, execs: map[string]int{}}
+	service := New().WithContainerRuntime(runtime)
+
+	cmd := config.Command{
+		Name:      "pooled",
+		Script:    "echo",
+		Container: "alpine",
+		Pool:      &config.PoolConfig{Size: 1, MaxUses: 2},
+	}
+	cmdBlock := cmd
+	cmdBlock.Name = "blocking"
+	cmdBlock.Script = "block"
+	cmdBlock.KillGraceDuration = 100 * time.Millisecond
+	cmdBlock.Pool = &config.PoolConfig{Size: 1, MaxUses: 10}
+	for _, pool := range []*config.PoolConfig{cmd.Pool, cmdBlock.Pool} {
+		pool.Keepalive = config.DefaultPoolKeepalive
+		pool.IdleTimeoutDuration = config.DefaultPoolIdleTimeout
+	}
+	if err := service.StartPools([]config.Command{cmd, cmdBlock}); err != nil {
+		t.Fatalf("Failed to start pools: %v", err)
+	}
+
+	// Calls reuse the warm container until MaxUses, then it is replaced
+	first, err := service.Execute(context.Background(), &cmd, nil)
+	if err != nil {
+		t.Fatalf("Pooled call failed: %v", err)
+	}
+	second, err := service.Execute(context.Background(), &cmd, nil)
+	if err != nil {
+		t.Fatalf("Pooled call failed: %v", err)
+	}
+	third, err := service.Execute(context.Background(), &cmd, nil)
+	if err != nil {
+		t.Fatalf("Pooled call failed: %v", err)
+	}
+	if first.Stdout != second.Stdout {
+		t.Errorf("Expected calls to reuse container %s, got %s", first.Stdout, second.Stdout)
+	}
+	if third.Stdout == first.Stdout {
+		t.Errorf("Expected container %s to be replaced after max_uses", first.Stdout)
+	}
+
+	// A cancelled call may leave processes behind, so its container is replaced
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+	if _, err := service.Execute(ctx, &cmdBlock, nil); !errors.Is(err, ErrCancelled) {
+		t.Errorf("Expected cancellation error, got %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		statuses := service.PoolStatus()
+		if len(statuses) == 2 && statuses[0].Idle == 1 && statuses[1].Idle == 1 {
+			if !statuses[0].Healthy || statuses[0].Command != "blocking" {
+				t.Errorf("Unexpected pool status: %+v", statuses[0])
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Pools did not refill: %+v", statuses)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if running, started := runtime.count(); running != 2 || started != 4 {
+		t.Errorf("Expected 2 running of 4 started containers, got %d of %d", running, started)
+	}
+
+	// Closing drains every container
+	service.Close()
+	if running, _ := runtime.count(); running != 0 {
+		t.Errorf("Expected pools to be drained, %d containers still running", running)
+	}
+}
+
+func TestContainerPoolRecovers(t *testing.T) {
+	defer func(delay time.Duration) { poolRetryDelay = delay }(poolRetryDelay)
+	poolRetryDelay = 10 * time.Millisecond
+
+	// Failed starts are retried without waiting for a call
+	runtime := &fakePooledRuntime{running: map[string]bool{}, execs: map[string]int{}, startErrs: 3}
+	service := New().WithContainerRuntime(runtime)
+	defer service.Close()
+
+	cmd := config.Command{
+		Name:      "pooled",
+		Script:    "echo",
+		Container: "alpine",
+		Pool: &config.PoolConfig{
+			Size:                1,
+			MaxUses:             10,
+			Keepalive:           config.DefaultPoolKeepalive,
+			IdleTimeoutDuration: config.DefaultPoolIdleTimeout,
+		},
+	}
+	if err := service.StartPools([]config.Command{cmd}); err != nil {
+		t.Fatalf("Failed to start pools: %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		statuses := service.PoolStatus()
+		if statuses[0].Idle == 1 {
+			if !statuses[0].Healthy || statuses[0].Error != "" {
+				t.Errorf("Expected a healthy pool, got %+v", statuses[0])
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Pool did not recover: %+v", statuses)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, started := runtime.count(); started != 1 {
+		t.Errorf("Expected one container to start, got %d", started)
+	}
+
+	// Runtime failures inside a pooled container trigger the fallback policy
+	runtime.mu.Lock()
+	runtime.execErr = fmt.Errorf("%w: connection reset", ErrRuntimeUnavailable)
+	runtime.mu.Unlock()
+	cmd.Args = []string{"local"}
+	cmd.Fallback = config.FallbackLocal
+	cmd.FallbackOn = config.DefaultFallbackOn
+	result, err := service.Execute(context.Background(), &cmd, nil)
+	if err != nil || result.Stdout != "local\n" {
+		t.Errorf("Expected fallback to run locally, got %v, %v", result, err)
+	}
+
+	// The CLI runtime classifies its own failures on exec as it does on run
+	_, err = NewCLIRuntime("mcpfier-no-such-runtime").Exec(context.Background(), "pooled", &ExecSpec{Command: []string{"true"}})
+	if !errors.Is(err, ErrRuntimeUnavailable) {
+		t.Errorf("Expected runtime unavailable, got %v", err)
+	}
+}
+
+// fakeRuntime fails every run with a fixed error
+type fakeRuntime struct {
+	err error
+}
+
+func (f *fakeRuntime) Run(ctx context.Context, spec *ContainerSpec) (*Result, error) {
+	return &Result{ExitCode: -1}, f.err
+}
+
+// recordingAnalytics keeps the command events it receives
+type recordingAnalytics struct {
+	analytics.NoOpAnalytics
+	mu     sync.Mutex
+	events []analytics.CommandEvent
+}
+
+func (r *recordingAnalytics) RecordCommand(ctx context.Context, event analytics.CommandEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func TestServiceFallback(t *testing.T) {
+	recorder := &recordingAnalytics{}
+	service := New().WithAnalytics(recorder)
+
+	remote := &config.Command{Name: "remote", Script: "echo", Args: []string{"from remote"}}
+	tests := []struct {
+		name       string
+		runtime    ContainerRuntime
+		cmd        config.Command
+		wantOutput string
+		wantErr    bool
+		fallback   string
+	}{
+		{
+			name:       "missing CLI falls back to local",
+			runtime:    NewCLIRuntime("mcpfier-no-such-runtime"),
+			cmd:        config.Command{Script: "echo", Args: []string{"local"}, Fallback: config.FallbackLocal, FallbackOn: config.DefaultFallbackOn},
+			wantOutput: "local\n",
+			fallback:   config.FailureUnavailable,
+		},
+		{
+			name:       "pull failure falls back to another command",
+			runtime:    &fakeRuntime{err: fmt.Errorf("%w: alpine: not found", ErrImagePull)},
+			cmd:        config.Command{Script: "echo", Fallback: "remote", FallbackCommand: remote, FallbackOn: config.DefaultFallbackOn},
+			wantOutput: "from remote\n",
+			fallback:   config.FailurePull,
+		},
+		{
+			name:    "failure class not listed in fallback_on",
+			runtime: &fakeRuntime{err: fmt.Errorf("%w: alpine: not found", ErrImagePull)},
+			cmd:     config.Command{Script: "echo", Fallback: config.FallbackLocal, FallbackOn: []string{config.FailureTimeout}},
+			wantErr: true,
+		},
+		{
+			name:    "command failures do not fall back",
+			runtime: &fakeRuntime{err: errors.New("exit status 1")},
+			cmd:     config.Command{Script: "echo", Fallback: config.FallbackLocal, FallbackOn: config.DefaultFallbackOn},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder.events = nil
+			service.WithContainerRuntime(tt.runtime)
+			cmd := tt.cmd
+			cmd.Name = "render"
+			cmd.Container = "alpine"
+
+			result, err := service.Execute(context.Background(), &cmd, nil)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Expected the container failure to be returned")
+				}
+				if recorder.events[0].ExecutionMode != "container" || recorder.events[0].Fallback != "" {
+					t.Errorf("Unexpected analytics event: %+v", recorder.events[0])
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected fallback to succeed, got %v", err)
+			}
+			if result.Stdout != tt.wantOutput {
+				t.Errorf("Expected output %q, got %q", tt.wantOutput, result.Stdout)
+			}
+			event := recorder.events[0]
+			if len(recorder.events) != 1 || event.ExecutionMode != "local" || event.Fallback != tt.fallback || event.CommandName != "render" {
+				t.Errorf("Unexpected analytics events: %+v", recorder.events)
+			}
+		})
+	}
+}
+
+func TestCLIErrorClassification(t *testing.T) {
+	exitErr := errors.New("exit status 125")
+	tests := []struct {
+		exitCode int
+		stderr   string
+		want     error
+	}{
+		{125, "docker: Cannot connect to the Docker daemon at unix:///var/run/docker.sock. Is the docker daemon running?", ErrRuntimeUnavailable},
+		{125, "Unable to find image 'nope:latest' locally\ndocker: Error response from daemon: pull access denied for nope", ErrImagePull},
+		{125, "Error: initializing source docker://nope:latest: reading manifest latest", ErrImagePull},
+		{125, "Unable to find image 'alpine:3' locally\nStatus: Downloaded newer image for alpine:3\ndocker: invalid mount config", nil},
+		{1, "pull access denied", nil},
+	}
+	for _, tt := range tests {
+		err := cliError(exitErr, tt.exitCode, tt.stderr)
+		if tt.want == nil {
+			if errors.Is(err, ErrRuntimeUnavailable) || errors.Is(err, ErrImagePull) {
+				t.Errorf("%q: expected unclassified error, got %v", tt.stderr, err)
+			}
+		} else if !errors.Is(err, tt.want) {
+			t.Errorf("%q: expected %v, got %v", tt.stderr, tt.want, err)
+		}
+	}
+}
+
+func TestWasmExecutor(t *testing.T) {
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go toolchain is needed to build the test module")
+	}
+	dir := t.TempDir()
+	module := filepath.Join(dir, "tool.wasm")
+	build := exec.Command(goBin, "build", "-o", module, "./testdata/wasm")
+	build.Env = append(os.Environ(), "GOOS=wasip1", "GOARCH=wasm")
+	if output, err := build.CombinedOutput(); err != nil {
+		t.Fatalf("Failed to build test module: %v\n%s", err, output)
+	}
+
+	data := filepath.Join(dir, "data")
+	os.Mkdir(data, 0755)
+	os.WriteFile(filepath.Join(data, "in.txt"), []byte("mounted"), 0644)
+
+	service := New()
+	cmd := &config.Command{
+		Name: "wasm-tool",
+		Args: []string{"{{.params.mode}}", "second"},
+		Env:  map[string]string{"GREETING": "hello {{.params.mode}}"},
+		Wasm: &config.WasmConfig{
+			Module: module,
+			Stdin:  "{{json .params}}",
+			Memory: "64m",
+			Mounts: []config.Mount{{Source: data, Target: "/data", ReadOnly: true}},
+		},
+		Artifacts: []config.Artifact{{Path: "result.txt", MIMEType: "text/plain"}},
+	}
+
+	result, err := service.Execute(context.Background(), cmd, map[string]interface{}{"mode": "run"})
+	if err != nil {
+		t.Fatalf("Wasm execution failed: %v (stderr %q)", err, result.Stderr)
+	}
+	for _, want := range []string{
+		`args=run,second stdin={"mode":"run"} greeting=hello run`,
+		"read=mounted <nil>",
+		"write denied",
+		"host denied",
+	} {
+		if !strings.Contains(result.Stdout, want) {
+			t.Errorf("Expected output to contain %q, got %q", want, result.Stdout)
+		}
+	}
+	if len(result.Artifacts) != 1 || string(result.Artifacts[0].Data) != "artifact" {
+		t.Errorf("Expected artifact from the output directory, got %+v", result.Artifacts)
+	}
+
+	result, err = service.Execute(context.Background(), cmd, map[string]interface{}{"mode": "fail"})
+	if err == nil || result.ExitCode != 3 {
+		t.Errorf("Expected exit status 3, got %d, %v", result.ExitCode, err)
+	}
+
+	// A module that never yields is interrupted at the timeout
+	cmd.TimeoutDuration = 200 * time.Millisecond
+	start := time.Now()
+	_, err = service.Execute(context.Background(), cmd, map[string]interface{}{"mode": "spin"})
+	if !errors.Is(err, ErrTimeout) {
+		t.Errorf("Expected timeout, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected the module to be interrupted promptly, took %s", elapsed)
+	}
+}
+
+func TestStarlarkExecutor(t *testing.T) {
+	var attempts int
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/users/ada":
+			if r.Header.Get("Authorization") != "Bearer secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			io.WriteString(w, `{"id": 7, "name": "Ada"}`)
+		case "/orders":
+			// Fail once to exercise the retry policy
+			mu.Lock()
+			attempts++
+			first := attempts == 1
+			mu.Unlock()
+			if first {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			var body map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&body)
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprintf(w, `{"user": %v, "count": 3}`, body["user"])
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	echo := &config.Command{Name: "echo", Script: "echo", Args: []string{"{{.params.text}}"},
+		Parameters: []config.Parameter{{Name: "text", Type: config.ParamString, Required: true}}}
+	starlarkCommand := func(source string, maxSteps uint64) *config.Command {
+		return &config.Command{
+			Name:       "tool",
+			Parameters: []config.Parameter{{Name: "user", Type: config.ParamString}},
+			Starlark: &config.StarlarkConfig{
+				Source:      source,
+				MaxSteps:    maxSteps,
+				Auth:        map[string]*config.WebhookAuth{"api": {Type: "bearer", Token: "secret"}},
+				Retry:       &config.WebhookRetry{MaxRetries: 2, Delay: "1ms"},
+				CommandRefs: map[string]*config.Command{"echo": echo},
+			},
+		}
+	}
+	service := New()
+
+	source := fmt.Sprintf(`
+base = %q
+
+def main(params):
+    user = http.get(base + "/users/" + params["user"], auth="api").json()
+    orders = http.post(base + "/orders", json={"user": user["id"]}).json()
+    denied = http.get(base + "/users/" + params["user"])
+    greeting = run("echo", text="hello " + user["name"])
+    print("looked up", user["name"])
+    return {"name": user["name"], "orders": orders["count"], "denied": denied.status, "greeting": greeting.stdout.strip()}
+`, server.URL)
+	result, err := service.Execute(context.Background(), starlarkCommand(source, config.DefaultStarlarkMaxSteps), map[string]interface{}{"user": "ada"})
+	if err != nil {
+		t.Fatalf("Expected script to succeed, got %v (stderr %q)", err, result.Stderr)
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal([]byte(result.Stdout), &got); err != nil {
+		t.Fatalf("Expected JSON result, got %q", result.Stdout)
+	}
+	if got["name"] != "Ada" || got["orders"] != float64(3) || got["denied"] != float64(401) || got["greeting"] != "hello Ada" {
+		t.Errorf("Unexpected result %v", got)
+	}
+	if !reflect.DeepEqual(result.Structured, map[string]interface{}(got)) {
+		t.Errorf("Expected the result as structured content, got %#v", result.Structured)
+	}
+	if result.Stderr != "looked up Ada\n" {
+		t.Errorf("Expected print output on stderr, got %q", result.Stderr)
+	}
+
+	tests := []struct {
+		name    string
+		source  string
+		timeout time.Duration
+		wantErr string
+	}{
+		{name: "text result", source: `def main(params): return "plain"`},
+		{name: "step limit", source: "def main(params):\n    while True:\n        pass", wantErr: "too many steps"},
+		{name: "timeout", source: "def main(params):\n    while True:\n        pass", timeout: 50 * time.Millisecond, wantErr: "timed out"},
+		{name: "no filesystem", source: `def main(params): return open("/etc/passwd")`, wantErr: "undefined: open"},
+		{name: "no load", source: "load(\"os.star\", \"system\")\ndef main(params): pass", wantErr: "load not implemented"},
+		{name: "unlisted command", source: `def main(params): return run("tool")`, wantErr: "not listed in starlark.commands"},
+		{name: "missing main", source: `x = 1`, wantErr: "does not define main"},
+		{name: "fail", source: `def main(params): fail("bad input")`, wantErr: "bad input"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			maxSteps := uint64(config.DefaultStarlarkMaxSteps)
+			if tt.timeout > 0 {
+				maxSteps = 1 << 62
+			}
+			cmd := starlarkCommand(tt.source, maxSteps)
+			cmd.TimeoutDuration = tt.timeout
+
+			start := time.Now()
+			result, err := service.Execute(context.Background(), cmd, nil)
+			if tt.wantErr == "" {
+				if err != nil || result.Stdout != "plain" || result.Structured != nil {
+					t.Errorf("Expected plain text result, got %q, %v", result.Stdout, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+			if tt.timeout > 0 && time.Since(start) > 5*time.Second {
+				t.Errorf("Expected the script to stop at the timeout, took %s", time.Since(start))
+			}
+		})
+	}
+}
+
+func TestSQLExecutor(t *testing.T) {
+	dsn := filepath.Join(t.TempDir(), "app.db")
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec(`CREATE TABLE users (id INTEGER, name TEXT, note TEXT, avatar BLOB);
+		INSERT INTO users VALUES (1, 'ada', 'likes | pipes', x'00ff'), (2, 'grace', NULL, NULL), (3, 'linus', 'two
+lines', NULL), (4, 'NULL', '', NULL)`)
+	db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	executor := NewSQLExecutor()
+	defer executor.Close()
+	command := func(query *config.SQLConfig, params ...config.Parameter) *config.Command {
+		query.Driver = config.DefaultSQLDriver
+		query.DSN = dsn
+		if query.Format == "" {
+			query.Format = config.SQLFormatTable
+		}
+		if query.MaxRows == 0 {
+			query.MaxRows = config.DefaultSQLMaxRows
+		}
+		return &config.Command{Name: "query", SQL: query, Parameters: params}
+	}
+	nameParam := config.Parameter{Name: "name", Type: config.ParamString}
+
+	tests := []struct {
+		name    string
+		cmd     *config.Command
+		params  map[string]interface{}
+		want    string
+		stderr  string
+		wantErr string
+		wantIs  error
+	}{
+		{
+			name:   "named parameter as markdown table",
+			cmd:    command(&config.SQLConfig{Query: "SELECT id, name, note FROM users WHERE name = :name OR :name IS NULL ORDER BY id"}, nameParam),
+			params: map[string]interface{}{"name": "ada"},
+			want:   "| id | name | note |\n| --- | --- | --- |\n| 1 | ada | likes \\| pipes |\n",
+		},
+		{
+			name:   "parameters are bound, not interpolated",
+			cmd:    command(&config.SQLConfig{Query: "SELECT id FROM users WHERE name = :name"}, nameParam),
+			params: map[string]interface{}{"name": "x' OR '1'='1"},
+			want:   "| id |\n| --- |\n",
+		},
+		{
+			name:   "positional binding as CSV",
+			cmd:    command(&config.SQLConfig{Query: "SELECT name, note FROM users WHERE id >= ? ORDER BY id", Bind: []string{"min"}, Format: config.SQLFormatCSV}, config.Parameter{Name: "min", Type: config.ParamNumber}),
+			params: map[string]interface{}{"min": float64(2)},
+			want:   "name,note\ngrace,\nlinus,\"two\nlines\"\nNULL,\"\"\n",
+		},
+		{
+			name: "NULL is an empty table cell",
+			cmd:  command(&config.SQLConfig{Query: "SELECT name, note FROM users WHERE id IN (2, 4) ORDER BY id"}),
+			want: "| name | note |\n| --- | --- |\n| grace |  |\n| NULL |  |\n",
+		},
+		{
+			name:   "JSON keeps column order and truncates at max_rows",
+			cmd:    command(&config.SQLConfig{Query: "SELECT name, id, avatar, note FROM users ORDER BY id", Format: config.SQLFormatJSON, MaxRows: 2}),
+			want:   `[{"name":"ada","id":1,"avatar":"x'00ff'","note":"likes | pipes"},{"name":"grace","id":2,"avatar":null,"note":null}]` + "\n",
+			stderr: "Result truncated to 2 rows (max_rows)\n",
+		},
+		{
+			name:    "read-only connection rejects writes",
+			cmd:     command(&config.SQLConfig{Query: "INSERT INTO users (id, name) VALUES (4, 'mallory') RETURNING id", ReadOnly: true}),
+			wantErr: "readonly",
+		},
+		{
+			name:    "query timeout",
+			cmd:     command(&config.SQLConfig{Query: "WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n) SELECT count(*) FROM n", TimeoutDuration: 50 * time.Millisecond}),
+			wantErr: "query exceeded 50ms",
+			wantIs:  ErrTimeout,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := executor.Execute(context.Background(), tt.cmd, tt.params)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Expected error containing %q, got %v", tt.wantErr, err)
+				}
+				if tt.wantIs != nil && !errors.Is(err, tt.wantIs) {
+					t.Errorf("Expected error to wrap %v, got %v", tt.wantIs, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected query to succeed, got %v", err)
+			}
+			if result.Stdout != tt.want || result.Stderr != tt.stderr {
+				t.Errorf("Expected output %q (stderr %q), got %q (stderr %q)", tt.want, tt.stderr, result.Stdout, result.Stderr)
+			}
+		})
+	}
+}
+
+// fakeKubernetes serves the Job and pod endpoints used by the Kubernetes executor
+type fakeKubernetes struct {
+	mu        sync.Mutex
+	token     string
+	namespace string
+	job       map[string]interface{}
+	deleted   []string
+	exitCode  int
+	reason    string
+	pullError bool
+	block     bool // the pod never terminates
+	noPod     bool // the Job never creates a pod
+	jobFailed bool // the Job has a Failed condition
+}
+
+func (f *fakeKubernetes) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /apis/batch/v1/namespaces/{ns}/jobs", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.namespace = r.PathValue("ns")
+		json.NewDecoder(r.Body).Decode(&f.job)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{}`))
+	})
+	mux.HandleFunc("DELETE /apis/batch/v1/namespaces/{ns}/jobs/{name}", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.deleted = append(f.deleted, r.PathValue("name")+" "+r.URL.Query().Get("propagationPolicy"))
+		w.Write([]byte(`{}`))
+	})
+	mux.HandleFunc("GET /apis/batch/v1/namespaces/{ns}/jobs/{name}", func(w http.ResponseWriter, r *http.Request) {
+		if f.jobFailed {
+			w.Write([]byte(`{"status": {"conditions": [{"type": "Failed", "status": "True", "reason": "DeadlineExceeded", "message": "Job was active longer than specified deadline"}]}}`))
+			return
+		}
+		w.Write([]byte(`{"status": {}}`))
+	})
+	mux.HandleFunc("GET /api/v1/namespaces/{ns}/pods", func(w http.ResponseWriter, r *http.Request) {
+		job := strings.TrimPrefix(r.URL.Query().Get("labelSelector"), "job-name=")
+		if f.noPod {
+			w.Write([]byte(`{"items": []}`))
+			return
+		}
+		if f.pullError {
+			fmt.Fprintf(w, `{"items": [{"metadata": {"name": "%s-x1"}, "status": {"phase": "Pending", "containerStatuses": [{"state": {"waiting": {"reason": "ErrImagePull", "message": "not found"}}}]}}]}`, job)
+			return
+		}
+		fmt.Fprintf(w, `{"items": [{"metadata": {"name": "%s-x1"}, "status": {"phase": "Running"}}]}`, job)
+	})
+	mux.HandleFunc("GET /api/v1/namespaces/{ns}/pods/{pod}/log", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello\n"))
+	})
+	mux.HandleFunc("GET /api/v1/namespaces/{ns}/pods/{pod}", func(w http.ResponseWriter, r *http.Request) {
+		if f.block {
+			w.Write([]byte(`{"status": {"phase": "Running", "containerStatuses": [{"state": {}}]}}`))
+			return
+		}
+		fmt.Fprintf(w, `{"status": {"phase": "Succeeded", "containerStatuses": [{"state": {"terminated": {"exitCode": %d, "reason": "%s"}}}]}}`, f.exitCode, f.reason)
+	})
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+f.token {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"kind": "Status", "message": "Unauthorized"}`))
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+func TestKubernetesExecutor(t *testing.T) {
+	defer func(interval time.Duration) { kubernetesPollInterval = interval }(kubernetesPollInterval)
+	kubernetesPollInterval = 10 * time.Millisecond
+
+	fake := &fakeKubernetes{token: "secret"}
+	server := httptest.NewTLSServer(fake.handler())
+	defer server.Close()
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+
+	dir := t.TempDir()
+	kubeconfig := filepath.Join(dir, "config")
+	os.WriteFile(kubeconfig, []byte(fmt.Sprintf(`
+current-context: dev
+contexts:
+  - name: dev
+    context: {cluster: dev, user: dev, namespace: tools}
+  - name: wrong-token
+    context: {cluster: dev, user: anonymous}
+clusters:
+  - name: dev
+    cluster:
+      server: %s
+      certificate-authority-data: %s
+users:
+  - name: dev
+    user: {tokenFile: token}
+  - name: anonymous
+    user: {token: nope}
+`, server.URL, base64.StdEncoding.EncodeToString(ca))), 0600)
+	os.WriteFile(filepath.Join(dir, "token"), []byte("secret\n"), 0600)
+
+	executor := NewKubernetesExecutor()
+	command := func(settings *config.KubernetesConfig) *config.Command {
+		settings.TTLAfterFinishedDuration = config.DefaultKubernetesTTL
+		return &config.Command{
+			Name:             "report tool",
+			Script:           "/bin/report",
+			Args:             []string{"--user={{.params.user}}"},
+			Env:              map[string]string{"GREETING": "hi {{.params.user}}"},
+			Container:        "alpine:3",
+			ContainerOptions: &config.ContainerConfig{Memory: "64m", CPUs: "0.5", User: "1000:1000", ReadOnly: true, Tmpfs: []string{"/tmp"}},
+			Kubernetes:       settings,
+			TimeoutDuration:  4500 * time.Millisecond,
+		}
+	}
+	params := map[string]interface{}{"user": "ada"}
+
+	result, err := executor.Execute(context.Background(), command(&config.KubernetesConfig{Kubeconfig: kubeconfig, ServiceAccount: "reporter"}), params)
+	if err != nil {
+		t.Fatalf("Expected job to succeed, got %v", err)
+	}
+	if result.Stdout != "hello\n" || result.ExitCode != 0 {
+		t.Errorf("Unexpected result: stdout %q exit %d", result.Stdout, result.ExitCode)
+	}
+	if fake.namespace != "tools" {
+		t.Errorf("Expected the context namespace, got %q", fake.namespace)
+	}
+	job, _ := json.Marshal(fake.job)
+	for _, want := range []string{
+		`"backoffLimit":0`, `"activeDeadlineSeconds":5`, `"ttlSecondsAfterFinished":600`,
+		`"restartPolicy":"Never"`, `"serviceAccountName":"reporter"`, `"mcpfier.io/command":"report-tool"`,
+		`"image":"alpine:3"`, `"args":["/bin/report","--user=ada"]`, `"env":[{"name":"GREETING","value":"hi ada"}]`,
+		`"limits":{"cpu":"500m","memory":"67108864"}`, `"readOnlyRootFilesystem":true`, `"runAsGroup":1000`, `"runAsUser":1000`,
+		`"emptyDir":{"medium":"Memory"}`, `"mountPath":"/tmp"`,
+	} {
+		if !strings.Contains(string(job), want) {
+			t.Errorf("Expected job to contain %s, got %s", want, job)
+		}
+	}
+	if len(fake.deleted) != 1 || !strings.HasSuffix(fake.deleted[0], " Background") {
+		t.Errorf("Expected job to be deleted, got %v", fake.deleted)
+	}
+
+	// A failed container reports its exit code; keep_finished leaves the Job to its TTL
+	fake.exitCode, fake.reason, fake.deleted = 137, "OOMKilled", nil
+	result, err = executor.Execute(context.Background(), command(&config.KubernetesConfig{Kubeconfig: kubeconfig, Namespace: "batch", KeepFinished: true}), params)
+	if err == nil || err.Error() != "exit status 137 (OOMKilled)" || result.ExitCode != 137 {
+		t.Errorf("Expected OOMKilled exit status, got %v", err)
+	}
+	if fake.namespace != "batch" || len(fake.deleted) != 0 {
+		t.Errorf("Expected finished job in batch to be kept, got namespace %q deleted %v", fake.namespace, fake.deleted)
+	}
+
+	fake.exitCode, fake.reason, fake.pullError = 0, "", true
+	if _, err := executor.Execute(context.Background(), command(&config.KubernetesConfig{Kubeconfig: kubeconfig}), params); !errors.Is(err, ErrImagePull) {
+		t.Errorf("Expected image pull error, got %v", err)
+	}
+	if len(fake.deleted) != 1 {
+		t.Errorf("Expected job with unpullable image to be deleted, got %v", fake.deleted)
+	}
+
+	// A timeout deletes the Job, which stops its pod
+	fake.pullError, fake.block, fake.deleted = false, true, nil
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	result, err = executor.Execute(ctx, command(&config.KubernetesConfig{Kubeconfig: kubeconfig, KeepFinished: true}), params)
+	if !errors.Is(err, context.DeadlineExceeded) || result == nil || result.ExitCode != -1 {
+		t.Errorf("Expected deadline exceeded, got %v", err)
+	}
+	if len(fake.deleted) != 1 {
+		t.Errorf("Expected timed out job to be deleted, got %v", fake.deleted)
+	}
+	fake.block = false
+
+	// A Job without a pod fails when it reports a Failed condition or the pod start deadline passes
+	defer func(timeout time.Duration) { kubernetesPodStartTimeout = timeout }(kubernetesPodStartTimeout)
+	kubernetesPodStartTimeout = 50 * time.Millisecond
+	fake.noPod, fake.jobFailed, fake.deleted = true, true, nil
+	if _, err := executor.Execute(context.Background(), command(&config.KubernetesConfig{Kubeconfig: kubeconfig}), params); err == nil || !strings.Contains(err.Error(), "job failed: DeadlineExceeded") {
+		t.Errorf("Expected failed job error, got %v", err)
+	}
+	fake.jobFailed = false
+	if _, err := executor.Execute(context.Background(), command(&config.KubernetesConfig{Kubeconfig: kubeconfig}), params); err == nil || !strings.Contains(err.Error(), "job created no pod within 50ms") {
+		t.Errorf("Expected pod start deadline error, got %v", err)
+	}
+	if len(fake.deleted) != 2 {
+		t.Errorf("Expected jobs without pods to be deleted, got %v", fake.deleted)
+	}
+	fake.noPod = false
+
+	var apiErr *kubeError
+	if _, err := executor.Execute(context.Background(), command(&config.KubernetesConfig{Kubeconfig: kubeconfig, Context: "wrong-token"}), params); !errors.As(err, &apiErr) || apiErr.status != http.StatusUnauthorized {
+		t.Errorf("Expected unauthorized error, got %v", err)
+	}
+
+	// In a pod, credentials come from the mounted service account
+	defer func(dir string) { serviceAccountDir = dir }(serviceAccountDir)
+	serviceAccountDir = t.TempDir()
+	os.WriteFile(filepath.Join(serviceAccountDir, "ca.crt"), ca, 0600)
+	os.WriteFile(filepath.Join(serviceAccountDir, "token"), []byte("secret"), 0600)
+	os.WriteFile(filepath.Join(serviceAccountDir, "namespace"), []byte("mcpfier\n"), 0600)
+	host, port, _ := net.SplitHostPort(strings.TrimPrefix(server.URL, "https://"))
+	t.Setenv("KUBERNETES_SERVICE_HOST", host)
+	t.Setenv("KUBERNETES_SERVICE_PORT", port)
+
+	result, err = executor.Execute(context.Background(), command(&config.KubernetesConfig{InCluster: true}), params)
+	if err != nil || result.Stdout != "hello\n" {
+		t.Fatalf("Expected in-cluster job to succeed, got %v", err)
+	}
+	if fake.namespace != "mcpfier" {
+		t.Errorf("Expected the pod namespace, got %q", fake.namespace)
+	}
+}
+
+// greeterExecutor is a custom executor configured by its executor block
+type greeterExecutor struct{}
+
+type greeterSettings struct {
+	Greeting string `yaml:"greeting"`
+}
+
+func (greeterExecutor) Execute(ctx context.Context, cmd *config.Command, params map[string]interface{}) (*Result, error) {
+	var settings greeterSettings
+	if err := cmd.Executor.Decode(&settings); err != nil {
+		return nil, err
+	}
+	return &Result{Stdout: fmt.Sprintf("%s, %v\n", settings.Greeting, params["name"])}, nil
+}
+
+// greeterCount numbers the greeter executors registered by TestExecutorRegistry
+var greeterCount atomic.Int64
+
+func TestExecutorRegistry(t *testing.T) {
+	// Registrations last as long as the test binary, so each run uses a new name
+	name := fmt.Sprintf("greeter-%d", greeterCount.Add(1))
+	Register(name, greeterExecutor{}, func(cmd *config.Command) error {
+		var settings greeterSettings
+		return cmd.Executor.Decode(&settings)
+	})
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	os.WriteFile(path, []byte(`
+commands:
+  - name: greet
+    parameters:
+      - name: name
+    executor:
+      type: `+name+`
+      greeting: Hello
+`), 0644)
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	recorder := &recordingAnalytics{}
+	service := New().WithAnalytics(recorder)
+	result, err := service.ExecuteByName(context.Background(), cfg, "greet", map[string]interface{}{"name": "ada"})
+	if err != nil || result.Stdout != "Hello, ada\n" {
+		t.Fatalf("Expected custom executor output, got %q (%v)", result.Stdout, err)
+	}
+	if mode := recorder.events[0].ExecutionMode; mode != name {
+		t.Errorf("Expected execution mode %s, got %s", name, mode)
+	}
+
+	// The executor's validation runs when the config is loaded
+	os.WriteFile(path, []byte(`
+commands:
+  - name: greet
+    executor:
+      type: `+name+`
+      greting: Hello
+`), 0644)
+	if _, err := config.Load(path); err == nil || !strings.Contains(err.Error(), "executor "+name) {
+		t.Errorf("Expected settings validation error, got %v", err)
+	}
+
+	for _, dup := range []string{name, "local", "container"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Expected registering %s to panic", dup)
+				}
+			}()
+			Register(dup, greeterExecutor{}, nil)
+		}()
+	}
+}
+
+// testPluginEnv makes the test binary act as an executor plugin
+const testPluginEnv = "MCPFIER_TEST_PLUGIN"
+
+// runTestPlugin serves the plugin protocol on stdin/stdout. The path argument
+// selects how it behaves.
+func runTestPlugin() {
+	decoder := json.NewDecoder(os.Stdin)
+	encoder := json.NewEncoder(os.Stdout)
+	for {
+		var req struct {
+			Type   string                 `json:"type"`
+			Params map[string]interface{} `json:"params"`
+		}
+		if decoder.Decode(&req) != nil {
+			return
+		}
+		switch req.Type {
+		case "describe":
+			encoder.Encode(map[string]interface{}{
+				"protocol":    1,
+				"type":        "description",
+				"description": "Read a secret",
+				"parameters": []map[string]interface{}{
+					{"name": "path", "required": true},
+					{"name": "version", "type": "number", "default": 1},
+				},
+			})
+		case "execute":
+			path := req.Params["path"]
+			switch path {
+			case "crash":
+				os.Exit(2)
+			case "future":
+				encoder.Encode(map[string]interface{}{"protocol": 2, "type": "result"})
+			case "slow":
+				var cancel struct{ Type string }
+				decoder.Decode(&cancel)
+				fmt.Fprintf(os.Stderr, "got %s\n", cancel.Type)
+				return
+			case "stuck":
+				time.Sleep(time.Minute)
+			default:
+				encoder.Encode(map[string]interface{}{"type": "log", "message": fmt.Sprintf("reading %v", path)})
+				encoder.Encode(map[string]interface{}{
+					"protocol":   1,
+					"type":       "result",
+					"structured": map[string]interface{}{"path": path, "version": req.Params["version"]},
+					"artifacts":  []map[string]interface{}{{"name": "key.txt", "data": []byte("key")}},
+				})
+			}
+		}
+	}
+}
+
+func TestPluginExecutor(t *testing.T) {
+	dir := t.TempDir()
+	os.Mkdir(filepath.Join(dir, "plugins"), 0755)
+	binary := "vault"
+	if runtime.GOOS == "windows" {
+		binary += ".exe"
+	}
+	if err := os.Symlink(os.Args[0], filepath.Join(dir, "plugins", binary)); err != nil {
+		t.Skipf("symlinks unavailable: %v", err)
+	}
+	path := filepath.Join(dir, "config.yaml")
+	os.WriteFile(path, []byte(`
+commands:
+  - name: vault-read
+    plugin: vault
+    kill_grace: 100ms
+    env:
+      `+testPluginEnv+`: "1"
+`), 0644)
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if err := DescribePlugins(cfg); err != nil {
+		t.Fatalf("Failed to describe plugins: %v", err)
+	}
+	cmd := &cfg.Commands[0]
+	if cmd.Description != "Read a secret" || len(cmd.Parameters) != 2 || !cmd.Parameters[0].Required {
+		t.Errorf("Expected the advertised description and parameters, got %q %+v", cmd.Description, cmd.Parameters)
+	}
+
+	recorder := &recordingAnalytics{}
+	service := New().WithAnalytics(recorder)
+	if _, err := service.ExecuteByName(context.Background(), cfg, "vault-read", nil); err == nil {
+		t.Error("Expected missing required parameter to be rejected")
+	}
+	result, err := service.ExecuteByName(context.Background(), cfg, "vault-read", map[string]interface{}{"path": "db/password"})
+	if err != nil {
+		t.Fatalf("Expected plugin call to succeed, got %v", err)
+	}
+	if result.Stdout != `{"path":"db/password","version":1}` || result.Stderr != "reading db/password\n" || result.Structured == nil {
+		t.Errorf("Unexpected result: stdout %q stderr %q structured %v", result.Stdout, result.Stderr, result.Structured)
+	}
+	if len(result.Artifacts) != 1 || string(result.Artifacts[0].Data) != "key" || result.Artifacts[0].MIMEType != "text/plain" {
+		t.Errorf("Expected a text artifact, got %+v", result.Artifacts)
+	}
+	if mode := recorder.events[len(recorder.events)-1].ExecutionMode; mode != "plugin" {
+		t.Errorf("Expected execution mode plugin, got %s", mode)
+	}
+
+	for path, want := range map[string]string{
+		"crash":  "plugin exited without a result: exit status 2",
+		"future": "plugin speaks protocol version 2, expected 1",
+	} {
+		if _, err := service.ExecuteByName(context.Background(), cfg, "vault-read", map[string]interface{}{"path": path}); err == nil || err.Error() != want {
+			t.Errorf("Expected %q for %s, got %v", want, path, err)
+		}
+	}
+
+	// Cancelled calls send cancel, and plugins that ignore it are killed after the grace period
+	for _, path := range []string{"slow", "stuck"} {
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(100*time.Millisecond, cancel)
+		start := time.Now()
+		result, err := service.ExecuteByName(ctx, cfg, "vault-read", map[string]interface{}{"path": path})
+		if !errors.Is(err, ErrCancelled) || time.Since(start) > 5*time.Second {
+			t.Errorf("Expected %s call to be cancelled promptly, got %v after %s", path, err, time.Since(start))
+		}
+		if path == "slow" && result.Stderr != "got cancel\n" {
+			t.Errorf("Expected plugin to receive cancel, got stderr %q", result.Stderr)
+		}
+	}
+}
+
+func TestWorkflowExecutor(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	os.WriteFile(path, []byte(`
+commands:
+  - name: lookup
+    script: echo
+    args: ['{"count": 3, "name": "{{.params.user}}"}']
+    parameters:
+      - name: user
+        required: true
+  - name: greet
+    script: echo
+    args: ["hello {{.params.name}}"]
+    parameters:
+      - name: name
+        required: true
+  - name: double
+    script: sh
+    args: ["-c", "echo $(({{.params.n}} * 2))"]
+    parameters:
+      - name: n
+        type: number
+        required: true
+  - name: broken
+    script: sh
+    args: ["-c", "echo broken >&2; exit 3"]
+  - name: undo
+    script: echo
+    args: ["{{.params.reason}}"]
+    parameters:
+      - name: reason
+  - name: stall
+    script: sleep
+    args: ["5"]
+
+  - name: report
+    parameters:
+      - name: user
+    workflow:
+      steps:
+        - command: lookup
+          params:
+            user: "{{.params.user}}"
+        - parallel:
+            - command: greet
+              params:
+                name: "{{.steps.lookup.json.name}}"
+            - command: double
+              params:
+                n: "{{.steps.lookup.json.count}}"
+      output: "{{.steps.greet.stdout}}{{.steps.double.stdout}}"
+  - name: tolerant
+    workflow:
+      steps:
+        - command: broken
+          on_failure: continue
+        - id: after
+          command: greet
+          params:
+            name: "{{.steps.broken.ok}} {{.steps.broken.exit_code}}"
+  - name: strict
+    workflow:
+      steps:
+        - command: broken
+        - command: greet
+          params:
+            name: unreachable
+  - name: compensating
+    workflow:
+      steps:
+        - command: broken
+          on_failure: compensate
+          compensate:
+            command: undo
+            params:
+              reason: "{{.steps.broken.stderr}}"
+  - name: slow
+    timeout: 100ms
+    workflow:
+      steps:
+        - command: stall
+`), 0644)
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	command := func(name string) *config.Command {
+		for i := range cfg.Commands {
+			if cfg.Commands[i].Name == name {
+				return &cfg.Commands[i]
+			}
+		}
+		t.Fatalf("command %s not found", name)
+		return nil
+	}
+	recorder := &recordingAnalytics{}
+	service := New().WithAnalytics(recorder)
+
+	result, err := service.Execute(context.Background(), command("report"), map[string]interface{}{"user": "ada"})
+	if err != nil {
+		t.Fatalf("Expected workflow to succeed, got %v (stderr %q)", err, result.Stderr)
+	}
+	if result.Stdout != "hello ada\n6\n" {
+		t.Errorf("Expected output from both parallel steps, got %q", result.Stdout)
+	}
+	workflowEvent := recorder.events[len(recorder.events)-1]
+	if workflowEvent.CommandName != "report" || workflowEvent.ExecutionMode != "workflow" || workflowEvent.ParentRunID != "" {
+		t.Errorf("Unexpected workflow event %+v", workflowEvent)
+	}
+	children := 0
+	for _, event := range recorder.events[:len(recorder.events)-1] {
+		if event.ParentRunID == workflowEvent.RunID && event.RunID != "" {
+			children++
+		}
+	}
+	if len(recorder.events) != 4 || children != 3 {
+		t.Errorf("Expected 3 step events under the workflow run, got %+v", recorder.events)
+	}
+
+	result, err = service.Execute(context.Background(), command("tolerant"), nil)
+	if err != nil || result.Stdout != "hello false 3\n" {
+		t.Errorf("Expected workflow to continue past the failure, got %q, %v", result.Stdout, err)
+	}
+	if !strings.Contains(result.Stderr, "step 'broken' failed: exit status 3\nbroken\n") {
+		t.Errorf("Expected the failure noted on stderr, got %q", result.Stderr)
+	}
+
+	recorder.events = nil
+	result, err = service.Execute(context.Background(), command("strict"), nil)
+	if err == nil || !strings.Contains(err.Error(), "step 'broken' failed") || result.ExitCode != 3 {
+		t.Errorf("Expected workflow to abort with the step's exit code, got %d, %v", result.ExitCode, err)
+	}
+	if len(recorder.events) != 2 {
+		t.Errorf("Expected the steps after the failure not to run, got %+v", recorder.events)
+	}
+
+	recorder.events = nil
+	_, err = service.Execute(context.Background(), command("compensating"), nil)
+	if err == nil || !strings.Contains(err.Error(), "step 'broken' failed") {
+		t.Errorf("Expected workflow to fail after compensating, got %v", err)
+	}
+	if len(recorder.events) != 3 || recorder.events[1].CommandName != "undo" || !recorder.events[1].Success {
+		t.Errorf("Expected the compensating step to run, got %+v", recorder.events)
+	}
+
+	start := time.Now()
+	_, err = service.Execute(context.Background(), command("slow"), nil)
+	if !errors.Is(err, ErrTimeout) {
+		t.Errorf("Expected workflow timeout, got %v", err)
+	}
+	if time.Since(start) > 3*time.Second {
+		t.Errorf("Expected the running step to stop at the workflow timeout, took %s", time.Since(start))
+	}
+}
@@ -0,0 +1,212 @@
+package executor
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gleicon/mcpfier/internal/config"
+	"gopkg.in/yaml.v2"
+)
+
+// serviceAccountDir holds the credentials of the pod MCPFier runs in
+var serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// kubeconfig is the subset of a kubeconfig file needed to reach the API server
+type kubeconfig struct {
+	CurrentContext string `yaml:"current-context"`
+	Clusters       []struct {
+		Name    string `yaml:"name"`
+		Cluster struct {
+			Server                   string `yaml:"server"`
+			CertificateAuthority     string `yaml:"certificate-authority"`
+			CertificateAuthorityData string `yaml:"certificate-authority-data"`
+			InsecureSkipTLSVerify    bool   `yaml:"insecure-skip-tls-verify"`
+			TLSServerName            string `yaml:"tls-server-name"`
+		} `yaml:"cluster"`
+	} `yaml:"clusters"`
+	Users []struct {
+		Name string `yaml:"name"`
+		User struct {
+			Token                 string      `yaml:"token"`
+			TokenFile             string      `yaml:"tokenFile"`
+			ClientCertificate     string      `yaml:"client-certificate"`
+			ClientCertificateData string      `yaml:"client-certificate-data"`
+			ClientKey             string      `yaml:"client-key"`
+			ClientKeyData         string      `yaml:"client-key-data"`
+			Exec                  interface{} `yaml:"exec"`
+			AuthProvider          interface{} `yaml:"auth-provider"`
+		} `yaml:"user"`
+	} `yaml:"users"`
+	Contexts []struct {
+		Name    string `yaml:"name"`
+		Context struct {
+			Cluster   string `yaml:"cluster"`
+			User      string `yaml:"user"`
+			Namespace string `yaml:"namespace"`
+		} `yaml:"context"`
+	} `yaml:"contexts"`
+}
+
+// newKubeClient loads API server credentials from the pod's service account or a kubeconfig file
+func newKubeClient(settings *config.KubernetesConfig) (*kubeClient, error) {
+	if settings.InCluster {
+		return inClusterClient()
+	}
+
+	path := settings.Kubeconfig
+	if path == "" {
+		path = os.Getenv("KUBECONFIG")
+		// Only the first file of a KUBECONFIG list is used
+		if i := strings.IndexRune(path, filepath.ListSeparator); i >= 0 {
+			path = path[:i]
+		}
+	}
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("cannot locate kubeconfig: %w", err)
+		}
+		path = filepath.Join(home, ".kube", "config")
+	}
+	return kubeconfigClient(path, settings.Context)
+}
+
+// inClusterClient uses the service account token and CA mounted into every pod
+func inClusterClient() (*kubeClient, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, fmt.Errorf("in_cluster requires KUBERNETES_SERVICE_HOST and KUBERNETES_SERVICE_PORT")
+	}
+
+	ca, err := os.ReadFile(filepath.Join(serviceAccountDir, "ca.crt"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read service account CA: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("invalid service account CA")
+	}
+	namespace, _ := os.ReadFile(filepath.Join(serviceAccountDir, "namespace"))
+
+	return &kubeClient{
+		server:    "https://" + net.JoinHostPort(host, port),
+		namespace: strings.TrimSpace(string(namespace)),
+		// The token is rotated by the kubelet, so it is read for every request
+		tokenFile: filepath.Join(serviceAccountDir, "token"),
+		client:    &http.Client{Transport: kubeTransport(&tls.Config{RootCAs: pool})},
+	}, nil
+}
+
+// kubeconfigClient uses the cluster and user of a kubeconfig context
+func kubeconfigClient(path, contextName string) (*kubeClient, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read kubeconfig: %w", err)
+	}
+	var kc kubeconfig
+	if err := yaml.Unmarshal(data, &kc); err != nil {
+		return nil, fmt.Errorf("invalid kubeconfig %s: %w", path, err)
+	}
+	// Relative file references are relative to the kubeconfig file
+	dir := filepath.Dir(path)
+	resolve := func(file string) string {
+		if file == "" || filepath.IsAbs(file) {
+			return file
+		}
+		return filepath.Join(dir, file)
+	}
+
+	if contextName == "" {
+		contextName = kc.CurrentContext
+	}
+	client := &kubeClient{}
+	clusterName, userName := "", ""
+	for _, c := range kc.Contexts {
+		if c.Name == contextName {
+			clusterName, userName, client.namespace = c.Context.Cluster, c.Context.User, c.Context.Namespace
+		}
+	}
+	if clusterName == "" {
+		return nil, fmt.Errorf("kubeconfig context '%s' not found", contextName)
+	}
+
+	tlsConfig := &tls.Config{}
+	found := false
+	for _, c := range kc.Clusters {
+		if c.Name != clusterName {
+			continue
+		}
+		found = true
+		client.server = strings.TrimSuffix(c.Cluster.Server, "/")
+		tlsConfig.InsecureSkipVerify = c.Cluster.InsecureSkipTLSVerify
+		tlsConfig.ServerName = c.Cluster.TLSServerName
+		ca, err := kubeconfigData(c.Cluster.CertificateAuthorityData, resolve(c.Cluster.CertificateAuthority))
+		if err != nil {
+			return nil, fmt.Errorf("cluster '%s' certificate authority: %w", clusterName, err)
+		}
+		if ca != nil {
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(ca) {
+				return nil, fmt.Errorf("cluster '%s': invalid certificate authority", clusterName)
+			}
+			tlsConfig.RootCAs = pool
+		}
+	}
+	if !found || client.server == "" {
+		return nil, fmt.Errorf("kubeconfig cluster '%s' not found", clusterName)
+	}
+
+	for _, u := range kc.Users {
+		if u.Name != userName {
+			continue
+		}
+		if u.User.Exec != nil || u.User.AuthProvider != nil {
+			return nil, fmt.Errorf("kubeconfig user '%s': exec and auth-provider credentials are not supported, use a token or client certificate", userName)
+		}
+		client.token = u.User.Token
+		client.tokenFile = resolve(u.User.TokenFile)
+		cert, err := kubeconfigData(u.User.ClientCertificateData, resolve(u.User.ClientCertificate))
+		if err != nil {
+			return nil, fmt.Errorf("user '%s' client certificate: %w", userName, err)
+		}
+		key, err := kubeconfigData(u.User.ClientKeyData, resolve(u.User.ClientKey))
+		if err != nil {
+			return nil, fmt.Errorf("user '%s' client key: %w", userName, err)
+		}
+		if cert != nil {
+			pair, err := tls.X509KeyPair(cert, key)
+			if err != nil {
+				return nil, fmt.Errorf("user '%s': %w", userName, err)
+			}
+			tlsConfig.Certificates = []tls.Certificate{pair}
+		}
+	}
+
+	client.client = &http.Client{Transport: kubeTransport(tlsConfig)}
+	return client, nil
+}
+
+// kubeconfigData returns inline base64 data, or the contents of file
+func kubeconfigData(data, file string) ([]byte, error) {
+	if data != "" {
+		return base64.StdEncoding.DecodeString(data)
+	}
+	if file != "" {
+		return os.ReadFile(file)
+	}
+	return nil, nil
+}
+
+// kubeTransport returns an HTTP transport that trusts the cluster's CA
+func kubeTransport(tlsConfig *tls.Config) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	return transport
+}
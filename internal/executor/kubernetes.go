@@ -0,0 +1,511 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gleicon/mcpfier/internal/config"
+)
+
+// kubernetesPollInterval is how often a Job's pod is checked while it starts and finishes
+var kubernetesPollInterval = time.Second
+
+// kubernetesPodStartTimeout bounds waiting for a Job to create its pod, which it may
+// never do, e.g. when a resource quota rejects it
+var kubernetesPodStartTimeout = 2 * time.Minute
+
+// kubernetesDeleteTimeout bounds deleting a Job after the call returned
+const kubernetesDeleteTimeout = 30 * time.Second
+
+// Pod waiting reasons that mean the image cannot be pulled
+var imagePullReasons = map[string]bool{
+	"ErrImagePull":     true,
+	"ImagePullBackOff": true,
+	"InvalidImageName": true,
+}
+
+// KubernetesExecutor runs containerized commands as Kubernetes Jobs, streaming
+// the pod's logs as output and deleting the Job when the call returns
+type KubernetesExecutor struct {
+	mu      sync.Mutex
+	clients map[string]*kubeClient // By credentials source, so connections are reused
+}
+
+// NewKubernetesExecutor creates a new Kubernetes executor
+func NewKubernetesExecutor() *KubernetesExecutor {
+	return &KubernetesExecutor{clients: make(map[string]*kubeClient)}
+}
+
+// Execute creates a Job for the command, waits for its pod to finish and returns
+// the pod's logs. The kubelet combines stdout and stderr, so all output is stdout.
+func (e *KubernetesExecutor) Execute(ctx context.Context, cmd *config.Command, params map[string]interface{}) (*Result, error) {
+	settings := cmd.Kubernetes
+	client, err := e.clientFor(settings)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrRuntimeUnavailable, err)
+	}
+	namespace := settings.Namespace
+	if namespace == "" {
+		namespace = client.namespace
+	}
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	data := templateData(params)
+	args, err := renderArgs(cmd.Args, data)
+	if err != nil {
+		return nil, err
+	}
+	env, err := renderEnv(cmd.Env, data)
+	if err != nil {
+		return nil, err
+	}
+
+	name := containerName()
+	jobsPath := "/apis/batch/v1/namespaces/" + url.PathEscape(namespace) + "/jobs"
+	podsPath := "/api/v1/namespaces/" + url.PathEscape(namespace) + "/pods"
+
+	start := time.Now()
+	if err := client.call(ctx, http.MethodPost, jobsPath, kubernetesJob(cmd, name, args, env), nil); err != nil {
+		return nil, fmt.Errorf("failed to create job: %w", err)
+	}
+
+	finished := false
+	defer func() {
+		if finished && settings.KeepFinished {
+			return
+		}
+		// Delete the Job even when ctx is done, so timed out and cancelled pods stop
+		deleteCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), kubernetesDeleteTimeout)
+		defer cancel()
+		if err := client.call(deleteCtx, http.MethodDelete, jobsPath+"/"+name+"?propagationPolicy=Background", nil, nil); err != nil {
+			log.Printf("Failed to delete job %s/%s: %v", namespace, name, err)
+		}
+	}()
+
+	pod, err := waitForPod(ctx, client, podsPath, jobsPath+"/"+name, name)
+	if err != nil {
+		if ctx.Err() != nil {
+			return &Result{ExitCode: -1}, ctx.Err()
+		}
+		return nil, err
+	}
+
+	// Logs follow the container until it exits; a dropped stream is not fatal,
+	// the exit status still comes from the pod
+	output := newOutputCapture(ctx)
+	if logs, err := client.stream(ctx, podsPath+"/"+pod+"/log?follow=true"); err == nil {
+		io.Copy(output.stdout, logs)
+		logs.Close()
+	} else if ctx.Err() == nil {
+		log.Printf("Failed to stream logs of pod %s/%s: %v", namespace, pod, err)
+	}
+
+	state, err := waitForTermination(ctx, client, podsPath+"/"+pod)
+	result := output.result()
+	result.Duration = time.Since(start)
+	if err != nil {
+		result.ExitCode = -1
+		if ctx.Err() != nil {
+			return result, ctx.Err()
+		}
+		return result, err
+	}
+	finished = true
+
+	result.ExitCode = state.ExitCode
+	switch {
+	case state.ExitCode == 0:
+	case state.Reason != "" && state.Reason != "Error" && state.Reason != "Completed":
+		err = fmt.Errorf("exit status %d (%s)", state.ExitCode, state.Reason)
+	default:
+		err = fmt.Errorf("exit status %d", state.ExitCode)
+	}
+	return result, err
+}
+
+// clientFor returns the cached API client for a credentials source
+func (e *KubernetesExecutor) clientFor(settings *config.KubernetesConfig) (*kubeClient, error) {
+	key := fmt.Sprintf("%t\x00%s\x00%s\x00%s", settings.InCluster, settings.Kubeconfig, settings.Context, os.Getenv("KUBECONFIG"))
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if client, ok := e.clients[key]; ok {
+		return client, nil
+	}
+	client, err := newKubeClient(settings)
+	if err != nil {
+		return nil, err
+	}
+	e.clients[key] = client
+	return client, nil
+}
+
+// kubernetesJob builds the Job manifest for a call. The Job never retries, its
+// pod never restarts, and the command timeout doubles as activeDeadlineSeconds
+// so the cluster stops the pod even if MCPFier cannot.
+func kubernetesJob(cmd *config.Command, name string, args []string, env map[string]string) map[string]interface{} {
+	settings := cmd.Kubernetes
+
+	labels := map[string]string{
+		"app.kubernetes.io/managed-by": "mcpfier",
+		"mcpfier.io/command":           labelValue(cmd.Name),
+	}
+	for k, v := range settings.Labels {
+		labels[k] = v
+	}
+
+	container := map[string]interface{}{
+		"name":  "command",
+		"image": cmd.Container,
+	}
+	// As with container runs, the script and arguments are passed to the image entrypoint
+	var containerArgs []string
+	if cmd.Script != "" {
+		containerArgs = append(containerArgs, cmd.Script)
+	}
+	containerArgs = append(containerArgs, args...)
+	if len(containerArgs) > 0 {
+		container["args"] = containerArgs
+	}
+	if len(env) > 0 {
+		keys := make([]string, 0, len(env))
+		for k := range env {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		vars := make([]map[string]string, 0, len(env))
+		for _, k := range keys {
+			vars = append(vars, map[string]string{"name": k, "value": env[k]})
+		}
+		container["env"] = vars
+	}
+
+	podSpec := map[string]interface{}{
+		"restartPolicy": "Never",
+	}
+	if settings.ServiceAccount != "" {
+		podSpec["serviceAccountName"] = settings.ServiceAccount
+	}
+	if opts := cmd.ContainerOptions; opts != nil {
+		applyContainerOptions(container, podSpec, opts)
+	}
+	podSpec["containers"] = []interface{}{container}
+
+	spec := map[string]interface{}{
+		"backoffLimit":            0,
+		"ttlSecondsAfterFinished": int64(settings.TTLAfterFinishedDuration / time.Second),
+		"template": map[string]interface{}{
+			"metadata": map[string]interface{}{"labels": labels},
+			"spec":     podSpec,
+		},
+	}
+	if cmd.TimeoutDuration > 0 {
+		spec["activeDeadlineSeconds"] = int64((cmd.TimeoutDuration + time.Second - 1) / time.Second)
+	}
+
+	return map[string]interface{}{
+		"apiVersion": "batch/v1",
+		"kind":       "Job",
+		"metadata":   map[string]interface{}{"name": name, "labels": labels},
+		"spec":       spec,
+	}
+}
+
+// applyContainerOptions maps container isolation settings onto the pod spec
+func applyContainerOptions(container, podSpec map[string]interface{}, opts *config.ContainerConfig) {
+	if opts.Entrypoint != "" {
+		container["command"] = []string{opts.Entrypoint}
+	}
+	if opts.Workdir != "" {
+		container["workingDir"] = opts.Workdir
+	}
+
+	limits := map[string]string{}
+	if memory := opts.MemoryBytes(); memory > 0 {
+		limits["memory"] = strconv.FormatInt(memory, 10)
+	}
+	if nanoCPUs := opts.NanoCPUs(); nanoCPUs > 0 {
+		limits["cpu"] = fmt.Sprintf("%dm", max(nanoCPUs/1e6, 1))
+	}
+	if len(limits) > 0 {
+		container["resources"] = map[string]interface{}{"limits": limits}
+	}
+
+	securityContext := map[string]interface{}{}
+	if opts.ReadOnly {
+		securityContext["readOnlyRootFilesystem"] = true
+	}
+	if opts.User != "" {
+		uid, gid, hasGroup := strings.Cut(opts.User, ":")
+		securityContext["runAsUser"], _ = strconv.ParseInt(uid, 10, 64)
+		if hasGroup {
+			securityContext["runAsGroup"], _ = strconv.ParseInt(gid, 10, 64)
+		}
+	}
+	if len(opts.CapDrop) > 0 || len(opts.CapAdd) > 0 {
+		capabilities := map[string]interface{}{}
+		if len(opts.CapDrop) > 0 {
+			capabilities["drop"] = opts.CapDrop
+		}
+		if len(opts.CapAdd) > 0 {
+			capabilities["add"] = opts.CapAdd
+		}
+		securityContext["capabilities"] = capabilities
+	}
+	if len(securityContext) > 0 {
+		container["securityContext"] = securityContext
+	}
+
+	// tmpfs paths become memory-backed emptyDir volumes
+	var volumes, mounts []interface{}
+	for i, path := range opts.Tmpfs {
+		volume := fmt.Sprintf("tmpfs-%d", i)
+		volumes = append(volumes, map[string]interface{}{"name": volume, "emptyDir": map[string]string{"medium": "Memory"}})
+		mounts = append(mounts, map[string]string{"name": volume, "mountPath": strings.SplitN(path, ":", 2)[0]})
+	}
+	if len(volumes) > 0 {
+		podSpec["volumes"] = volumes
+		container["volumeMounts"] = mounts
+	}
+}
+
+// labelValue makes s a valid label value: at most 63 alphanumerics, '-', '_' or '.',
+// starting and ending with an alphanumeric
+func labelValue(s string) string {
+	b := []byte(s)
+	for i, c := range b {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.') {
+			b[i] = '-'
+		}
+	}
+	if len(b) > 63 {
+		b = b[:63]
+	}
+	return strings.Trim(string(b), "-_.")
+}
+
+// kubePod is the subset of a pod's status needed to follow a Job
+type kubePod struct {
+	Metadata struct {
+		Name string `json:"name"`
+	} `json:"metadata"`
+	Status struct {
+		Phase             string `json:"phase"`
+		Reason            string `json:"reason"`
+		Message           string `json:"message"`
+		ContainerStatuses []struct {
+			State struct {
+				Waiting *struct {
+					Reason  string `json:"reason"`
+					Message string `json:"message"`
+				} `json:"waiting"`
+				Terminated *kubeTerminated `json:"terminated"`
+			} `json:"state"`
+		} `json:"containerStatuses"`
+	} `json:"status"`
+}
+
+// kubeJob is the subset of a Job's status needed to tell that it failed
+type kubeJob struct {
+	Status struct {
+		Conditions []struct {
+			Type    string `json:"type"`
+			Status  string `json:"status"`
+			Reason  string `json:"reason"`
+			Message string `json:"message"`
+		} `json:"conditions"`
+	} `json:"status"`
+}
+
+// kubeTerminated describes how a container exited
+type kubeTerminated struct {
+	ExitCode int    `json:"exitCode"`
+	Reason   string `json:"reason"`
+}
+
+// waitForPod waits until the Job's pod has started, returning its name. Image
+// pull failures are reported right away instead of waiting for the deadline, and
+// while there is no pod the Job is checked for failure.
+func waitForPod(ctx context.Context, client *kubeClient, podsPath, jobPath, job string) (string, error) {
+	query := "?labelSelector=" + url.QueryEscape("job-name="+job)
+	deadline := time.Now().Add(kubernetesPodStartTimeout)
+	for {
+		var pods struct {
+			Items []kubePod `json:"items"`
+		}
+		if err := client.call(ctx, http.MethodGet, podsPath+query, nil, &pods); err != nil {
+			return "", fmt.Errorf("failed to list job pods: %w", err)
+		}
+		for _, pod := range pods.Items {
+			for _, status := range pod.Status.ContainerStatuses {
+				if waiting := status.State.Waiting; waiting != nil && imagePullReasons[waiting.Reason] {
+					return "", fmt.Errorf("%w: %s: %s", ErrImagePull, waiting.Reason, waiting.Message)
+				}
+			}
+			if pod.Status.Phase != "" && pod.Status.Phase != "Pending" {
+				return pod.Metadata.Name, nil
+			}
+		}
+		if len(pods.Items) == 0 {
+			if err := checkJobFailed(ctx, client, jobPath); err != nil {
+				return "", err
+			}
+			if time.Now().After(deadline) {
+				return "", fmt.Errorf("job created no pod within %s", kubernetesPodStartTimeout)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(kubernetesPollInterval):
+		}
+	}
+}
+
+// checkJobFailed returns an error if the Job has failed, e.g. past activeDeadlineSeconds
+// before its pod started
+func checkJobFailed(ctx context.Context, client *kubeClient, jobPath string) error {
+	var job kubeJob
+	if err := client.call(ctx, http.MethodGet, jobPath, nil, &job); err != nil {
+		return fmt.Errorf("failed to get job status: %w", err)
+	}
+	for _, condition := range job.Status.Conditions {
+		if condition.Type == "Failed" && condition.Status == "True" {
+			return fmt.Errorf("job failed: %s %s", condition.Reason, condition.Message)
+		}
+	}
+	return nil
+}
+
+// waitForTermination waits until the pod's container has exited
+func waitForTermination(ctx context.Context, client *kubeClient, podPath string) (*kubeTerminated, error) {
+	for {
+		var pod kubePod
+		if err := client.call(ctx, http.MethodGet, podPath, nil, &pod); err != nil {
+			return nil, fmt.Errorf("failed to get pod status: %w", err)
+		}
+		for _, status := range pod.Status.ContainerStatuses {
+			if status.State.Terminated != nil {
+				return status.State.Terminated, nil
+			}
+		}
+		// Pods can fail without a container exit, e.g. when evicted or past activeDeadlineSeconds
+		if pod.Status.Phase == "Failed" {
+			return nil, fmt.Errorf("pod failed: %s %s", pod.Status.Reason, pod.Status.Message)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(kubernetesPollInterval):
+		}
+	}
+}
+
+// kubeClient calls the Kubernetes API server
+type kubeClient struct {
+	server    string
+	namespace string // Default namespace of the credentials
+	token     string
+	tokenFile string // Read for every request, since projected tokens rotate
+	client    *http.Client
+}
+
+// kubeError is an error response from the API server
+type kubeError struct {
+	status  int
+	message string
+}
+
+func (e *kubeError) Error() string {
+	return fmt.Sprintf("kubernetes API error (%d): %s", e.status, e.message)
+}
+
+// call sends a JSON request and decodes the JSON response into out, if set
+func (c *kubeClient) call(ctx context.Context, method, path string, body, out interface{}) error {
+	resp, err := c.do(ctx, method, path, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if out == nil {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// stream returns the body of a streaming response, such as followed logs
+func (c *kubeClient) stream(ctx context.Context, path string) (io.ReadCloser, error) {
+	resp, err := c.do(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// do sends a request to the API server. Connection failures wrap
+// ErrRuntimeUnavailable, so they can trigger a command's fallback.
+func (c *kubeClient) do(ctx context.Context, method, path string, body interface{}) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.server+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	token := c.token
+	if c.tokenFile != "" {
+		data, err := os.ReadFile(c.tokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read token: %w", err)
+		}
+		token = strings.TrimSpace(string(data))
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		if ctx.Err() == nil {
+			err = fmt.Errorf("%w: %w", ErrRuntimeUnavailable, err)
+		}
+		return nil, err
+	}
+	if resp.StatusCode >= 400 {
+		defer resp.Body.Close()
+		var status struct{ Message string }
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		if json.Unmarshal(data, &status) != nil || status.Message == "" {
+			status.Message = strings.TrimSpace(string(data))
+		}
+		return nil, &kubeError{status: resp.StatusCode, message: status.Message}
+	}
+	return resp, nil
+}
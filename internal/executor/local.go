@@ -3,6 +3,7 @@ package executor
 import (
 	"context"
 	"fmt"
+	"os"
 	"os/exec"
 
 	"github.com/gleicon/mcpfier/internal/config"
@@ -16,15 +17,42 @@ func NewLocalExecutor() *LocalExecutor {
 	return &LocalExecutor{}
 }
 
-// Execute runs a command locally
-func (e *LocalExecutor) Execute(ctx context.Context, cmd *config.Command) (string, error) {
-	execCmd := exec.CommandContext(ctx, cmd.Script, cmd.Args...)
+// Execute runs a command locally. When ctx is done the process group is terminated.
+func (e *LocalExecutor) Execute(ctx context.Context, cmd *config.Command, params map[string]interface{}) (*Result, error) {
+	outputDir, err := newOutputDir(cmd)
+	if err != nil {
+		return nil, err
+	}
+	if outputDir != "" {
+		defer os.RemoveAll(outputDir)
+	}
+
+	data := templateData(params)
+	if outputDir != "" {
+		data["output_dir"] = outputDir
+	}
+	args, err := renderArgs(cmd.Args, data)
+	if err != nil {
+		return nil, err
+	}
+	env, err := renderEnv(cmd.Env, data)
+	if err != nil {
+		return nil, err
+	}
+
+	execCmd := exec.Command(cmd.Script, args...)
 	
 	// Set environment variables
-	for k, v := range cmd.Env {
+	for k, v := range env {
 		execCmd.Env = append(execCmd.Env, fmt.Sprintf("%s=%s", k, v))
 	}
+	if outputDir != "" {
+		execCmd.Env = append(execCmd.Env, fmt.Sprintf("%s=%s", outputDirEnv, outputDir))
+	}
 	
-	output, err := execCmd.CombinedOutput()
-	return string(output), err
+	result, err := runProcess(ctx, execCmd, cmd.GetKillGrace(), nil)
+	if err == nil && outputDir != "" {
+		result.Artifacts, err = collectArtifacts(outputDir, cmd.Artifacts)
+	}
+	return result, err
 }
\ No newline at end of file
@@ -0,0 +1,380 @@
+package executor
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/gleicon/mcpfier/internal/config"
+)
+
+// pluginProtocol is the version of the plugin protocol spoken on stdin/stdout
+const pluginProtocol = 1
+
+// Plugin message types. MCPFier sends describe, execute and cancel; plugins
+// reply with description or result, and may send log messages before either.
+const (
+	pluginDescribe    = "describe"
+	pluginExecute     = "execute"
+	pluginCancel      = "cancel"
+	pluginDescription = "description"
+	pluginResult      = "result"
+	pluginLog         = "log"
+)
+
+// pluginRequest is a message from MCPFier to a plugin, one JSON object per line
+type pluginRequest struct {
+	Protocol int                    `json:"protocol"`
+	Type     string                 `json:"type"`
+	ID       string                 `json:"id,omitempty"`      // Identifies the call for execute and cancel
+	Command  string                 `json:"command,omitempty"` // Command name, for plugins serving several commands
+	Params   map[string]interface{} `json:"params,omitempty"`  // Validated tool arguments
+	Args     []string               `json:"args,omitempty"`    // The command's rendered args
+}
+
+// pluginResponse is a message from a plugin to MCPFier
+type pluginResponse struct {
+	Protocol int    `json:"protocol"`
+	Type     string `json:"type"`
+
+	// description
+	Description string            `json:"description"`
+	Parameters  []pluginParameter `json:"parameters"`
+
+	// log
+	Message string `json:"message"`
+
+	// result
+	Stdout     string           `json:"stdout"`
+	Stderr     string           `json:"stderr"`
+	ExitCode   int              `json:"exit_code"`
+	Error      string           `json:"error"`
+	Structured interface{}      `json:"structured"` // Returned as the tool's structured content
+	Artifacts  []pluginArtifact `json:"artifacts"`
+}
+
+// pluginParameter is a tool parameter advertised by a plugin
+type pluginParameter struct {
+	Name        string      `json:"name"`
+	Type        string      `json:"type"`
+	Required    bool        `json:"required"`
+	Default     interface{} `json:"default"`
+	Description string      `json:"description"`
+	Values      []string    `json:"values"`
+	Items       string      `json:"items"`
+}
+
+// pluginArtifact is a binary result, base64 encoded in JSON
+type pluginArtifact struct {
+	Name     string `json:"name"`
+	MIMEType string `json:"mime_type"`
+	Data     []byte `json:"data"`
+}
+
+// PluginExecutor runs external plugin binaries. Each call starts the plugin,
+// sends it an execute message and reads messages until its result.
+type PluginExecutor struct{}
+
+// NewPluginExecutor creates a new plugin executor
+func NewPluginExecutor() *PluginExecutor {
+	return &PluginExecutor{}
+}
+
+// Execute runs a call through the command's plugin. When ctx is done the plugin
+// is sent a cancel message, and its process group is killed if it has not
+// exited once the kill grace period has elapsed.
+func (e *PluginExecutor) Execute(ctx context.Context, cmd *config.Command, params map[string]interface{}) (*Result, error) {
+	data := templateData(params)
+	args, err := renderArgs(cmd.Args, data)
+	if err != nil {
+		return nil, err
+	}
+	env, err := renderEnv(cmd.Env, data)
+	if err != nil {
+		return nil, err
+	}
+
+	output := newOutputCapture(ctx)
+	// Log messages and the plugin's own stderr share the stderr stream
+	stderr := &lockedWriter{w: output.stderr}
+	p, err := startPlugin(cmd, env, stderr)
+	if err != nil {
+		return &Result{ExitCode: -1}, err
+	}
+	defer p.close()
+
+	start := time.Now()
+	id := pluginCallID()
+	if err := p.send(pluginRequest{Type: pluginExecute, ID: id, Command: cmd.Name, Params: params, Args: args}); err != nil {
+		return &Result{ExitCode: -1}, err
+	}
+
+	replies := make(chan pluginReply, 1)
+	go func() {
+		replies <- p.receive(pluginResult, stderr)
+	}()
+
+	var reply pluginReply
+	select {
+	case reply = <-replies:
+	case <-ctx.Done():
+		p.send(pluginRequest{Type: pluginCancel, ID: id})
+		select {
+		case reply = <-replies:
+		case <-time.After(cmd.GetKillGrace()):
+			killProcessGroup(p.cmd)
+			reply = <-replies
+		}
+	}
+	p.close()
+
+	msg := reply.msg
+	if reply.err == nil {
+		io.WriteString(output.stdout, msg.Stdout)
+		io.WriteString(stderr, msg.Stderr)
+	}
+	result := output.result()
+	result.Duration = time.Since(start)
+
+	if ctx.Err() != nil {
+		result.ExitCode = -1
+		return result, ctx.Err()
+	}
+	if reply.err != nil {
+		result.ExitCode, result.Signal = exitStatus(p.cmd)
+		if result.ExitCode == 0 {
+			result.ExitCode = -1
+		}
+		return result, reply.err
+	}
+
+	result.ExitCode = msg.ExitCode
+	if msg.Structured != nil {
+		result.Structured = msg.Structured
+		// Clients without structured content support read the text
+		if result.Stdout == "" {
+			encoded, err := json.Marshal(msg.Structured)
+			if err != nil {
+				return result, fmt.Errorf("plugin returned invalid structured content: %w", err)
+			}
+			result.Stdout = string(encoded)
+		}
+	}
+	for _, artifact := range msg.Artifacts {
+		if len(artifact.Data) > maxArtifactSize {
+			return result, fmt.Errorf("artifact '%s' is %d bytes, larger than %d", artifact.Name, len(artifact.Data), maxArtifactSize)
+		}
+		if artifact.MIMEType == "" {
+			artifact.MIMEType = detectMIMEType(artifact.Name, artifact.Data)
+		}
+		result.Artifacts = append(result.Artifacts, Artifact(artifact))
+	}
+
+	switch {
+	case msg.Error != "":
+		err = errors.New(msg.Error)
+	case msg.ExitCode != 0:
+		err = fmt.Errorf("exit status %d", msg.ExitCode)
+	}
+	return result, err
+}
+
+// DescribePlugins asks the plugin of every plugin command for its description
+// and parameters, and applies them to commands that do not declare their own.
+// Commands whose plugin cannot describe itself keep their configuration; the
+// returned error lists them.
+func DescribePlugins(cfg *config.Config) error {
+	var errs []error
+	for i := range cfg.Commands {
+		cmd := &cfg.Commands[i]
+		if !cmd.IsPlugin() {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.Plugins.DescribeTimeoutDuration)
+		description, err := describePlugin(ctx, cmd)
+		cancel()
+		if err == nil {
+			err = cmd.ApplyPluginDescription(description.Description, description.parameters())
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("command '%s': %w", cmd.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// describePlugin starts a command's plugin and asks for its description
+func describePlugin(ctx context.Context, cmd *config.Command) (*pluginResponse, error) {
+	// The environment is rendered without arguments, since there is no call yet
+	env, err := renderEnv(cmd.Env, templateData(nil))
+	if err != nil {
+		return nil, err
+	}
+	p, err := startPlugin(cmd, env, io.Discard)
+	if err != nil {
+		return nil, err
+	}
+	defer p.close()
+	stop := context.AfterFunc(ctx, func() {
+		killProcessGroup(p.cmd)
+	})
+	defer stop()
+
+	if err := p.send(pluginRequest{Type: pluginDescribe}); err != nil {
+		return nil, err
+	}
+	reply := p.receive(pluginDescription, io.Discard)
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("plugin did not describe itself: %w", ctx.Err())
+	}
+	return reply.msg, reply.err
+}
+
+// parameters converts the advertised parameters to parameter definitions
+func (r *pluginResponse) parameters() []config.Parameter {
+	var params []config.Parameter
+	for _, p := range r.Parameters {
+		params = append(params, config.Parameter{
+			Name:        p.Name,
+			Type:        p.Type,
+			Required:    p.Required,
+			Default:     p.Default,
+			Description: p.Description,
+			Values:      p.Values,
+			Items:       p.Items,
+		})
+	}
+	return params
+}
+
+// pluginProcess is a running plugin with its protocol streams
+type pluginProcess struct {
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	decoder *json.Decoder
+	grace   time.Duration // How long the plugin gets to exit once stdin is closed
+	done    chan struct{} // Closed once the process has exited
+	once    sync.Once
+	mu      sync.Mutex // Serializes writes to stdin
+}
+
+// pluginReply is the message a plugin answered with, or why it did not
+type pluginReply struct {
+	msg *pluginResponse
+	err error
+}
+
+// startPlugin starts the plugin binary of a command. Its stderr goes to stderr.
+func startPlugin(cmd *config.Command, env map[string]string, stderr io.Writer) (*pluginProcess, error) {
+	execCmd := exec.Command(cmd.PluginPath)
+	for k, v := range env {
+		execCmd.Env = append(execCmd.Env, fmt.Sprintf("%s=%s", k, v))
+	}
+	execCmd.Stderr = stderr
+	setProcessGroup(execCmd)
+
+	stdin, err := execCmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := execCmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := execCmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start plugin: %w", err)
+	}
+
+	p := &pluginProcess{
+		cmd:     execCmd,
+		stdin:   stdin,
+		decoder: json.NewDecoder(stdout),
+		grace:   cmd.GetKillGrace(),
+		done:    make(chan struct{}),
+	}
+	return p, nil
+}
+
+// send writes a message to the plugin
+func (p *pluginProcess) send(req pluginRequest) error {
+	req.Protocol = pluginProtocol
+	data, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, err := p.stdin.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to send %s to plugin: %w", req.Type, err)
+	}
+	return nil
+}
+
+// receive reads messages until one of type want, writing log messages to logs
+func (p *pluginProcess) receive(want string, logs io.Writer) pluginReply {
+	for {
+		var msg pluginResponse
+		if err := p.decoder.Decode(&msg); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				// Report how the plugin exited rather than the closed pipe
+				p.close()
+				return pluginReply{err: fmt.Errorf("plugin exited without a %s: %v", want, p.cmd.ProcessState)}
+			}
+			return pluginReply{err: fmt.Errorf("invalid plugin message: %w", err)}
+		}
+
+		switch msg.Type {
+		case pluginLog:
+			io.WriteString(logs, msg.Message+"\n")
+		case want:
+			if msg.Protocol != pluginProtocol {
+				return pluginReply{err: fmt.Errorf("plugin speaks protocol version %d, expected %d", msg.Protocol, pluginProtocol)}
+			}
+			return pluginReply{msg: &msg}
+		default:
+			return pluginReply{err: fmt.Errorf("unexpected plugin message type '%s', expected %s", msg.Type, want)}
+		}
+	}
+}
+
+// close closes stdin, telling the plugin no more messages follow, and waits for
+// it to exit. Plugins still running after the kill grace period are killed.
+func (p *pluginProcess) close() {
+	p.once.Do(func() {
+		p.mu.Lock()
+		p.stdin.Close()
+		p.mu.Unlock()
+		timer := time.AfterFunc(p.grace, func() {
+			killProcessGroup(p.cmd)
+		})
+		p.cmd.Wait()
+		timer.Stop()
+		close(p.done)
+	})
+	<-p.done
+}
+
+// pluginCallID returns a random identifier for an execute call
+func pluginCallID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return fmt.Sprintf("%x", b)
+}
+
+// lockedWriter serializes writes from the plugin's stderr and its log messages
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (w *lockedWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.w.Write(p)
+}
@@ -0,0 +1,400 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/gleicon/mcpfier/internal/config"
+)
+
+// errPoolClosed is returned when a call arrives while the pool is draining
+var errPoolClosed = errors.New("container pool is shut down")
+
+// poolRetryDelay is how long the pool waits before retrying a failed container start.
+// The delay doubles after each failure, up to poolRetryMaxDelay.
+var poolRetryDelay = time.Second
+
+const poolRetryMaxDelay = time.Minute
+
+// PoolStatus reports the state of a warm container pool
+type PoolStatus struct {
+	Command  string `json:"command"`
+	Size     int    `json:"size"`
+	Idle     int    `json:"idle"`
+	Busy     int    `json:"busy"`
+	Starting int    `json:"starting"`
+	Healthy  bool   `json:"healthy"`
+	Error    string `json:"error,omitempty"`
+}
+
+// pooledContainer is a running container that calls are exec'd into
+type pooledContainer struct {
+	name      string
+	outputDir string // Host directory mounted at containerOutputDir, if the command has artifacts
+	uses      int
+	lastUsed  time.Time
+}
+
+// containerPool keeps pre-started containers for one command. Containers are
+// replaced after MaxUses calls, after IdleTimeout without calls, and after a
+// call that failed to finish (timeout or cancellation), since processes from
+// that call may still be running inside.
+type containerPool struct {
+	runtime PooledRuntime
+	cmd     config.Command
+
+	ctx    context.Context // Cancelled on Close to abort container startups
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu        sync.Mutex
+	idle      []*pooledContainer
+	busy      int
+	starting  int
+	closed    bool
+	lastErr   error
+	available chan struct{} // Closed and replaced whenever the pool state changes
+}
+
+// newContainerPool creates a pool and starts warming it in the background
+func newContainerPool(runtime PooledRuntime, cmd config.Command) *containerPool {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &containerPool{
+		runtime:   runtime,
+		cmd:       cmd,
+		ctx:       ctx,
+		cancel:    cancel,
+		available: make(chan struct{}),
+	}
+
+	p.wg.Add(2)
+	go func() {
+		defer p.wg.Done()
+		p.fill()
+	}()
+	go func() {
+		defer p.wg.Done()
+		p.recycleIdle()
+	}()
+	return p
+}
+
+// acquire takes an idle container, starting one if the pool is below its size,
+// or waits for one to be released
+func (p *containerPool) acquire(ctx context.Context) (*pooledContainer, error) {
+	for {
+		p.mu.Lock()
+		if p.closed {
+			p.mu.Unlock()
+			return nil, errPoolClosed
+		}
+		if n := len(p.idle); n > 0 {
+			c := p.idle[n-1]
+			p.idle = p.idle[:n-1]
+			p.busy++
+			p.mu.Unlock()
+			return c, nil
+		}
+		if p.total() < p.cmd.Pool.Size {
+			p.starting++
+			p.mu.Unlock()
+
+			c, err := p.start(ctx)
+
+			p.mu.Lock()
+			p.starting--
+			if ctx.Err() == nil {
+				p.lastErr = err
+			}
+			if err == nil {
+				p.busy++
+			}
+			p.notify()
+			p.mu.Unlock()
+			return c, err
+		}
+		available := p.available
+		p.mu.Unlock()
+
+		select {
+		case <-available:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// release returns a container to the pool, or replaces it if it is used up or
+// the call did not finish cleanly
+func (p *containerPool) release(c *pooledContainer, healthy bool) {
+	c.uses++
+	c.lastUsed = time.Now()
+
+	p.mu.Lock()
+	p.busy--
+	keep := healthy && !p.closed && c.uses < p.cmd.Pool.MaxUses
+	replace := !keep && !p.closed
+	if keep {
+		p.idle = append(p.idle, c)
+	}
+	if replace {
+		p.wg.Add(1)
+	}
+	p.notify()
+	p.mu.Unlock()
+
+	if keep {
+		return
+	}
+	p.remove(c)
+	if replace {
+		go func() {
+			defer p.wg.Done()
+			p.fill()
+		}()
+	}
+}
+
+// fill starts containers until the pool is back at its size. Failed starts are
+// retried with backoff until one succeeds or the pool is closed.
+func (p *containerPool) fill() {
+	delay := poolRetryDelay
+	for {
+		p.mu.Lock()
+		if p.closed || p.total() >= p.cmd.Pool.Size {
+			p.mu.Unlock()
+			return
+		}
+		p.starting++
+		p.mu.Unlock()
+
+		c, err := p.start(p.ctx)
+
+		p.mu.Lock()
+		p.starting--
+		closed := p.closed
+		if p.ctx.Err() == nil {
+			p.lastErr = err
+		}
+		if err == nil && !closed {
+			p.idle = append(p.idle, c)
+		}
+		p.notify()
+		p.mu.Unlock()
+
+		// The pool was drained while this container was starting
+		if err == nil && closed {
+			p.remove(c)
+			return
+		}
+
+		if err != nil {
+			if p.ctx.Err() != nil {
+				return
+			}
+			log.Printf("Container pool for %s: %v, retrying in %s", p.cmd.Name, err, delay)
+			select {
+			case <-p.ctx.Done():
+				return
+			case <-time.After(delay):
+			}
+			delay = min(2*delay, poolRetryMaxDelay)
+			continue
+		}
+		delay = poolRetryDelay
+	}
+}
+
+// recycleIdle periodically replaces containers that have been idle too long
+func (p *containerPool) recycleIdle() {
+	interval := p.cmd.Pool.IdleTimeoutDuration / 2
+	if interval > time.Minute {
+		interval = time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		var expired []*pooledContainer
+		p.mu.Lock()
+		kept := p.idle[:0]
+		for _, c := range p.idle {
+			if time.Since(c.lastUsed) >= p.cmd.Pool.IdleTimeoutDuration {
+				expired = append(expired, c)
+			} else {
+				kept = append(kept, c)
+			}
+		}
+		p.idle = kept
+		p.mu.Unlock()
+
+		for _, c := range expired {
+			p.remove(c)
+		}
+		// Refill in the background, since fill keeps retrying while starts fail
+		if len(expired) > 0 {
+			p.wg.Add(1)
+			go func() {
+				defer p.wg.Done()
+				p.fill()
+			}()
+		}
+	}
+}
+
+// start starts a container that runs the keepalive process
+func (p *containerPool) start(ctx context.Context) (*pooledContainer, error) {
+	c := &pooledContainer{name: containerName(), lastUsed: time.Now()}
+
+	spec := &ContainerSpec{Name: c.name, Image: p.cmd.Container}
+	if p.cmd.ContainerOptions != nil {
+		spec.Options = *p.cmd.ContainerOptions
+	}
+	keepalive := p.cmd.Pool.Keepalive
+	spec.Options.Entrypoint = keepalive[0]
+	spec.Command = keepalive[1:]
+
+	if len(p.cmd.Artifacts) > 0 {
+		dir, err := os.MkdirTemp("", "mcpfier-pool-")
+		if err != nil {
+			return nil, err
+		}
+		// The container user is not necessarily the server's user
+		os.Chmod(dir, 0777)
+		c.outputDir = dir
+		spec.Options.Mounts = append(append([]config.Mount(nil), spec.Options.Mounts...),
+			config.Mount{Source: dir, Target: containerOutputDir})
+	}
+
+	if err := p.runtime.Start(ctx, spec); err != nil {
+		p.remove(c)
+		return nil, err
+	}
+	return c, nil
+}
+
+// remove removes a container and its output directory
+func (p *containerPool) remove(c *pooledContainer) {
+	p.runtime.Remove(c.name)
+	if c.outputDir != "" {
+		os.RemoveAll(c.outputDir)
+	}
+}
+
+// execute runs a call inside a pooled container
+func (p *containerPool) execute(ctx context.Context, params map[string]interface{}) (*Result, error) {
+	c, err := p.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	healthy := false
+	defer func() {
+		p.release(c, healthy)
+	}()
+
+	// Each call gets its own subdirectory of the container's output directory
+	data := templateData(params)
+	runDir := ""
+	if c.outputDir != "" {
+		runDir, err = os.MkdirTemp(c.outputDir, "run-")
+		if err != nil {
+			return nil, err
+		}
+		os.Chmod(runDir, 0777)
+		defer os.RemoveAll(runDir)
+		data["output_dir"] = path.Join(containerOutputDir, filepath.Base(runDir))
+	}
+
+	args, err := renderArgs(p.cmd.Args, data)
+	if err != nil {
+		healthy = true
+		return nil, err
+	}
+	env, err := renderEnv(p.cmd.Env, data)
+	if err != nil {
+		healthy = true
+		return nil, err
+	}
+	if runDir != "" {
+		env[outputDirEnv] = data["output_dir"].(string)
+	}
+
+	spec := &ExecSpec{
+		Env:       env,
+		KillGrace: p.cmd.GetKillGrace(),
+	}
+	if p.cmd.ContainerOptions != nil {
+		spec.User = p.cmd.ContainerOptions.User
+		spec.Workdir = p.cmd.ContainerOptions.Workdir
+	}
+	if p.cmd.Script != "" {
+		spec.Command = append(spec.Command, p.cmd.Script)
+	}
+	spec.Command = append(spec.Command, args...)
+
+	result, err := p.runtime.Exec(ctx, c.name, spec)
+	// A container the runtime cannot reach is replaced rather than reused
+	healthy = ctx.Err() == nil && !errors.Is(err, ErrRuntimeUnavailable)
+	if err == nil && runDir != "" {
+		result.Artifacts, err = collectArtifacts(runDir, p.cmd.Artifacts)
+	}
+	return result, err
+}
+
+// status reports the pool's current state
+func (p *containerPool) status() PoolStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	status := PoolStatus{
+		Command:  p.cmd.Name,
+		Size:     p.cmd.Pool.Size,
+		Idle:     len(p.idle),
+		Busy:     p.busy,
+		Starting: p.starting,
+		Healthy:  p.lastErr == nil,
+	}
+	if p.lastErr != nil {
+		status.Error = p.lastErr.Error()
+	}
+	return status
+}
+
+// close drains the pool: idle containers are removed now, busy ones when released
+func (p *containerPool) close() {
+	p.mu.Lock()
+	p.closed = true
+	idle := p.idle
+	p.idle = nil
+	p.notify()
+	p.mu.Unlock()
+
+	p.cancel()
+	p.wg.Wait()
+	for _, c := range idle {
+		p.remove(c)
+	}
+}
+
+// total counts containers that are idle, busy or starting. Callers hold p.mu.
+func (p *containerPool) total() int {
+	return len(p.idle) + p.busy + p.starting
+}
+
+// notify wakes up callers waiting for a container. Callers hold p.mu.
+func (p *containerPool) notify() {
+	close(p.available)
+	p.available = make(chan struct{})
+}
@@ -0,0 +1,97 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os/exec"
+	"time"
+)
+
+// ErrTimeout is returned when a command runs longer than its configured timeout
+var ErrTimeout = errors.New("command timed out")
+
+// ErrCancelled is returned when the caller cancelled the request while the command was running
+var ErrCancelled = errors.New("command cancelled")
+
+// runProcess runs execCmd until it exits or ctx is done. When ctx is done the whole
+// process group gets SIGTERM, then SIGKILL once grace has elapsed. stop, if set, runs
+// after the process group was signalled (e.g. to kill a container the CLI started).
+func runProcess(ctx context.Context, execCmd *exec.Cmd, grace time.Duration, stop func()) (*Result, error) {
+	output := newOutputCapture(ctx)
+	execCmd.Stdout = output.stdout
+	execCmd.Stderr = output.stderr
+
+	execCmd.WaitDelay = grace
+	setProcessGroup(execCmd)
+
+	start := time.Now()
+	if err := execCmd.Start(); err != nil {
+		return &Result{ExitCode: -1}, err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- execCmd.Wait()
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		terminateProcessGroup(execCmd)
+		select {
+		case <-done:
+		case <-time.After(grace):
+			killProcessGroup(execCmd)
+			<-done
+		}
+
+		if stop != nil {
+			stop()
+		}
+		err = ctx.Err()
+	}
+
+	result := output.result()
+	result.Duration = time.Since(start)
+	result.ExitCode, result.Signal = exitStatus(execCmd)
+	return result, err
+}
+
+// outputCapture collects stdout and stderr, streaming them line by line when the
+// caller asked for it (see WithOutput)
+type outputCapture struct {
+	stdout, stderr       io.Writer
+	stdoutBuf, stderrBuf bytes.Buffer
+	writers              []*lineWriter
+}
+
+// newOutputCapture creates the writers a process or container writes its output to
+func newOutputCapture(ctx context.Context) *outputCapture {
+	c := &outputCapture{}
+	c.stdout = &c.stdoutBuf
+	c.stderr = &c.stderrBuf
+
+	if fn := outputFromContext(ctx); fn != nil {
+		stdoutWriter := newLineWriter("stdout", &c.stdoutBuf, fn)
+		stderrWriter := newLineWriter("stderr", &c.stderrBuf, fn)
+		c.stdout = stdoutWriter
+		c.stderr = stderrWriter
+		c.writers = append(c.writers, stdoutWriter, stderrWriter)
+	}
+	return c
+}
+
+// result flushes partial lines and returns the captured output.
+// It must only be called once the writers are no longer in use.
+func (c *outputCapture) result() *Result {
+	for _, w := range c.writers {
+		w.Flush()
+	}
+	return &Result{
+		Stdout: c.stdoutBuf.String(),
+		Stderr: c.stderrBuf.String(),
+	}
+}
@@ -0,0 +1,44 @@
+//go:build !windows
+
+package executor
+
+import (
+	"os/exec"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// setProcessGroup starts the command in its own process group so the whole tree can be signalled
+func setProcessGroup(execCmd *exec.Cmd) {
+	if execCmd.SysProcAttr == nil {
+		execCmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	execCmd.SysProcAttr.Setpgid = true
+}
+
+// terminateProcessGroup sends SIGTERM to the command's process group
+func terminateProcessGroup(execCmd *exec.Cmd) {
+	if execCmd.Process != nil {
+		syscall.Kill(-execCmd.Process.Pid, syscall.SIGTERM)
+	}
+}
+
+// killProcessGroup sends SIGKILL to the command's process group
+func killProcessGroup(execCmd *exec.Cmd) {
+	if execCmd.Process != nil {
+		syscall.Kill(-execCmd.Process.Pid, syscall.SIGKILL)
+	}
+}
+
+// exitStatus returns the exit code and terminating signal of a finished command
+func exitStatus(execCmd *exec.Cmd) (int, string) {
+	state := execCmd.ProcessState
+	if state == nil {
+		return -1, ""
+	}
+	if ws, ok := state.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+		return -1, unix.SignalName(ws.Signal())
+	}
+	return state.ExitCode(), ""
+}
@@ -0,0 +1,30 @@
+//go:build windows
+
+package executor
+
+import "os/exec"
+
+// setProcessGroup is a no-op on Windows
+func setProcessGroup(execCmd *exec.Cmd) {}
+
+// terminateProcessGroup kills the process; Windows has no SIGTERM
+func terminateProcessGroup(execCmd *exec.Cmd) {
+	if execCmd.Process != nil {
+		execCmd.Process.Kill()
+	}
+}
+
+// killProcessGroup kills the process
+func killProcessGroup(execCmd *exec.Cmd) {
+	if execCmd.Process != nil {
+		execCmd.Process.Kill()
+	}
+}
+
+// exitStatus returns the exit code of a finished command; Windows has no signals
+func exitStatus(execCmd *exec.Cmd) (int, string) {
+	if execCmd.ProcessState == nil {
+		return -1, ""
+	}
+	return execCmd.ProcessState.ExitCode(), ""
+}
@@ -0,0 +1,117 @@
+package executor
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/gleicon/mcpfier/internal/config"
+)
+
+// modeLocal is the mode of commands without any execution settings
+const modeLocal = "local"
+
+// builtins creates the built-in executors of a service, one for each config
+// backend and one for local commands. The config backends decide which of them
+// runs a command and validate its settings.
+var builtins = []struct {
+	name        string
+	newExecutor func(s *Service) Executor
+}{
+	{modeLocal, func(s *Service) Executor { return NewLocalExecutor() }},
+	{"webhook", func(s *Service) Executor { return s.webhook }},
+	{"kubernetes", func(s *Service) Executor { return NewKubernetesExecutor() }},
+	{"container", func(s *Service) Executor { return s.container }},
+	{"ssh", func(s *Service) Executor { return NewSSHExecutor() }},
+	{"sql", func(s *Service) Executor { return s.sql }},
+	{"starlark", func(s *Service) Executor {
+		starlark := NewStarlarkExecutor(s.webhook)
+		starlark.run = s.Execute
+		return starlark
+	}},
+	{"wasm", func(s *Service) Executor { return NewWasmExecutor() }},
+	{"sandbox", func(s *Service) Executor { return NewSandboxExecutor() }},
+	{"plugin", func(s *Service) Executor { return NewPluginExecutor() }},
+	{"workflow", func(s *Service) Executor {
+		workflow := NewWorkflowExecutor()
+		workflow.run = s.Execute
+		return workflow
+	}},
+}
+
+// registration is an executor in the registry. Built-in executors are created
+// for each service; custom ones are shared.
+type registration struct {
+	newExecutor func(s *Service) Executor
+	builtin     bool
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]registration)
+)
+
+// init registers the built-in executors and checks they match the config backends
+func init() {
+	backends := config.Backends()
+	for _, b := range builtins {
+		if b.name != modeLocal && !slices.Contains(backends, b.name) {
+			panic("executor: no config backend selects built-in executor " + b.name)
+		}
+		register(b.name, registration{newExecutor: b.newExecutor, builtin: true})
+	}
+	for _, backend := range backends {
+		if _, ok := registry[backend]; !ok {
+			panic("executor: no built-in executor for config backend " + backend)
+		}
+	}
+}
+
+// Register makes a custom executor available to commands with an
+// `executor: {type: name}` block. validate checks those commands, typically by
+// decoding the block's settings, when the config is loaded and may be nil.
+// Executors are registered before the config is loaded, usually from init;
+// registering a name twice or a built-in mode name panics.
+func Register(name string, executor Executor, validate func(cmd *config.Command) error) {
+	register(name, registration{newExecutor: func(*Service) Executor { return executor }})
+	config.RegisterExecutorType(name, validate)
+}
+
+// register adds an executor to the registry under name
+func register(name string, r registration) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if existing, dup := registry[name]; dup {
+		if existing.builtin {
+			panic("executor: cannot register built-in mode " + name)
+		}
+		panic("executor: Register called twice for " + name)
+	}
+	registry[name] = r
+}
+
+// executionMode returns the name of the executor that runs cmd, which
+// analytics record as the execution mode
+func executionMode(cmd *config.Command) string {
+	if cmd.IsCustom() {
+		return cmd.Executor.Type
+	}
+	if backend := cmd.Backend(); backend != "" {
+		return backend
+	}
+	return modeLocal
+}
+
+// executor returns the executor for a mode: the service's own for built-in
+// modes, otherwise a registered custom one
+func (s *Service) executor(mode string) (Executor, error) {
+	if executor, ok := s.executors[mode]; ok {
+		return executor, nil
+	}
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	if r, ok := registry[mode]; ok && !r.builtin {
+		return r.newExecutor(s), nil
+	}
+	return nil, fmt.Errorf("unknown executor type '%s'", mode)
+}
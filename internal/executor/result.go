@@ -0,0 +1,37 @@
+package executor
+
+import "time"
+
+// Result is the structured outcome of a command execution
+type Result struct {
+	Stdout          string
+	Stderr          string
+	ExitCode        int    // Process exit code; -1 if the process was killed by a signal, 1 for a failed webhook response
+	Signal          string // Signal that terminated the process, e.g. SIGKILL
+	HTTPStatus      int    // Response status code for webhook executions
+	Duration        time.Duration
+	StdoutTruncated bool
+	StderrTruncated bool
+	Artifacts       []Artifact  // Files or binary responses returned as image, audio or resource content
+	Structured      interface{} // Structured tool output, such as a plugin's structured result
+}
+
+// Output returns stdout followed by stderr, for callers that want a single stream
+func (r *Result) Output() string {
+	if r == nil {
+		return ""
+	}
+	return r.Stdout + r.Stderr
+}
+
+// Size returns the number of output bytes captured, including artifacts
+func (r *Result) Size() int64 {
+	if r == nil {
+		return 0
+	}
+	size := int64(len(r.Stdout) + len(r.Stderr))
+	for _, artifact := range r.Artifacts {
+		size += int64(len(artifact.Data))
+	}
+	return size
+}
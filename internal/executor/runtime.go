@@ -0,0 +1,67 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gleicon/mcpfier/internal/config"
+)
+
+// Runtimes wrap these errors so fallback policies can tell infrastructure
+// failures apart from the command itself failing
+var (
+	ErrRuntimeUnavailable = errors.New("container runtime unavailable")
+	ErrImagePull          = errors.New("container image pull failed")
+)
+
+// ContainerRuntime runs a container to completion and returns its output.
+// When ctx is done the container must be stopped (SIGTERM, then SIGKILL after
+// KillGrace) and removed before Run returns.
+type ContainerRuntime interface {
+	Run(ctx context.Context, spec *ContainerSpec) (*Result, error)
+}
+
+// PooledRuntime is implemented by runtimes that can keep containers running
+// and execute commands inside them, which warm pools require
+type PooledRuntime interface {
+	ContainerRuntime
+	// Start starts a detached container running spec.Command
+	Start(ctx context.Context, spec *ContainerSpec) error
+	// Exec runs a command inside a running container
+	Exec(ctx context.Context, container string, spec *ExecSpec) (*Result, error)
+	// Remove force-removes a container
+	Remove(container string)
+}
+
+// ExecSpec describes a command executed in a running container
+type ExecSpec struct {
+	Command   []string
+	Env       map[string]string
+	User      string
+	Workdir   string
+	KillGrace time.Duration
+}
+
+// ContainerSpec describes a single container run
+type ContainerSpec struct {
+	Name      string                 // Unique container name
+	Image     string
+	Command   []string               // Script and arguments; empty runs the image default
+	Env       map[string]string
+	Options   config.ContainerConfig // Limits, mounts, network and user settings
+	KillGrace time.Duration
+}
+
+// NewContainerRuntime creates the runtime selected in the configuration
+func NewContainerRuntime(cfg config.ContainerRuntimeConfig) (ContainerRuntime, error) {
+	if cfg.GetType() == config.RuntimeEngine {
+		return NewEngineRuntime(cfg.Host)
+	}
+
+	binary := cfg.Binary
+	if binary == "" {
+		binary = cfg.GetType()
+	}
+	return NewCLIRuntime(binary), nil
+}
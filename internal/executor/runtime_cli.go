@@ -0,0 +1,182 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gleicon/mcpfier/internal/config"
+)
+
+// CLIRuntime runs containers through a docker-compatible CLI: docker, podman or nerdctl
+type CLIRuntime struct {
+	binary string
+}
+
+// NewCLIRuntime creates a runtime that invokes the given CLI binary
+func NewCLIRuntime(binary string) *CLIRuntime {
+	return &CLIRuntime{binary: binary}
+}
+
+// Run executes `<cli> run --rm` and streams its output
+func (r *CLIRuntime) Run(ctx context.Context, spec *ContainerSpec) (*Result, error) {
+	runArgs := []string{"run", "--rm", "--name", spec.Name}
+	
+	// Add environment variables
+	for k, v := range spec.Env {
+		runArgs = append(runArgs, "-e", fmt.Sprintf("%s=%s", k, v))
+	}
+	
+	// Add resource limits, mounts, network and user settings
+	runArgs = append(runArgs, containerFlags(&spec.Options)...)
+	
+	// Add container image, script and args
+	runArgs = append(runArgs, spec.Image)
+	runArgs = append(runArgs, spec.Command...)
+	
+	execCmd := exec.Command(r.binary, runArgs...)
+	result, err := runProcess(ctx, execCmd, spec.KillGrace, func() {
+		r.killContainer(spec.Name)
+	})
+	return result, cliError(err, result.ExitCode, result.Stderr)
+}
+
+// Start runs a detached container for a warm pool
+func (r *CLIRuntime) Start(ctx context.Context, spec *ContainerSpec) error {
+	runArgs := []string{"run", "-d", "--name", spec.Name}
+	for k, v := range spec.Env {
+		runArgs = append(runArgs, "-e", fmt.Sprintf("%s=%s", k, v))
+	}
+	runArgs = append(runArgs, containerFlags(&spec.Options)...)
+	runArgs = append(runArgs, spec.Image)
+	runArgs = append(runArgs, spec.Command...)
+
+	execCmd := exec.CommandContext(ctx, r.binary, runArgs...)
+	if output, err := execCmd.CombinedOutput(); err != nil {
+		err = cliError(err, execCmd.ProcessState.ExitCode(), string(output))
+		return fmt.Errorf("failed to start container: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// Exec runs `<cli> exec` in a running container. Processes started by a cancelled
+// exec may keep running inside the container, so callers should remove it. Failures
+// of the CLI itself are classified like Run's, so fallback policies apply.
+func (r *CLIRuntime) Exec(ctx context.Context, container string, spec *ExecSpec) (*Result, error) {
+	execArgs := []string{"exec"}
+	for k, v := range spec.Env {
+		execArgs = append(execArgs, "-e", fmt.Sprintf("%s=%s", k, v))
+	}
+	if spec.User != "" {
+		execArgs = append(execArgs, "--user", spec.User)
+	}
+	if spec.Workdir != "" {
+		execArgs = append(execArgs, "--workdir", spec.Workdir)
+	}
+	execArgs = append(execArgs, container)
+	execArgs = append(execArgs, spec.Command...)
+
+	result, err := runProcess(ctx, exec.Command(r.binary, execArgs...), spec.KillGrace, nil)
+	return result, cliError(err, result.ExitCode, result.Stderr)
+}
+
+// Remove force-removes a container
+func (r *CLIRuntime) Remove(container string) {
+	r.killContainer(container)
+}
+
+// cliRunFailure is the exit status docker and podman use when `run` fails
+// before the container starts
+const cliRunFailure = 125
+
+// cliError classifies a failed CLI invocation: a missing binary or unreachable
+// daemon as ErrRuntimeUnavailable and a failed image pull as ErrImagePull
+func cliError(err error, exitCode int, stderr string) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, exec.ErrNotFound) || errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%w: %w", ErrRuntimeUnavailable, err)
+	}
+	if exitCode != cliRunFailure {
+		return err
+	}
+
+	message := strings.ToLower(stderr)
+	switch {
+	case strings.Contains(message, "cannot connect to the docker daemon"),
+		strings.Contains(message, "is the docker daemon running"),
+		strings.Contains(message, "unable to connect to podman"),
+		strings.Contains(message, "connection refused"):
+		return fmt.Errorf("%w: %w", ErrRuntimeUnavailable, err)
+	case strings.Contains(message, "pull access denied"),
+		strings.Contains(message, "manifest unknown"),
+		strings.Contains(message, "reading manifest"),
+		strings.Contains(message, "initializing source"),
+		strings.Contains(message, "unable to pull"),
+		// docker prints this before every pull; the download status means the pull succeeded
+		strings.Contains(message, "unable to find image") && !strings.Contains(message, "status: downloaded"):
+		return fmt.Errorf("%w: %w", ErrImagePull, err)
+	}
+	return err
+}
+
+// killContainer stops and removes a container that outlived its CLI process.
+// --rm only cleans up when the CLI is still attached, so the container is removed explicitly.
+func (r *CLIRuntime) killContainer(name string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	exec.CommandContext(ctx, r.binary, "kill", name).Run()
+	exec.CommandContext(ctx, r.binary, "rm", "-f", name).Run()
+}
+
+// containerFlags translates container settings into docker run flags
+func containerFlags(opts *config.ContainerConfig) []string {
+	var flags []string
+	if opts.Memory != "" {
+		flags = append(flags, "--memory", opts.Memory)
+	}
+	if opts.CPUs != "" {
+		flags = append(flags, "--cpus", opts.CPUs)
+	}
+	if opts.PidsLimit > 0 {
+		flags = append(flags, "--pids-limit", strconv.Itoa(opts.PidsLimit))
+	}
+	if opts.Network != "" {
+		flags = append(flags, "--network", opts.Network)
+	}
+	if opts.User != "" {
+		flags = append(flags, "--user", opts.User)
+	}
+	if opts.ReadOnly {
+		flags = append(flags, "--read-only")
+	}
+	for _, capability := range opts.CapDrop {
+		flags = append(flags, "--cap-drop", capability)
+	}
+	for _, capability := range opts.CapAdd {
+		flags = append(flags, "--cap-add", capability)
+	}
+	if opts.Workdir != "" {
+		flags = append(flags, "--workdir", opts.Workdir)
+	}
+	if opts.Entrypoint != "" {
+		flags = append(flags, "--entrypoint", opts.Entrypoint)
+	}
+	for _, mount := range opts.Mounts {
+		volume := fmt.Sprintf("%s:%s", mount.Source, mount.Target)
+		if mount.ReadOnly {
+			volume += ":ro"
+		}
+		flags = append(flags, "-v", volume)
+	}
+	for _, path := range opts.Tmpfs {
+		flags = append(flags, "--tmpfs", path)
+	}
+	return flags
+}
@@ -0,0 +1,462 @@
+package executor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultEngineHost is the Docker Engine API socket used when neither the config nor DOCKER_HOST set one
+const defaultEngineHost = "unix:///var/run/docker.sock"
+
+// engineCleanupTimeout bounds the stop and remove calls made after a run
+const engineCleanupTimeout = 10 * time.Second
+
+// EngineRuntime runs containers through the Docker Engine API. Podman's
+// Docker-compatible socket works as well.
+type EngineRuntime struct {
+	client  *http.Client
+	baseURL string
+}
+
+// NewEngineRuntime creates a runtime for the Engine API at host
+// (unix:///path/to/socket, tcp://host:port or http://host:port)
+func NewEngineRuntime(host string) (*EngineRuntime, error) {
+	if host == "" {
+		host = os.Getenv("DOCKER_HOST")
+	}
+	if host == "" {
+		host = defaultEngineHost
+	}
+
+	u, err := url.Parse(host)
+	if err != nil {
+		return nil, fmt.Errorf("invalid engine host '%s': %w", host, err)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	baseURL := ""
+	switch u.Scheme {
+	case "unix":
+		socket := u.Path
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", socket)
+		}
+		baseURL = "http://engine"
+	case "tcp", "http":
+		baseURL = "http://" + u.Host
+	default:
+		return nil, fmt.Errorf("unsupported engine host '%s'", host)
+	}
+
+	return &EngineRuntime{
+		client:  &http.Client{Transport: transport},
+		baseURL: baseURL,
+	}, nil
+}
+
+// engineCreateRequest is the body of POST /containers/create
+type engineCreateRequest struct {
+	Image      string
+	Cmd        []string `json:",omitempty"`
+	Entrypoint []string `json:",omitempty"`
+	Env        []string `json:",omitempty"`
+	User       string   `json:",omitempty"`
+	WorkingDir string   `json:",omitempty"`
+	HostConfig engineHostConfig
+}
+
+// engineHostConfig holds the resource limits and isolation settings of a container
+type engineHostConfig struct {
+	Memory         int64             `json:",omitempty"`
+	NanoCpus       int64             `json:",omitempty"`
+	PidsLimit      int64             `json:",omitempty"`
+	NetworkMode    string            `json:",omitempty"`
+	ReadonlyRootfs bool              `json:",omitempty"`
+	CapAdd         []string          `json:",omitempty"`
+	CapDrop        []string          `json:",omitempty"`
+	Binds          []string          `json:",omitempty"`
+	Tmpfs          map[string]string `json:",omitempty"`
+}
+
+// Run creates, starts and waits on a container, following its logs, and always removes it
+func (r *EngineRuntime) Run(ctx context.Context, spec *ContainerSpec) (*Result, error) {
+	start := time.Now()
+
+	// Remove by name, which also covers a create that was interrupted after the engine accepted it
+	defer r.remove(spec.Name)
+	id, err := r.create(ctx, spec)
+	if err != nil {
+		return &Result{ExitCode: -1}, err
+	}
+
+	if err := r.call(ctx, http.MethodPost, "/containers/"+id+"/start", nil, nil); err != nil {
+		return &Result{ExitCode: -1}, fmt.Errorf("failed to start container: %w", err)
+	}
+
+	// Follow the logs until the container exits. The request is not tied to ctx so
+	// output written while the container shuts down is still captured.
+	output := newOutputCapture(ctx)
+	logsDone := make(chan error, 1)
+	logsCtx, cancelLogs := context.WithCancel(context.Background())
+	defer cancelLogs()
+	go func() {
+		logsDone <- r.followLogs(logsCtx, id, output)
+	}()
+
+	waitCtx, cancelWait := context.WithCancel(context.Background())
+	defer cancelWait()
+	waitDone := make(chan waitResult, 1)
+	go func() {
+		waitDone <- r.wait(waitCtx, id)
+	}()
+
+	var status waitResult
+	select {
+	case status = <-waitDone:
+	case <-ctx.Done():
+		r.stop(id, spec.KillGrace)
+		select {
+		case status = <-waitDone:
+		case <-time.After(spec.KillGrace + engineCleanupTimeout):
+			cancelWait()
+			status = waitResult{exitCode: -1}
+		}
+		status.err = ctx.Err()
+	}
+
+	// Logs end when the container exits; don't hang on a stuck stream
+	select {
+	case <-logsDone:
+	case <-time.After(engineCleanupTimeout):
+		cancelLogs()
+		<-logsDone
+	}
+
+	result := output.result()
+	result.ExitCode = status.exitCode
+	result.Duration = time.Since(start)
+
+	err = status.err
+	if err == nil && status.exitCode != 0 {
+		err = fmt.Errorf("exit status %d", status.exitCode)
+	}
+	return result, err
+}
+
+// Start creates and starts a detached container for a warm pool
+func (r *EngineRuntime) Start(ctx context.Context, spec *ContainerSpec) error {
+	id, err := r.create(ctx, spec)
+	if err != nil {
+		return err
+	}
+	if err := r.call(ctx, http.MethodPost, "/containers/"+id+"/start", nil, nil); err != nil {
+		r.remove(id)
+		return fmt.Errorf("failed to start container: %w", err)
+	}
+	return nil
+}
+
+// engineExecRequest is the body of POST /containers/{id}/exec
+type engineExecRequest struct {
+	AttachStdout bool
+	AttachStderr bool
+	Cmd          []string
+	Env          []string `json:",omitempty"`
+	User         string   `json:",omitempty"`
+	WorkingDir   string   `json:",omitempty"`
+}
+
+// Exec runs a command in a running container and waits for it. The engine has no
+// way to signal an exec'd process, so a cancelled exec keeps running until the
+// container is removed.
+func (r *EngineRuntime) Exec(ctx context.Context, container string, spec *ExecSpec) (*Result, error) {
+	start := time.Now()
+
+	body := engineExecRequest{
+		AttachStdout: true,
+		AttachStderr: true,
+		Cmd:          spec.Command,
+		User:         spec.User,
+		WorkingDir:   spec.Workdir,
+	}
+	for k, v := range spec.Env {
+		body.Env = append(body.Env, k+"="+v)
+	}
+
+	var created struct{ Id string }
+	if err := r.call(ctx, http.MethodPost, "/containers/"+url.PathEscape(container)+"/exec", body, &created); err != nil {
+		return &Result{ExitCode: -1}, fmt.Errorf("failed to create exec: %w", err)
+	}
+
+	// Without Detach the engine streams the multiplexed output until the process exits
+	output := newOutputCapture(ctx)
+	resp, err := r.do(ctx, http.MethodPost, "/exec/"+created.Id+"/start", map[string]bool{"Detach": false, "Tty": false})
+	if err == nil {
+		err = demuxLogs(resp.Body, output.stdout, output.stderr)
+		resp.Body.Close()
+	}
+
+	result := output.result()
+	result.ExitCode = -1
+	if ctx.Err() != nil {
+		err = ctx.Err()
+	} else if err != nil {
+		err = fmt.Errorf("failed to run exec: %w", err)
+	} else {
+		var inspect struct{ ExitCode int }
+		if err = r.call(ctx, http.MethodGet, "/exec/"+created.Id+"/json", nil, &inspect); err == nil {
+			result.ExitCode = inspect.ExitCode
+			if inspect.ExitCode != 0 {
+				err = fmt.Errorf("exit status %d", inspect.ExitCode)
+			}
+		}
+	}
+	result.Duration = time.Since(start)
+	return result, err
+}
+
+// Remove force-removes a container
+func (r *EngineRuntime) Remove(container string) {
+	r.remove(container)
+}
+
+// create creates the container, pulling the image first if it is missing
+func (r *EngineRuntime) create(ctx context.Context, spec *ContainerSpec) (string, error) {
+	opts := spec.Options
+	body := engineCreateRequest{
+		Image:      spec.Image,
+		Cmd:        spec.Command,
+		User:       opts.User,
+		WorkingDir: opts.Workdir,
+		HostConfig: engineHostConfig{
+			Memory:         opts.MemoryBytes(),
+			NanoCpus:       opts.NanoCPUs(),
+			PidsLimit:      int64(opts.PidsLimit),
+			NetworkMode:    opts.Network,
+			ReadonlyRootfs: opts.ReadOnly,
+			CapAdd:         opts.CapAdd,
+			CapDrop:        opts.CapDrop,
+		},
+	}
+	if opts.Entrypoint != "" {
+		body.Entrypoint = []string{opts.Entrypoint}
+	}
+	for k, v := range spec.Env {
+		body.Env = append(body.Env, k+"="+v)
+	}
+	for _, mount := range opts.Mounts {
+		bind := mount.Source + ":" + mount.Target
+		if mount.ReadOnly {
+			bind += ":ro"
+		}
+		body.HostConfig.Binds = append(body.HostConfig.Binds, bind)
+	}
+	if len(opts.Tmpfs) > 0 {
+		body.HostConfig.Tmpfs = make(map[string]string, len(opts.Tmpfs))
+		for _, path := range opts.Tmpfs {
+			body.HostConfig.Tmpfs[path] = ""
+		}
+	}
+
+	path := "/containers/create?name=" + url.QueryEscape(spec.Name)
+	var created struct{ Id string }
+	err := r.call(ctx, http.MethodPost, path, body, &created)
+
+	var apiErr *engineError
+	if errors.As(err, &apiErr) && apiErr.status == http.StatusNotFound {
+		if err := r.pull(ctx, spec.Image); err != nil {
+			return "", err
+		}
+		err = r.call(ctx, http.MethodPost, path, body, &created)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to create container: %w", err)
+	}
+	return created.Id, nil
+}
+
+// pull pulls an image, reading the progress stream to completion
+func (r *EngineRuntime) pull(ctx context.Context, image string) error {
+	resp, err := r.do(ctx, http.MethodPost, "/images/create?"+pullQuery(image).Encode(), nil)
+	if err != nil {
+		return fmt.Errorf("%w: %s: %w", ErrImagePull, image, err)
+	}
+	defer resp.Body.Close()
+
+	// Errors during the pull are reported inside the stream
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var message struct{ Error string }
+		if err := decoder.Decode(&message); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("%w: %s: %w", ErrImagePull, image, err)
+		}
+		if message.Error != "" {
+			return fmt.Errorf("%w: %s: %s", ErrImagePull, image, message.Error)
+		}
+	}
+}
+
+// pullQuery returns the query of an image pull. Without a tag the engine pulls every
+// tag of the repository, so untagged references get latest; references pinned to a
+// digest are passed as they are.
+func pullQuery(image string) url.Values {
+	if strings.Contains(image, "@") {
+		return url.Values{"fromImage": {image}}
+	}
+	repo, tag := image, "latest"
+	// A colon before the last slash belongs to a registry port
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		repo, tag = image[:i], image[i+1:]
+	}
+	return url.Values{"fromImage": {repo}, "tag": {tag}}
+}
+
+// followLogs demultiplexes the container's stdout and stderr into output
+func (r *EngineRuntime) followLogs(ctx context.Context, id string, output *outputCapture) error {
+	resp, err := r.do(ctx, http.MethodGet, "/containers/"+id+"/logs?follow=1&stdout=1&stderr=1", nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return demuxLogs(resp.Body, output.stdout, output.stderr)
+}
+
+// demuxLogs splits the Engine API multiplexed stream: each frame has an 8-byte
+// header holding the stream type (1 stdout, 2 stderr) and the payload size
+func demuxLogs(r io.Reader, stdout, stderr io.Writer) error {
+	reader := bufio.NewReader(r)
+	header := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(reader, header); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+
+		dst := stdout
+		if header[0] == 2 {
+			dst = stderr
+		}
+		size := int64(binary.BigEndian.Uint32(header[4:]))
+		if _, err := io.CopyN(dst, reader, size); err != nil {
+			return err
+		}
+	}
+}
+
+// waitResult is the outcome of POST /containers/{id}/wait
+type waitResult struct {
+	exitCode int
+	err      error
+}
+
+// wait blocks until the container stops
+func (r *EngineRuntime) wait(ctx context.Context, id string) waitResult {
+	var status struct {
+		StatusCode int
+		Error      *struct{ Message string }
+	}
+	if err := r.call(ctx, http.MethodPost, "/containers/"+id+"/wait", nil, &status); err != nil {
+		return waitResult{exitCode: -1, err: fmt.Errorf("failed to wait for container: %w", err)}
+	}
+	if status.Error != nil && status.Error.Message != "" {
+		return waitResult{exitCode: status.StatusCode, err: errors.New(status.Error.Message)}
+	}
+	return waitResult{exitCode: status.StatusCode}
+}
+
+// stop sends SIGTERM and lets the engine escalate to SIGKILL after grace
+func (r *EngineRuntime) stop(id string, grace time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), grace+engineCleanupTimeout)
+	defer cancel()
+	seconds := int(grace.Round(time.Second) / time.Second)
+	r.call(ctx, http.MethodPost, "/containers/"+id+"/stop?t="+strconv.Itoa(seconds), nil, nil)
+}
+
+// remove force-removes a container by ID or name, with its anonymous volumes
+func (r *EngineRuntime) remove(container string) {
+	ctx, cancel := context.WithTimeout(context.Background(), engineCleanupTimeout)
+	defer cancel()
+	r.call(ctx, http.MethodDelete, "/containers/"+url.PathEscape(container)+"?force=1&v=1", nil, nil)
+}
+
+// engineError is an error response from the Engine API
+type engineError struct {
+	status  int
+	message string
+}
+
+func (e *engineError) Error() string {
+	return fmt.Sprintf("engine API %d: %s", e.status, e.message)
+}
+
+// call performs a request and decodes the JSON response into out, if set
+func (r *EngineRuntime) call(ctx context.Context, method, path string, body, out interface{}) error {
+	resp, err := r.do(ctx, method, path, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if out == nil {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// do sends a request and turns error status codes into engineError
+func (r *EngineRuntime) do(ctx context.Context, method, path string, body interface{}) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, r.baseURL+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		if ctx.Err() == nil {
+			err = fmt.Errorf("%w: %w", ErrRuntimeUnavailable, err)
+		}
+		return nil, err
+	}
+	// Error responses carry a JSON message; 304 (already stopped) is not an error
+	if resp.StatusCode >= 400 {
+		defer resp.Body.Close()
+		var apiErr struct{ Message string }
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		if json.Unmarshal(data, &apiErr) != nil || apiErr.Message == "" {
+			apiErr.Message = strings.TrimSpace(string(data))
+		}
+		return nil, &engineError{status: resp.StatusCode, message: apiErr.Message}
+	}
+	return resp, nil
+}
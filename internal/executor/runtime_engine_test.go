@@ -0,0 +1,60 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestEngineRuntimePull(t *testing.T) {
+	var query string
+	engine := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/images/create" {
+			http.NotFound(w, r)
+			return
+		}
+		query = r.URL.RawQuery
+		if r.URL.Query().Get("fromImage") == "missing" {
+			fmt.Fprintln(w, `{"status":"Pulling"}`)
+			fmt.Fprintln(w, `{"error":"manifest unknown"}`)
+			return
+		}
+		fmt.Fprintln(w, `{"status":"Pulling"}`)
+		fmt.Fprintln(w, `{"status":"Downloaded newer image"}`)
+	}))
+	defer engine.Close()
+
+	runtime, err := NewEngineRuntime(engine.URL)
+	if err != nil {
+		t.Fatalf("Failed to create runtime: %v", err)
+	}
+
+	tests := []struct {
+		image string
+		want  string
+	}{
+		{"alpine", "fromImage=alpine&tag=latest"},
+		{"alpine:3.20", "fromImage=alpine&tag=3.20"},
+		{"ghcr.io/org/app", "fromImage=ghcr.io%2Forg%2Fapp&tag=latest"},
+		{"localhost:5000/app", "fromImage=localhost%3A5000%2Fapp&tag=latest"},
+		{"localhost:5000/app:v1", "fromImage=localhost%3A5000%2Fapp&tag=v1"},
+		{"alpine@sha256:abc", "fromImage=alpine%40sha256%3Aabc"},
+		{"alpine:3.20@sha256:abc", "fromImage=alpine%3A3.20%40sha256%3Aabc"},
+	}
+	for _, tt := range tests {
+		if err := runtime.pull(context.Background(), tt.image); err != nil {
+			t.Errorf("%s: pull failed: %v", tt.image, err)
+		}
+		if query != tt.want {
+			t.Errorf("%s: expected query %s, got %s", tt.image, tt.want, query)
+		}
+	}
+
+	// Errors in the progress stream fail the pull
+	if err := runtime.pull(context.Background(), "missing"); !errors.Is(err, ErrImagePull) {
+		t.Errorf("Expected image pull error, got %v", err)
+	}
+}
@@ -0,0 +1,112 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/gleicon/mcpfier/internal/config"
+	"github.com/gleicon/mcpfier/internal/sandbox"
+)
+
+// SandboxExecutor executes local commands in a Linux sandbox
+type SandboxExecutor struct{}
+
+// NewSandboxExecutor creates a new sandbox executor
+func NewSandboxExecutor() *SandboxExecutor {
+	return &SandboxExecutor{}
+}
+
+// Execute runs a command in the sandbox. The command may read its own executable,
+// system directories and the configured read paths, and write the configured
+// write paths and its output directory.
+func (e *SandboxExecutor) Execute(ctx context.Context, cmd *config.Command, params map[string]interface{}) (*Result, error) {
+	outputDir, err := newOutputDir(cmd)
+	if err != nil {
+		return nil, err
+	}
+	if outputDir != "" {
+		defer os.RemoveAll(outputDir)
+	}
+
+	data := templateData(params)
+	if outputDir != "" {
+		data["output_dir"] = outputDir
+	}
+	args, err := renderArgs(cmd.Args, data)
+	if err != nil {
+		return nil, err
+	}
+	env, err := renderEnv(cmd.Env, data)
+	if err != nil {
+		return nil, err
+	}
+
+	// Resolve the executable here; inside the sandbox PATH directories may not be readable
+	path, err := exec.LookPath(cmd.Script)
+	if err != nil {
+		return nil, err
+	}
+	if path, err = filepath.Abs(path); err != nil {
+		return nil, err
+	}
+
+	policy := sandboxPolicy(cmd.Sandbox)
+	policy.Read = append(policy.Read, path)
+
+	envList := sandboxEnv(cmd.Sandbox, env)
+	if outputDir != "" {
+		policy.Write = append(policy.Write, outputDir)
+		envList = append(envList, fmt.Sprintf("%s=%s", outputDirEnv, outputDir))
+	}
+
+	execCmd, err := sandbox.Command(policy, path, args, envList)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := runProcess(ctx, execCmd, cmd.GetKillGrace(), nil)
+	var exitErr *exec.ExitError
+	if err != nil && ctx.Err() == nil && !errors.As(err, &exitErr) {
+		return result, fmt.Errorf("failed to start sandbox: %w", err)
+	}
+	if err == nil && outputDir != "" {
+		result.Artifacts, err = collectArtifacts(outputDir, cmd.Artifacts)
+	}
+	return result, err
+}
+
+// sandboxBaseEnv are the server environment variables every sandboxed command gets
+var sandboxBaseEnv = []string{"PATH", "HOME", "LANG", "TMPDIR"}
+
+// sandboxEnv returns a sandboxed command's environment: the base variables and the
+// ones listed in inherit_env from the server's environment, then the command's env.
+// Nothing else is inherited, so secrets in the server's environment stay outside.
+func sandboxEnv(cfg *config.SandboxConfig, env map[string]string) []string {
+	envList := []string{}
+	for _, name := range append(append([]string(nil), sandboxBaseEnv...), cfg.InheritEnv...) {
+		if value, ok := os.LookupEnv(name); ok {
+			envList = append(envList, fmt.Sprintf("%s=%s", name, value))
+		}
+	}
+	for k, v := range env {
+		envList = append(envList, fmt.Sprintf("%s=%s", k, v))
+	}
+	return envList
+}
+
+// sandboxPolicy converts a command's sandbox settings into a sandbox policy
+func sandboxPolicy(cfg *config.SandboxConfig) sandbox.Policy {
+	return sandbox.Policy{
+		Read:      append([]string(nil), cfg.Read...),
+		Write:     append([]string(nil), cfg.Write...),
+		Network:   cfg.Network,
+		CPU:       uint64(cfg.CPU),
+		Memory:    uint64(cfg.MemoryBytes()),
+		OpenFiles: uint64(cfg.OpenFiles),
+		Processes: uint64(cfg.Processes),
+	}
+}
@@ -0,0 +1,167 @@
+package executor
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/gleicon/mcpfier/internal/config"
+)
+
+// nobody is the uid and gid the non-root sandbox test runs as
+const nobody = 65534
+
+// requireUserNamespaces skips the test when the current user cannot create user
+// namespaces, by running the test binary in one without any tests
+func requireUserNamespaces(t *testing.T) {
+	t.Helper()
+	self, err := os.Executable()
+	if err != nil {
+		t.Fatalf("Failed to locate test binary: %v", err)
+	}
+	probe := exec.Command(self, "-test.run=^$")
+	probe.SysProcAttr = &syscall.SysProcAttr{
+		Cloneflags:  syscall.CLONE_NEWUSER,
+		UidMappings: []syscall.SysProcIDMap{{ContainerID: os.Getuid(), HostID: os.Getuid(), Size: 1}},
+		GidMappings: []syscall.SysProcIDMap{{ContainerID: os.Getgid(), HostID: os.Getgid(), Size: 1}},
+	}
+	if output, err := probe.CombinedOutput(); err != nil {
+		t.Skipf("User namespaces are not available: %v %s", err, output)
+	}
+}
+
+func TestSandboxExecutor(t *testing.T) {
+	requireUserNamespaces(t)
+
+	dir := t.TempDir()
+	allowed := filepath.Join(dir, "allowed")
+	writable := filepath.Join(dir, "writable")
+	os.Mkdir(allowed, 0755)
+	os.Mkdir(writable, 0755)
+	os.WriteFile(filepath.Join(allowed, "data.txt"), []byte("allowed"), 0644)
+	os.WriteFile(filepath.Join(dir, "secret.txt"), []byte("secret"), 0644)
+
+	t.Setenv("MCPFIER_TEST_SECRET", "secret")
+	t.Setenv("MCPFIER_TEST_SHARED", "shared")
+
+	executor := NewSandboxExecutor()
+	run := func(script string) (*Result, error) {
+		cmd := &config.Command{
+			Name:   "sandboxed",
+			Script: "sh",
+			Args:   []string{"-c", script},
+			Env:    map[string]string{"GREETING": "hello"},
+			Sandbox: &config.SandboxConfig{
+				Read:       []string{allowed},
+				Write:      []string{writable},
+				OpenFiles:  64,
+				InheritEnv: []string{"MCPFIER_TEST_SHARED"},
+			},
+		}
+		return executor.Execute(context.Background(), cmd, nil)
+	}
+
+	result, err := run("cat " + filepath.Join(allowed, "data.txt"))
+	if err != nil || result.Stdout != "allowed" {
+		t.Fatalf("Expected allowed path to be readable as uid %d, got %q, %v (stderr %q)", os.Getuid(), result.Stdout, err, result.Stderr)
+	}
+
+	// Only the base variables, inherit_env and the command's env reach the command
+	result, err = run(`echo "$GREETING ${MCPFIER_TEST_SHARED:-unset} ${MCPFIER_TEST_SECRET:-unset}" && test "$PATH" = "` + os.Getenv("PATH") + `"`)
+	if err != nil || result.Stdout != "hello shared unset\n" {
+		t.Errorf("Expected only the allowed environment, got %q, %v (stderr %q)", result.Stdout, err, result.Stderr)
+	}
+
+	// The command keeps the caller's uid and none of the capabilities used for setup
+	result, err = run("id -u && grep CapEff /proc/self/status")
+	if err != nil {
+		t.Fatalf("Expected uid and capabilities, got %v (stderr %q)", err, result.Stderr)
+	}
+	if fields := strings.Fields(result.Stdout); len(fields) != 3 || fields[0] != strconv.Itoa(os.Getuid()) || strings.Trim(fields[2], "0") != "" {
+		t.Errorf("Expected uid %d without effective capabilities, got %q", os.Getuid(), result.Stdout)
+	}
+
+	denied := map[string]string{
+		"reading outside the allowlist":  "cat " + filepath.Join(dir, "secret.txt"),
+		"writing to a read-only path":    "echo x > " + filepath.Join(allowed, "new.txt"),
+		"creating namespaces (seccomp)":  "unshare -U true",
+		"reaching the network namespace": "cat /proc/net/dev | grep -v ' lo:' | grep -q ':'",
+	}
+	for name, script := range denied {
+		if name == "creating namespaces (seccomp)" {
+			if _, err := exec.LookPath("unshare"); err != nil {
+				continue
+			}
+		}
+		if _, err := run(script); err == nil {
+			t.Errorf("Expected sandbox to prevent %s", name)
+		}
+	}
+
+	result, err = run("echo ok > " + filepath.Join(writable, "out.txt") + " && ulimit -n && echo $$")
+	if err != nil {
+		t.Fatalf("Expected write path to be writable, got %v (stderr %q)", err, result.Stderr)
+	}
+	lines := strings.Fields(result.Stdout)
+	if len(lines) != 2 || lines[0] != "64" {
+		t.Fatalf("Expected open file limit and PID, got %q", result.Stdout)
+	}
+	if pid, _ := strconv.Atoi(lines[1]); pid > 100 {
+		t.Errorf("Expected a small PID inside the PID namespace, got %d", pid)
+	}
+}
+
+// TestSandboxExecutorNonRoot runs TestSandboxExecutor as an unprivileged user. When the
+// tests already run as one, TestSandboxExecutor covers it.
+func TestSandboxExecutorNonRoot(t *testing.T) {
+	if os.Getuid() != 0 {
+		t.Skip("TestSandboxExecutor already runs as a non-root user")
+	}
+
+	// Copy the test binary and give it a temporary directory the user can reach
+	dir := t.TempDir()
+	for path := dir; strings.HasPrefix(path, os.TempDir()+string(filepath.Separator)); path = filepath.Dir(path) {
+		os.Chmod(path, 0755)
+	}
+	tmp := filepath.Join(dir, "tmp")
+	os.Mkdir(tmp, 0777)
+	os.Chmod(tmp, 0777)
+
+	self, err := os.Executable()
+	if err != nil {
+		t.Fatalf("Failed to locate test binary: %v", err)
+	}
+	binary := filepath.Join(dir, "executor.test")
+	src, err := os.Open(self)
+	if err != nil {
+		t.Fatalf("Failed to open test binary: %v", err)
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(binary, os.O_CREATE|os.O_WRONLY, 0755)
+	if err != nil {
+		t.Fatalf("Failed to copy test binary: %v", err)
+	}
+	_, err = io.Copy(dst, src)
+	dst.Close()
+	if err != nil {
+		t.Fatalf("Failed to copy test binary: %v", err)
+	}
+
+	cmd := exec.Command(binary, "-test.run=^TestSandboxExecutor$", "-test.v")
+	cmd.Dir = tmp
+	cmd.Env = append(os.Environ(), "TMPDIR="+tmp, "HOME="+tmp)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Credential: &syscall.Credential{Uid: nobody, Gid: nobody}}
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("TestSandboxExecutor failed as uid %d: %v\n%s", nobody, err, output)
+	}
+	if strings.Contains(string(output), "--- SKIP") {
+		t.Skipf("TestSandboxExecutor was skipped as uid %d:\n%s", nobody, output)
+	}
+}
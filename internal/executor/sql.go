@@ -0,0 +1,329 @@
+package executor
+
+import (
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gleicon/mcpfier/internal/config"
+	_ "modernc.org/sqlite"
+)
+
+// SQLExecutor runs parameterized queries against database/sql data sources.
+// Connection pools are shared by commands using the same data source.
+type SQLExecutor struct {
+	mu  sync.Mutex
+	dbs map[string]*sql.DB // By driver, DSN and read-only mode
+}
+
+// NewSQLExecutor creates a new SQL executor
+func NewSQLExecutor() *SQLExecutor {
+	return &SQLExecutor{dbs: make(map[string]*sql.DB)}
+}
+
+// Execute runs the command's query with the tool parameters bound to its
+// placeholders and formats the rows as a markdown table, CSV or JSON
+func (e *SQLExecutor) Execute(ctx context.Context, cmd *config.Command, params map[string]interface{}) (*Result, error) {
+	query := cmd.SQL
+
+	args, err := sqlArgs(cmd, params)
+	if err != nil {
+		return nil, err
+	}
+	db, err := e.db(query)
+	if err != nil {
+		return nil, err
+	}
+
+	queryCtx := ctx
+	if query.TimeoutDuration > 0 {
+		var cancel context.CancelFunc
+		queryCtx, cancel = context.WithTimeout(ctx, query.TimeoutDuration)
+		defer cancel()
+	}
+
+	start := time.Now()
+	result, err := runQuery(queryCtx, db, query, args)
+	if err != nil {
+		if ctx.Err() == nil && errors.Is(queryCtx.Err(), context.DeadlineExceeded) {
+			return nil, fmt.Errorf("%w: query exceeded %s", ErrTimeout, query.TimeoutDuration)
+		}
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	result.Duration = time.Since(start)
+	return result, nil
+}
+
+// Close closes all connection pools
+func (e *SQLExecutor) Close() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for key, db := range e.dbs {
+		db.Close()
+		delete(e.dbs, key)
+	}
+}
+
+// db returns the connection pool for a data source, opening it on first use
+func (e *SQLExecutor) db(query *config.SQLConfig) (*sql.DB, error) {
+	dsn := query.DSN
+	// SQLite ignores read-only transactions, so the connection itself is made read-only
+	if query.ReadOnly && query.Driver == config.DefaultSQLDriver {
+		separator := "?"
+		if strings.Contains(dsn, "?") {
+			separator = "&"
+		}
+		dsn += separator + "_pragma=query_only(1)"
+	}
+	key := query.Driver + "\x00" + dsn
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if db, ok := e.dbs[key]; ok {
+		return db, nil
+	}
+	db, err := sql.Open(query.Driver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	e.dbs[key] = db
+	return db, nil
+}
+
+// sqlArgs binds tool parameters to the query: in order for positional
+// placeholders when bind is set, otherwise by name
+func sqlArgs(cmd *config.Command, params map[string]interface{}) ([]interface{}, error) {
+	var args []interface{}
+	if len(cmd.SQL.Bind) > 0 {
+		for _, name := range cmd.SQL.Bind {
+			if err := checkSQLArg(name, params[name]); err != nil {
+				return nil, err
+			}
+			args = append(args, params[name])
+		}
+		return args, nil
+	}
+
+	// Unset optional parameters bind as NULL
+	for _, param := range cmd.Parameters {
+		if err := checkSQLArg(param.Name, params[param.Name]); err != nil {
+			return nil, err
+		}
+		args = append(args, sql.Named(param.Name, params[param.Name]))
+	}
+	return args, nil
+}
+
+// checkSQLArg rejects values database/sql cannot bind
+func checkSQLArg(name string, value interface{}) error {
+	switch value.(type) {
+	case []interface{}, map[string]interface{}:
+		return fmt.Errorf("parameter '%s': %T values cannot be bound to a query", name, value)
+	}
+	return nil
+}
+
+// runQuery runs the query, reading at most max_rows rows
+func runQuery(ctx context.Context, db *sql.DB, query *config.SQLConfig, args []interface{}) (*Result, error) {
+	var rows *sql.Rows
+	var err error
+	if query.ReadOnly {
+		tx, err := db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+		if err != nil {
+			return nil, err
+		}
+		defer tx.Rollback()
+		rows, err = tx.QueryContext(ctx, query.Query, args...)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		rows, err = db.QueryContext(ctx, query.Query, args...)
+		if err != nil {
+			return nil, err
+		}
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	var values [][]interface{}
+	truncated := false
+	for rows.Next() {
+		if len(values) == query.MaxRows {
+			truncated = true
+			break
+		}
+		row := make([]interface{}, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for i := range row {
+			pointers[i] = &row[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, err
+		}
+		values = append(values, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	result := &Result{}
+	switch query.Format {
+	case config.SQLFormatCSV:
+		result.Stdout = formatCSV(columns, values)
+	case config.SQLFormatJSON:
+		result.Stdout, err = formatJSON(columns, values)
+	default:
+		result.Stdout = formatTable(columns, values)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if truncated {
+		result.Stderr = fmt.Sprintf("Result truncated to %d rows (max_rows)\n", query.MaxRows)
+	}
+	return result, nil
+}
+
+// formatTable renders rows as a markdown table
+func formatTable(columns []string, rows [][]interface{}) string {
+	if len(columns) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	cells := make([]string, len(columns))
+	writeRow := func(cells []string) {
+		b.WriteString("| ")
+		b.WriteString(strings.Join(cells, " | "))
+		b.WriteString(" |\n")
+	}
+
+	for i, column := range columns {
+		cells[i] = tableCell(column)
+	}
+	writeRow(cells)
+	for i := range cells {
+		cells[i] = "---"
+	}
+	writeRow(cells)
+	for _, row := range rows {
+		for i, value := range row {
+			cells[i] = tableCell(sqlText(value))
+		}
+		writeRow(cells)
+	}
+	return b.String()
+}
+
+// tableCell escapes a value for a markdown table cell
+func tableCell(s string) string {
+	s = strings.ReplaceAll(s, "|", "\\|")
+	s = strings.ReplaceAll(s, "\r\n", "<br>")
+	return strings.ReplaceAll(s, "\n", "<br>")
+}
+
+// formatCSV renders rows as CSV with a header line. As in PostgreSQL's CSV
+// format, NULL is an empty field and empty strings are quoted.
+func formatCSV(columns []string, rows [][]interface{}) string {
+	var b strings.Builder
+	writeRecord := func(fields []string) {
+		b.WriteString(strings.Join(fields, ","))
+		b.WriteString("\n")
+	}
+
+	record := make([]string, len(columns))
+	for i, column := range columns {
+		record[i] = csvField(column)
+	}
+	writeRecord(record)
+	for _, row := range rows {
+		for i, value := range row {
+			record[i] = ""
+			if value != nil {
+				record[i] = csvField(sqlText(value))
+			}
+		}
+		writeRecord(record)
+	}
+	return b.String()
+}
+
+// csvField quotes a value when it is empty or contains separators, quotes or line breaks
+func csvField(s string) string {
+	if s != "" && !strings.ContainsAny(s, ",\"\r\n") && s[0] != ' ' && s[0] != '\t' {
+		return s
+	}
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
+
+// formatJSON renders rows as an array of objects, keeping the column order
+func formatJSON(columns []string, rows [][]interface{}) (string, error) {
+	var b strings.Builder
+	b.WriteString("[")
+	for r, row := range rows {
+		if r > 0 {
+			b.WriteString(",")
+		}
+		b.WriteString("{")
+		for i, value := range row {
+			if i > 0 {
+				b.WriteString(",")
+			}
+			key, _ := json.Marshal(columns[i])
+			encoded, err := json.Marshal(sqlJSONValue(value))
+			if err != nil {
+				return "", fmt.Errorf("column %s: %w", columns[i], err)
+			}
+			b.Write(key)
+			b.WriteString(":")
+			b.Write(encoded)
+		}
+		b.WriteString("}")
+	}
+	b.WriteString("]\n")
+	return b.String(), nil
+}
+
+// sqlText formats a column value for text output; NULL is empty
+func sqlText(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case []byte:
+		return sqlBytes(v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	}
+	return fmt.Sprint(value)
+}
+
+// sqlJSONValue converts a column value to its JSON representation
+func sqlJSONValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case []byte:
+		return sqlBytes(v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	}
+	return value
+}
+
+// sqlBytes returns text columns as strings and binary ones as x'..' hex literals
+func sqlBytes(b []byte) string {
+	if utf8.Valid(b) {
+		return string(b)
+	}
+	return "x'" + hex.EncodeToString(b) + "'"
+}
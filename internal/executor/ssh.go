@@ -0,0 +1,272 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gleicon/mcpfier/internal/config"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// SSHExecutor runs commands on remote hosts. Each call opens its own connection,
+// tunneled through the jump host when one is configured.
+type SSHExecutor struct{}
+
+// NewSSHExecutor creates a new SSH executor
+func NewSSHExecutor() *SSHExecutor {
+	return &SSHExecutor{}
+}
+
+// Execute runs the command's script with its arguments and environment on the
+// remote host. When ctx is done the remote process gets SIGTERM, and the
+// connection is closed once the kill grace period has elapsed.
+func (e *SSHExecutor) Execute(ctx context.Context, cmd *config.Command, params map[string]interface{}) (*Result, error) {
+	data := templateData(params)
+	args, err := renderArgs(cmd.Args, data)
+	if err != nil {
+		return nil, err
+	}
+	env, err := renderEnv(cmd.Env, data)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := dialSSH(ctx, cmd.SSH)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	session, err := client.NewSession()
+	if err != nil {
+		return nil, fmt.Errorf("ssh: failed to open session: %w", err)
+	}
+	defer session.Close()
+
+	output := newOutputCapture(ctx)
+	session.Stdout = output.stdout
+	session.Stderr = output.stderr
+
+	start := time.Now()
+	if err := session.Start(remoteCommand(cmd.Script, args, env)); err != nil {
+		return &Result{ExitCode: -1}, fmt.Errorf("ssh: failed to start command: %w", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- session.Wait()
+	}()
+
+	var waitErr error
+	select {
+	case waitErr = <-done:
+	case <-ctx.Done():
+		session.Signal(ssh.SIGTERM)
+		select {
+		case waitErr = <-done:
+		case <-time.After(cmd.GetKillGrace()):
+			// Not every server delivers signals; closing the connection hangs up the command
+			session.Signal(ssh.SIGKILL)
+			client.Close()
+			waitErr = <-done
+		}
+	}
+
+	result := output.result()
+	result.Duration = time.Since(start)
+
+	var exitErr *ssh.ExitError
+	switch {
+	case errors.As(waitErr, &exitErr):
+		result.ExitCode = exitErr.ExitStatus()
+		if exitErr.Signal() != "" {
+			result.ExitCode = -1
+			result.Signal = "SIG" + exitErr.Signal()
+		}
+		err = fmt.Errorf("exit status %d", result.ExitCode)
+		if result.Signal != "" {
+			err = fmt.Errorf("signal: %s", result.Signal)
+		}
+	case waitErr != nil:
+		result.ExitCode = -1
+		err = fmt.Errorf("ssh: %w", waitErr)
+	}
+
+	if ctx.Err() != nil {
+		result.ExitCode = -1
+		return result, ctx.Err()
+	}
+	return result, err
+}
+
+// dialSSH connects and authenticates to a host, through its jump host if set
+func dialSSH(ctx context.Context, settings *config.SSHConfig) (*ssh.Client, error) {
+	addr := net.JoinHostPort(settings.Host, strconv.Itoa(settings.Port))
+
+	clientConfig, closeAgent, err := sshClientConfig(settings)
+	if err != nil {
+		return nil, fmt.Errorf("ssh %s: %w", addr, err)
+	}
+	defer closeAgent()
+
+	dialCtx, cancel := context.WithTimeout(ctx, settings.ConnectTimeoutDuration)
+	defer cancel()
+
+	var jump *ssh.Client
+	var conn net.Conn
+	if settings.Jump != nil {
+		jump, err = dialSSH(dialCtx, settings.Jump)
+		if err != nil {
+			return nil, fmt.Errorf("jump host: %w", err)
+		}
+		conn, err = jump.DialContext(dialCtx, "tcp", addr)
+	} else {
+		var dialer net.Dialer
+		conn, err = dialer.DialContext(dialCtx, "tcp", addr)
+	}
+	if err != nil {
+		if jump != nil {
+			jump.Close()
+		}
+		return nil, fmt.Errorf("ssh: failed to connect to %s: %w", addr, err)
+	}
+
+	// Abort the handshake if the deadline expires or the call is cancelled
+	stop := context.AfterFunc(dialCtx, func() {
+		conn.Close()
+	})
+	c, chans, reqs, err := ssh.NewClientConn(conn, addr, clientConfig)
+	if !stop() || err != nil {
+		conn.Close()
+		if jump != nil {
+			jump.Close()
+		}
+		if err == nil {
+			err = dialCtx.Err()
+		}
+		return nil, fmt.Errorf("ssh: failed to connect to %s: %w", addr, err)
+	}
+
+	client := ssh.NewClient(c, chans, reqs)
+	if jump != nil {
+		go func() {
+			client.Wait()
+			jump.Close()
+		}()
+	}
+	return client, nil
+}
+
+// sshClientConfig builds the authentication and host key settings for a host.
+// The returned function closes the agent connection once the handshake is done.
+func sshClientConfig(settings *config.SSHConfig) (*ssh.ClientConfig, func(), error) {
+	clientConfig := &ssh.ClientConfig{User: settings.User}
+	closeAgent := func() {}
+
+	var signers []ssh.Signer
+	if settings.KeyFile != "" {
+		key, err := os.ReadFile(settings.KeyFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read key file: %w", err)
+		}
+		var signer ssh.Signer
+		if settings.Passphrase != "" {
+			signer, err = ssh.ParsePrivateKeyWithPassphrase(key, []byte(settings.Passphrase))
+		} else {
+			signer, err = ssh.ParsePrivateKey(key)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to parse key file: %w", err)
+		}
+		signers = append(signers, signer)
+	}
+	if len(signers) > 0 {
+		clientConfig.Auth = append(clientConfig.Auth, ssh.PublicKeys(signers...))
+	}
+	if settings.Agent {
+		socket := os.Getenv("SSH_AUTH_SOCK")
+		if socket == "" {
+			return nil, nil, fmt.Errorf("agent authentication requires SSH_AUTH_SOCK")
+		}
+		conn, err := net.Dial("unix", socket)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to connect to ssh agent: %w", err)
+		}
+		closeAgent = func() { conn.Close() }
+		clientConfig.Auth = append(clientConfig.Auth, ssh.PublicKeysCallback(agent.NewClient(conn).Signers))
+	}
+
+	if settings.HostKey != "" {
+		key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(settings.HostKey))
+		if err != nil {
+			closeAgent()
+			return nil, nil, fmt.Errorf("invalid host_key: %w", err)
+		}
+		clientConfig.HostKeyCallback = ssh.FixedHostKey(key)
+		clientConfig.HostKeyAlgorithms = hostKeyAlgorithms(key)
+		return clientConfig, closeAgent, nil
+	}
+
+	knownHosts := settings.KnownHosts
+	if knownHosts == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			closeAgent()
+			return nil, nil, fmt.Errorf("cannot locate known_hosts: %w", err)
+		}
+		knownHosts = filepath.Join(home, ".ssh", "known_hosts")
+	}
+	callback, err := knownhosts.New(knownHosts)
+	if err != nil {
+		closeAgent()
+		return nil, nil, fmt.Errorf("failed to load known_hosts: %w", err)
+	}
+	clientConfig.HostKeyCallback = callback
+	return clientConfig, closeAgent, nil
+}
+
+// hostKeyAlgorithms makes the server present the pinned key rather than another one it holds
+func hostKeyAlgorithms(key ssh.PublicKey) []string {
+	if key.Type() == ssh.KeyAlgoRSA {
+		return []string{ssh.KeyAlgoRSASHA512, ssh.KeyAlgoRSASHA256, ssh.KeyAlgoRSA}
+	}
+	return []string{key.Type()}
+}
+
+// remoteCommand quotes the script, arguments and environment for the remote shell,
+// so arguments reach the command unchanged as they would locally
+func remoteCommand(script string, args []string, env map[string]string) string {
+	var parts []string
+	if len(env) > 0 {
+		// Servers usually refuse setenv requests, so env(1) sets the variables
+		keys := make([]string, 0, len(env))
+		for k := range env {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		parts = append(parts, "env")
+		for _, k := range keys {
+			parts = append(parts, shellQuote(k+"="+env[k]))
+		}
+	}
+	parts = append(parts, shellQuote(script))
+	for _, arg := range args {
+		parts = append(parts, shellQuote(arg))
+	}
+	return strings.Join(parts, " ")
+}
+
+// shellQuote quotes s as a single POSIX shell word
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,268 @@
+//go:build unix
+
+package executor
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/gleicon/mcpfier/internal/config"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+	"golang.org/x/sys/unix"
+)
+
+// startSSHServer runs an SSH server that executes commands with sh -c, like sshd,
+// accepts one client key and forwards direct-tcpip channels for jump host use
+func startSSHServer(t *testing.T, hostKey ssh.Signer, clientKey ssh.PublicKey) string {
+	serverConfig := &ssh.ServerConfig{
+		PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if conn.User() == "ops" && bytes.Equal(key.Marshal(), clientKey.Marshal()) {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("unknown key for %s", conn.User())
+		},
+	}
+	serverConfig.AddHostKey(hostKey)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				_, chans, reqs, err := ssh.NewServerConn(conn, serverConfig)
+				if err != nil {
+					conn.Close()
+					return
+				}
+				go ssh.DiscardRequests(reqs)
+				for newChannel := range chans {
+					switch newChannel.ChannelType() {
+					case "session":
+						go serveSSHSession(newChannel)
+					case "direct-tcpip":
+						go serveDirectTCPIP(newChannel)
+					default:
+						newChannel.Reject(ssh.UnknownChannelType, "unsupported")
+					}
+				}
+			}()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func serveSSHSession(newChannel ssh.NewChannel) {
+	channel, requests, err := newChannel.Accept()
+	if err != nil {
+		return
+	}
+	defer channel.Close()
+
+	var process *exec.Cmd
+	exited := make(chan struct{})
+	for req := range requests {
+		switch req.Type {
+		case "exec":
+			var payload struct{ Command string }
+			ssh.Unmarshal(req.Payload, &payload)
+			process = exec.Command("sh", "-c", payload.Command)
+			process.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+			process.Stdout = channel
+			process.Stderr = channel.Stderr()
+			if err := process.Start(); err != nil {
+				req.Reply(false, nil)
+				return
+			}
+			req.Reply(true, nil)
+			go func() {
+				defer close(exited)
+				process.Wait()
+				status := process.ProcessState.Sys().(syscall.WaitStatus)
+				if status.Signaled() {
+					name := strings.TrimPrefix(unix.SignalName(status.Signal()), "SIG")
+					channel.SendRequest("exit-signal", false, ssh.Marshal(struct {
+						Signal     string
+						CoreDumped bool
+						Message    string
+						Lang       string
+					}{Signal: name}))
+				} else {
+					channel.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{uint32(status.ExitStatus())}))
+				}
+				channel.Close()
+			}()
+		case "signal":
+			var payload struct{ Signal string }
+			ssh.Unmarshal(req.Payload, &payload)
+			if process != nil && process.Process != nil {
+				unix.Kill(-process.Process.Pid, unix.SignalNum("SIG"+payload.Signal))
+			}
+		default:
+			req.Reply(false, nil)
+		}
+	}
+	if process != nil && process.Process != nil {
+		unix.Kill(-process.Process.Pid, unix.SIGKILL)
+		<-exited
+	}
+}
+
+func serveDirectTCPIP(newChannel ssh.NewChannel) {
+	var target struct {
+		Host       string
+		Port       uint32
+		OriginHost string
+		OriginPort uint32
+	}
+	ssh.Unmarshal(newChannel.ExtraData(), &target)
+	conn, err := net.Dial("tcp", net.JoinHostPort(target.Host, strconv.Itoa(int(target.Port))))
+	if err != nil {
+		newChannel.Reject(ssh.ConnectionFailed, err.Error())
+		return
+	}
+	channel, requests, err := newChannel.Accept()
+	if err != nil {
+		conn.Close()
+		return
+	}
+	go ssh.DiscardRequests(requests)
+	go func() {
+		io.Copy(conn, channel)
+		conn.Close()
+	}()
+	io.Copy(channel, conn)
+	channel.Close()
+}
+
+func TestSSHExecutor(t *testing.T) {
+	newSigner := func() ssh.Signer {
+		_, key, err := ed25519.GenerateKey(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		signer, err := ssh.NewSignerFromKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signer
+	}
+	hostKey := newSigner()
+	otherHostKey := newSigner()
+
+	// The client key is read from a file, as configured with key_file
+	_, clientKey, _ := ed25519.GenerateKey(nil)
+	block, err := ssh.MarshalPrivateKey(clientKey, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "id_ed25519")
+	os.WriteFile(keyFile, pem.EncodeToMemory(block), 0600)
+	clientSigner, _ := ssh.NewSignerFromKey(clientKey)
+
+	addr := startSSHServer(t, hostKey, clientSigner.PublicKey())
+	host, portText, _ := net.SplitHostPort(addr)
+	port, _ := strconv.Atoi(portText)
+
+	knownHosts := filepath.Join(dir, "known_hosts")
+	os.WriteFile(knownHosts, []byte(knownhosts.Line([]string{addr}, hostKey.PublicKey())+"\n"), 0600)
+	pinned := string(ssh.MarshalAuthorizedKey(hostKey.PublicKey()))
+
+	remote := func(mutate func(*config.SSHConfig)) *config.SSHConfig {
+		settings := &config.SSHConfig{
+			Host: host, Port: port, User: "ops", KeyFile: keyFile, HostKey: pinned,
+			ConnectTimeoutDuration: 5 * time.Second,
+		}
+		if mutate != nil {
+			mutate(settings)
+		}
+		return settings
+	}
+	command := func(settings *config.SSHConfig) *config.Command {
+		return &config.Command{
+			Name:       "remote",
+			Script:     "sh",
+			Args:       []string{"-c", `echo "$GREETING, $1"; echo oops >&2; exit 3`, "sh", "{{.params.name}}"},
+			Env:        map[string]string{"GREETING": "hello"},
+			Parameters: []config.Parameter{{Name: "name", Type: config.ParamString}},
+			SSH:        settings,
+		}
+	}
+	params := map[string]interface{}{"name": "O'Brien; rm -rf /"}
+	service := New()
+
+	tests := []struct {
+		name     string
+		settings *config.SSHConfig
+		wantErr  string
+	}{
+		{name: "pinned host key", settings: remote(nil)},
+		{name: "known_hosts", settings: remote(func(s *config.SSHConfig) { s.HostKey = ""; s.KnownHosts = knownHosts })},
+		{name: "jump host", settings: remote(func(s *config.SSHConfig) { s.Jump = remote(nil) })},
+		{
+			name:     "host key mismatch",
+			settings: remote(func(s *config.SSHConfig) { s.HostKey = string(ssh.MarshalAuthorizedKey(otherHostKey.PublicKey())) }),
+			wantErr:  "host key mismatch",
+		},
+		{
+			name:     "unknown user",
+			settings: remote(func(s *config.SSHConfig) { s.User = "root" }),
+			wantErr:  "unable to authenticate",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := service.Execute(context.Background(), command(tt.settings), params)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err == nil || err.Error() != "exit status 3" || result.ExitCode != 3 {
+				t.Errorf("Expected exit status 3, got %d, %v", result.ExitCode, err)
+			}
+			if result.Stdout != "hello, O'Brien; rm -rf /\n" || result.Stderr != "oops\n" {
+				t.Errorf("Unexpected output %q, stderr %q", result.Stdout, result.Stderr)
+			}
+		})
+	}
+
+	t.Run("timeout terminates the remote command", func(t *testing.T) {
+		cmd := &config.Command{Name: "slow", Script: "sleep", Args: []string{"30"}, SSH: remote(nil), TimeoutDuration: 200 * time.Millisecond}
+		start := time.Now()
+		result, err := service.Execute(context.Background(), cmd, nil)
+		if !errors.Is(err, ErrTimeout) {
+			t.Fatalf("Expected timeout, got %v", err)
+		}
+		if elapsed := time.Since(start); elapsed > 3*time.Second {
+			t.Errorf("Expected the command to stop after SIGTERM, took %s", elapsed)
+		}
+		if result.ExitCode != -1 {
+			t.Errorf("Expected exit code -1, got %d", result.ExitCode)
+		}
+	})
+}
@@ -0,0 +1,453 @@
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gleicon/mcpfier/internal/config"
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkjson"
+	"go.starlark.net/starlarkstruct"
+	"go.starlark.net/syntax"
+)
+
+// maxStarlarkDepth bounds nested run() calls between Starlark commands
+const maxStarlarkDepth = 8
+
+// starlarkDepthKey holds the run() nesting depth in the context
+type starlarkDepthKey struct{}
+
+// starlarkFileOptions enables the language features tool scripts commonly need.
+// Recursion stays disabled; unbounded loops are stopped by the step limit.
+var starlarkFileOptions = &syntax.FileOptions{
+	Set:             true,
+	While:           true,
+	TopLevelControl: true,
+	GlobalReassign:  true,
+}
+
+// StarlarkExecutor runs Starlark scripts in-process. Scripts have no filesystem or
+// process access: besides the language itself they only get the http, json and
+// run built-ins, and load() is disabled.
+type StarlarkExecutor struct {
+	webhook *WebhookExecutor
+	// run executes commands called with run(); set by Service so they get
+	// timeouts and analytics like any other call
+	run func(ctx context.Context, cmd *config.Command, params map[string]interface{}) (*Result, error)
+
+	mu       sync.Mutex
+	programs map[string]*starlark.Program // Compiled scripts by source
+}
+
+// NewStarlarkExecutor creates a new Starlark executor that makes HTTP calls with webhook
+func NewStarlarkExecutor(webhook *WebhookExecutor) *StarlarkExecutor {
+	return &StarlarkExecutor{
+		webhook:  webhook,
+		programs: make(map[string]*starlark.Program),
+	}
+}
+
+// Execute runs the script's main(params) function. A string result becomes the
+// tool output; other values are returned as JSON and as structured content.
+// print() writes to stderr.
+func (e *StarlarkExecutor) Execute(ctx context.Context, cmd *config.Command, params map[string]interface{}) (*Result, error) {
+	depth, _ := ctx.Value(starlarkDepthKey{}).(int)
+	if depth >= maxStarlarkDepth {
+		return nil, fmt.Errorf("starlark run() nesting exceeds %d levels", maxStarlarkDepth)
+	}
+	ctx = context.WithValue(ctx, starlarkDepthKey{}, depth+1)
+
+	program, err := e.program(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	output := newOutputCapture(ctx)
+	thread := &starlark.Thread{
+		Name: cmd.Name,
+		Print: func(_ *starlark.Thread, msg string) {
+			fmt.Fprintln(output.stderr, msg)
+		},
+	}
+	thread.SetMaxExecutionSteps(cmd.Starlark.MaxSteps)
+
+	// Stop the script when the call times out or is cancelled
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			thread.Cancel(ctx.Err().Error())
+		case <-done:
+		}
+	}()
+
+	start := time.Now()
+	value, err := e.call(ctx, thread, program, cmd, params)
+
+	result := output.result()
+	result.Duration = time.Since(start)
+	if err != nil {
+		result.ExitCode = 1
+		if evalErr, ok := err.(*starlark.EvalError); ok {
+			result.Stderr += evalErr.Backtrace() + "\n"
+		}
+		if ctx.Err() != nil {
+			result.ExitCode = -1
+			return result, ctx.Err()
+		}
+		return result, fmt.Errorf("starlark: %w", err)
+	}
+
+	switch v := value.(type) {
+	case starlark.NoneType:
+	case starlark.String:
+		result.Stdout = string(v)
+	default:
+		encoded, err := starlark.Call(thread, starlarkjson.Module.Members["encode"], starlark.Tuple{value}, nil)
+		if err != nil {
+			result.ExitCode = 1
+			return result, fmt.Errorf("starlark: cannot encode result: %w", err)
+		}
+		result.Stdout = string(encoded.(starlark.String))
+		// Returned as structured content too, like a plugin's structured result
+		if err := json.Unmarshal([]byte(result.Stdout), &result.Structured); err != nil {
+			result.ExitCode = 1
+			return result, fmt.Errorf("starlark: cannot decode result: %w", err)
+		}
+	}
+	return result, nil
+}
+
+// program returns the compiled script for a command, compiling it on first use
+func (e *StarlarkExecutor) program(cmd *config.Command) (*starlark.Program, error) {
+	source := cmd.Starlark.Source
+	filename := cmd.Name + ".star"
+	if cmd.Starlark.File != "" {
+		data, err := os.ReadFile(cmd.Starlark.File)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read starlark file: %w", err)
+		}
+		source = string(data)
+		filename = cmd.Starlark.File
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if program, ok := e.programs[source]; ok {
+		return program, nil
+	}
+	_, program, err := starlark.SourceProgramOptions(starlarkFileOptions, filename, source, starlarkPredeclared.Has)
+	if err != nil {
+		return nil, fmt.Errorf("starlark: %w", err)
+	}
+	e.programs[source] = program
+	return program, nil
+}
+
+// starlarkPredeclared lists the built-ins every script can reference; the
+// http and run implementations are bound per call
+var starlarkPredeclared = starlark.StringDict{
+	"http":   starlark.None,
+	"json":   starlarkjson.Module,
+	"run":    starlark.None,
+	"struct": starlark.NewBuiltin("struct", starlarkstruct.Make),
+}
+
+// call initializes the script and calls its main function with the parameters
+func (e *StarlarkExecutor) call(ctx context.Context, thread *starlark.Thread, program *starlark.Program, cmd *config.Command, params map[string]interface{}) (starlark.Value, error) {
+	predeclared := starlark.StringDict{
+		"http":   e.httpModule(ctx, cmd),
+		"json":   starlarkjson.Module,
+		"run":    starlark.NewBuiltin("run", e.runBuiltin(ctx, cmd)),
+		"struct": starlarkPredeclared["struct"],
+	}
+	globals, err := program.Init(thread, predeclared)
+	if err != nil {
+		return nil, err
+	}
+	main, ok := globals["main"].(starlark.Callable)
+	if !ok {
+		return nil, fmt.Errorf("script does not define main(params)")
+	}
+
+	args, err := toStarlark(params)
+	if err != nil {
+		return nil, err
+	}
+	args.Freeze()
+	return starlark.Call(thread, main, starlark.Tuple{args}, nil)
+}
+
+// httpModule returns the http built-in: request(method, url, ...), get(url, ...)
+// and post(url, ...). Requests use the command's named credentials, retry
+// policy and client settings, like webhook commands.
+func (e *StarlarkExecutor) httpModule(ctx context.Context, cmd *config.Command) *starlarkstruct.Module {
+	request := func(thread *starlark.Thread, fn *starlark.Builtin, method string, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		var url, auth string
+		var headers *starlark.Dict
+		var body, jsonBody starlark.Value
+		if err := starlark.UnpackArgs(fn.Name(), args, kwargs,
+			"url", &url, "headers?", &headers, "body?", &body, "json?", &jsonBody, "auth?", &auth); err != nil {
+			return nil, err
+		}
+		return e.doRequest(ctx, thread, cmd, method, url, headers, body, jsonBody, auth)
+	}
+
+	return &starlarkstruct.Module{
+		Name: "http",
+		Members: starlark.StringDict{
+			"request": starlark.NewBuiltin("http.request", func(thread *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+				if len(args) == 0 {
+					return nil, fmt.Errorf("%s: missing argument for method", fn.Name())
+				}
+				method, ok := starlark.AsString(args[0])
+				if !ok {
+					return nil, fmt.Errorf("%s: method must be a string", fn.Name())
+				}
+				return request(thread, fn, strings.ToUpper(method), args[1:], kwargs)
+			}),
+			"get": starlark.NewBuiltin("http.get", func(thread *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+				return request(thread, fn, http.MethodGet, args, kwargs)
+			}),
+			"post": starlark.NewBuiltin("http.post", func(thread *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+				return request(thread, fn, http.MethodPost, args, kwargs)
+			}),
+		},
+	}
+}
+
+// doRequest performs an HTTP request for a script. Error statuses are returned
+// to the script, which decides how to handle them; transport errors fail it.
+func (e *StarlarkExecutor) doRequest(ctx context.Context, thread *starlark.Thread, cmd *config.Command, method, url string, headers *starlark.Dict, body, jsonBody starlark.Value, auth string) (starlark.Value, error) {
+	settings := cmd.Starlark
+
+	var reqBody io.Reader
+	contentType := ""
+	switch {
+	case body != nil && jsonBody != nil:
+		return nil, fmt.Errorf("http: body and json are mutually exclusive")
+	case jsonBody != nil:
+		encoded, err := starlark.Call(thread, starlarkjson.Module.Members["encode"], starlark.Tuple{jsonBody}, nil)
+		if err != nil {
+			return nil, err
+		}
+		reqBody = strings.NewReader(string(encoded.(starlark.String)))
+		contentType = "application/json"
+	case body != nil:
+		s, ok := starlark.AsString(body)
+		if !ok {
+			return nil, fmt.Errorf("http: body must be a string, got %s", body.Type())
+		}
+		reqBody = strings.NewReader(s)
+		contentType = "text/plain"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("http: %w", err)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if headers != nil {
+		for _, item := range headers.Items() {
+			key, ok1 := starlark.AsString(item[0])
+			value, ok2 := starlark.AsString(item[1])
+			if !ok1 || !ok2 {
+				return nil, fmt.Errorf("http: headers must map strings to strings")
+			}
+			req.Header.Set(key, value)
+		}
+	}
+	if req.Header.Get("User-Agent") == "" {
+		req.Header.Set("User-Agent", "MCPFier/1.0.0")
+	}
+
+	if auth != "" {
+		credentials, ok := settings.Auth[auth]
+		if !ok {
+			return nil, fmt.Errorf("http: unknown auth '%s'", auth)
+		}
+		if err := e.webhook.setAuthentication(req, credentials); err != nil {
+			return nil, fmt.Errorf("http: failed to set authentication: %w", err)
+		}
+	}
+
+	response, err := e.webhook.executeWithRetry(e.webhook.clientFor(cmd.Name, settings.Client), req, settings.Retry)
+	if err != nil {
+		return nil, fmt.Errorf("http: request failed: %w", err)
+	}
+	defer response.Body.Close()
+
+	limit := maxResponseSize(settings.Client)
+	data, err := io.ReadAll(io.LimitReader(response.Body, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("http: failed to read response: %w", err)
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("http: response exceeds max_response_size of %d bytes", limit)
+	}
+
+	responseHeaders := starlark.NewDict(len(response.Header))
+	for key := range response.Header {
+		responseHeaders.SetKey(starlark.String(strings.ToLower(key)), starlark.String(response.Header.Get(key)))
+	}
+	text := starlark.String(data)
+	return starlarkstruct.FromStringDict(starlarkstruct.Default, starlark.StringDict{
+		"status":  starlark.MakeInt(response.StatusCode),
+		"ok":      starlark.Bool(response.StatusCode < 400),
+		"headers": responseHeaders,
+		"body":    text,
+		"json": starlark.NewBuiltin("json", func(thread *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+			if err := starlark.UnpackPositionalArgs(fn.Name(), args, kwargs, 0); err != nil {
+				return nil, err
+			}
+			return starlark.Call(thread, starlarkjson.Module.Members["decode"], starlark.Tuple{text}, nil)
+		}),
+	}), nil
+}
+
+// runBuiltin returns the run(name, **params) built-in, which runs one of the
+// commands listed in starlark.commands and returns its output
+func (e *StarlarkExecutor) runBuiltin(ctx context.Context, cmd *config.Command) func(*starlark.Thread, *starlark.Builtin, starlark.Tuple, []starlark.Tuple) (starlark.Value, error) {
+	return func(thread *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		var name string
+		if err := starlark.UnpackPositionalArgs(fn.Name(), args, nil, 1, &name); err != nil {
+			return nil, err
+		}
+		target, ok := cmd.Starlark.CommandRefs[name]
+		if !ok {
+			return nil, fmt.Errorf("run: command '%s' is not listed in starlark.commands", name)
+		}
+
+		raw := make(map[string]interface{}, len(kwargs))
+		for _, kv := range kwargs {
+			value, err := fromStarlark(kv[1])
+			if err != nil {
+				return nil, fmt.Errorf("run: argument %s: %w", kv[0], err)
+			}
+			raw[string(kv[0].(starlark.String))] = value
+		}
+		params, err := target.ResolveArguments(raw)
+		if err != nil {
+			return nil, fmt.Errorf("run: command '%s': %w", name, err)
+		}
+
+		result, err := e.run(ctx, target, params)
+		if result == nil {
+			result = &Result{ExitCode: -1}
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		errText := starlark.Value(starlark.None)
+		if err != nil {
+			errText = starlark.String(err.Error())
+		}
+		return starlarkstruct.FromStringDict(starlarkstruct.Default, starlark.StringDict{
+			"ok":        starlark.Bool(err == nil),
+			"stdout":    starlark.String(result.Stdout),
+			"stderr":    starlark.String(result.Stderr),
+			"exit_code": starlark.MakeInt(result.ExitCode),
+			"error":     errText,
+		}), nil
+	}
+}
+
+// toStarlark converts tool arguments to Starlark values
+func toStarlark(v interface{}) (starlark.Value, error) {
+	switch v := v.(type) {
+	case nil:
+		return starlark.None, nil
+	case bool:
+		return starlark.Bool(v), nil
+	case string:
+		return starlark.String(v), nil
+	case int:
+		return starlark.MakeInt(v), nil
+	case int64:
+		return starlark.MakeInt64(v), nil
+	case float64:
+		return starlark.Float(v), nil
+	case []interface{}:
+		items := make([]starlark.Value, 0, len(v))
+		for _, item := range v {
+			value, err := toStarlark(item)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, value)
+		}
+		return starlark.NewList(items), nil
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		dict := starlark.NewDict(len(v))
+		for _, key := range keys {
+			value, err := toStarlark(v[key])
+			if err != nil {
+				return nil, err
+			}
+			dict.SetKey(starlark.String(key), value)
+		}
+		return dict, nil
+	}
+	return nil, fmt.Errorf("unsupported argument type %T", v)
+}
+
+// fromStarlark converts a Starlark value to a tool argument
+func fromStarlark(v starlark.Value) (interface{}, error) {
+	switch v := v.(type) {
+	case starlark.NoneType:
+		return nil, nil
+	case starlark.Bool:
+		return bool(v), nil
+	case starlark.String:
+		return string(v), nil
+	case starlark.Int:
+		i, ok := v.Int64()
+		if !ok {
+			return nil, fmt.Errorf("integer %s out of range", v)
+		}
+		return i, nil
+	case starlark.Float:
+		return float64(v), nil
+	case starlark.Indexable: // list, tuple
+		items := make([]interface{}, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			item, err := fromStarlark(v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	case *starlark.Dict:
+		m := make(map[string]interface{}, v.Len())
+		for _, item := range v.Items() {
+			key, ok := starlark.AsString(item[0])
+			if !ok {
+				return nil, fmt.Errorf("dict keys must be strings, got %s", item[0].Type())
+			}
+			value, err := fromStarlark(item[1])
+			if err != nil {
+				return nil, err
+			}
+			m[key] = value
+		}
+		return m, nil
+	}
+	return nil, fmt.Errorf("unsupported value of type %s", v.Type())
+}
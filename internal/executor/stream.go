@@ -0,0 +1,124 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"strconv"
+	"strings"
+)
+
+// progressMarker prefixes output lines that report structured progress, e.g.
+// "::progress 40/100 compiling"
+const progressMarker = "::progress "
+
+// maxLineLength bounds how much of an unterminated line is buffered before it is emitted
+const maxLineLength = 64 * 1024
+
+// Progress is a structured progress update reported by a command
+type Progress struct {
+	Current float64
+	Total   float64 // 0 when the total is unknown
+	Message string
+}
+
+// OutputLine is a line of command output observed while the command runs
+type OutputLine struct {
+	Stream   string    // "stdout" or "stderr"
+	Text     string    // Line without the trailing newline
+	Progress *Progress // Set when the line is a progress marker
+}
+
+// OutputFunc receives output lines as they are produced. It may be called
+// concurrently for stdout and stderr.
+type OutputFunc func(OutputLine)
+
+// outputKey is the context key for the output observer
+type outputKey struct{}
+
+// WithOutput returns a context that streams command output to fn while it runs
+func WithOutput(ctx context.Context, fn OutputFunc) context.Context {
+	return context.WithValue(ctx, outputKey{}, fn)
+}
+
+// outputFromContext returns the output observer, or nil when output isn't streamed
+func outputFromContext(ctx context.Context) OutputFunc {
+	fn, _ := ctx.Value(outputKey{}).(OutputFunc)
+	return fn
+}
+
+// lineWriter captures output into buf and emits each complete line to fn
+type lineWriter struct {
+	stream  string
+	buf     *bytes.Buffer
+	fn      OutputFunc
+	partial []byte
+}
+
+// newLineWriter creates a writer that tees output into buf and fn
+func newLineWriter(stream string, buf *bytes.Buffer, fn OutputFunc) *lineWriter {
+	return &lineWriter{stream: stream, buf: buf, fn: fn}
+}
+
+// Write implements io.Writer
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.buf.Write(p)
+
+	w.partial = append(w.partial, p...)
+	for {
+		i := bytes.IndexByte(w.partial, '\n')
+		if i < 0 {
+			break
+		}
+		w.emit(string(w.partial[:i]))
+		w.partial = w.partial[i+1:]
+	}
+	if len(w.partial) > maxLineLength {
+		w.emit(string(w.partial))
+		w.partial = nil
+	}
+
+	return len(p), nil
+}
+
+// Flush emits any trailing output that didn't end with a newline
+func (w *lineWriter) Flush() {
+	if len(w.partial) > 0 {
+		w.emit(string(w.partial))
+		w.partial = nil
+	}
+}
+
+// emit sends a single line to the observer
+func (w *lineWriter) emit(text string) {
+	text = strings.TrimSuffix(text, "\r")
+	w.fn(OutputLine{
+		Stream:   w.stream,
+		Text:     text,
+		Progress: parseProgress(text),
+	})
+}
+
+// parseProgress parses "::progress <current>[/<total>] [message]" marker lines
+func parseProgress(line string) *Progress {
+	rest, ok := strings.CutPrefix(line, progressMarker)
+	if !ok {
+		return nil
+	}
+
+	value, message, _ := strings.Cut(strings.TrimSpace(rest), " ")
+	currentStr, totalStr, hasTotal := strings.Cut(value, "/")
+
+	current, err := strconv.ParseFloat(currentStr, 64)
+	if err != nil {
+		return nil
+	}
+	progress := &Progress{Current: current, Message: strings.TrimSpace(message)}
+	if hasTotal {
+		total, err := strconv.ParseFloat(totalStr, 64)
+		if err != nil {
+			return nil
+		}
+		progress.Total = total
+	}
+	return progress
+}
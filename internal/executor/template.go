@@ -0,0 +1,237 @@
+package executor
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// escapeFunc converts a template value into text that is safe for its destination.
+// It is appended to every {{ }} action, so values are escaped where they are inserted.
+type escapeFunc func(value interface{}) (string, error)
+
+// escaperName is the template function injected at the end of every action pipeline
+const escaperName = "_mcpfier_escape"
+
+// jsonText marks output of the json template function as already JSON-encoded
+type jsonText string
+
+// templateFuncs are available to every argument template
+var templateFuncs = template.FuncMap{
+	// json encodes a value as JSON, e.g. {"tags": {{json .params.tags}}}
+	"json": func(v interface{}) (jsonText, error) {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		return jsonText(b), nil
+	},
+	// join joins an array parameter with a separator
+	"join": func(v interface{}, sep string) string {
+		items, ok := v.([]interface{})
+		if !ok {
+			return formatValue(v)
+		}
+		parts := make([]string, len(items))
+		for i, item := range items {
+			parts[i] = formatValue(item)
+		}
+		return strings.Join(parts, sep)
+	},
+}
+
+// templateData builds the data available to templates
+func templateData(params map[string]interface{}) map[string]interface{} {
+	if params == nil {
+		params = map[string]interface{}{}
+	}
+	return map[string]interface{}{"params": params}
+}
+
+// renderTemplate renders text against data (see templateData), escaping every inserted value
+func renderTemplate(name, text string, data map[string]interface{}, escape escapeFunc) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+
+	funcs := template.FuncMap{escaperName: escape}
+	for k, v := range templateFuncs {
+		funcs[k] = v
+	}
+
+	tmpl, err := template.New(name).Funcs(funcs).Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid template in %s: %w", name, err)
+	}
+	for _, t := range tmpl.Templates() {
+		if t.Tree != nil {
+			addEscaper(t.Tree, t.Tree.Root)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render %s: %w", name, err)
+	}
+	return buf.String(), nil
+}
+
+// addEscaper appends the escaper to every output action in the parse tree
+func addEscaper(tree *parse.Tree, node parse.Node) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			addEscaper(tree, child)
+		}
+	case *parse.ActionNode:
+		// Variable declarations produce no output
+		if len(n.Pipe.Decl) > 0 {
+			return
+		}
+		// Constant strings, such as {{"{{"}} from escaped config values, are not caller input
+		if len(n.Pipe.Cmds) == 1 && len(n.Pipe.Cmds[0].Args) == 1 {
+			if _, ok := n.Pipe.Cmds[0].Args[0].(*parse.StringNode); ok {
+				return
+			}
+		}
+		n.Pipe.Cmds = append(n.Pipe.Cmds, &parse.CommandNode{
+			NodeType: parse.NodeCommand,
+			Pos:      n.Pos,
+			Args:     []parse.Node{parse.NewIdentifier(escaperName).SetTree(tree).SetPos(n.Pos)},
+		})
+	case *parse.IfNode:
+		addEscaper(tree, n.List)
+		addEscaper(tree, n.ElseList)
+	case *parse.RangeNode:
+		addEscaper(tree, n.List)
+		addEscaper(tree, n.ElseList)
+	case *parse.WithNode:
+		addEscaper(tree, n.List)
+		addEscaper(tree, n.ElseList)
+	}
+}
+
+// formatValue converts a parameter value to its plain text form
+func formatValue(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case jsonText:
+		return string(val)
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(val)
+	case []interface{}:
+		parts := make([]string, len(val))
+		for i, item := range val {
+			parts[i] = formatValue(item)
+		}
+		return strings.Join(parts, ",")
+	default:
+		return fmt.Sprint(val)
+	}
+}
+
+// rawEscape inserts values verbatim; used for argv elements and env values, which never pass through a shell
+func rawEscape(v interface{}) (string, error) {
+	return formatValue(v), nil
+}
+
+// urlEscape percent-encodes values for URL paths and query strings
+func urlEscape(v interface{}) (string, error) {
+	return strings.ReplaceAll(url.QueryEscape(formatValue(v)), "+", "%20"), nil
+}
+
+// formEscape encodes values for application/x-www-form-urlencoded bodies
+func formEscape(v interface{}) (string, error) {
+	return url.QueryEscape(formatValue(v)), nil
+}
+
+// jsonEscape escapes values for insertion into JSON strings; json function output is kept as-is
+func jsonEscape(v interface{}) (string, error) {
+	switch val := v.(type) {
+	case jsonText:
+		return string(val), nil
+	case float64, bool:
+		return formatValue(val), nil
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(formatValue(v)); err != nil {
+		return "", err
+	}
+	quoted := strings.TrimSuffix(buf.String(), "\n")
+	return quoted[1 : len(quoted)-1], nil
+}
+
+// xmlEscape escapes values for XML text and attributes
+func xmlEscape(v interface{}) (string, error) {
+	var buf bytes.Buffer
+	if err := xml.EscapeText(&buf, []byte(formatValue(v))); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// headerEscape rejects values that would inject additional HTTP headers
+func headerEscape(v interface{}) (string, error) {
+	s := formatValue(v)
+	if strings.ContainsAny(s, "\r\n") {
+		return "", fmt.Errorf("header value contains a line break")
+	}
+	return s, nil
+}
+
+// bodyEscaper returns the escaper matching a webhook body format
+func bodyEscaper(format string) escapeFunc {
+	switch strings.ToLower(format) {
+	case "json":
+		return jsonEscape
+	case "form":
+		return formEscape
+	case "xml":
+		return xmlEscape
+	default:
+		return rawEscape
+	}
+}
+
+// renderArgs renders each argument as a separate argv element
+func renderArgs(args []string, data map[string]interface{}) ([]string, error) {
+	rendered := make([]string, 0, len(args))
+	for i, arg := range args {
+		s, err := renderTemplate(fmt.Sprintf("args[%d]", i), arg, data, rawEscape)
+		if err != nil {
+			return nil, err
+		}
+		rendered = append(rendered, s)
+	}
+	return rendered, nil
+}
+
+// renderEnv renders environment variable values
+func renderEnv(env map[string]string, data map[string]interface{}) (map[string]string, error) {
+	rendered := make(map[string]string, len(env))
+	for k, v := range env {
+		s, err := renderTemplate("env."+k, v, data, rawEscape)
+		if err != nil {
+			return nil, err
+		}
+		rendered[k] = s
+	}
+	return rendered, nil
+}
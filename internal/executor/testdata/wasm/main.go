@@ -0,0 +1,35 @@
+// Command wasm is a WASI test module for TestWasmExecutor
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	switch os.Args[1] {
+	case "spin":
+		for {
+		}
+	case "fail":
+		os.Exit(3)
+	}
+
+	input, _ := io.ReadAll(os.Stdin)
+	fmt.Printf("args=%s stdin=%s greeting=%s\n", strings.Join(os.Args[1:], ","), input, os.Getenv("GREETING"))
+
+	data, err := os.ReadFile("/data/in.txt")
+	fmt.Printf("read=%s %v\n", data, err)
+	if err := os.WriteFile("/data/out.txt", []byte("x"), 0644); err != nil {
+		fmt.Println("write denied")
+	}
+	if _, err := os.ReadFile("/etc/passwd"); err != nil {
+		fmt.Println("host denied")
+	}
+	if dir := os.Getenv("MCPFIER_OUTPUT_DIR"); dir != "" {
+		os.WriteFile(filepath.Join(dir, "result.txt"), []byte("artifact"), 0644)
+	}
+}
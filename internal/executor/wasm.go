@@ -0,0 +1,146 @@
+package executor
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gleicon/mcpfier/internal/config"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+// wasmPageSize is the size of a WebAssembly linear memory page
+const wasmPageSize = 64 * 1024
+
+// WasmExecutor runs WASI modules in-process with wazero. Each call gets its own
+// runtime, so modules share no state; compiled code is cached across calls.
+type WasmExecutor struct {
+	cache wazero.CompilationCache
+}
+
+// NewWasmExecutor creates a new WebAssembly executor
+func NewWasmExecutor() *WasmExecutor {
+	return &WasmExecutor{cache: wazero.NewCompilationCache()}
+}
+
+// Execute instantiates the command's module, running its _start function. The
+// module sees only its configured mounts, environment and stdin. wazero cannot
+// meter fuel, so execution time is bounded only by ctx: the module is
+// interrupted when it is done, which the command's timeout arranges.
+func (e *WasmExecutor) Execute(ctx context.Context, cmd *config.Command, params map[string]interface{}) (*Result, error) {
+	wasm := cmd.Wasm
+
+	outputDir, err := newOutputDir(cmd)
+	if err != nil {
+		return nil, err
+	}
+	if outputDir != "" {
+		defer os.RemoveAll(outputDir)
+	}
+
+	// The output directory is mounted at the same guest path as in containers
+	data := templateData(params)
+	if outputDir != "" {
+		data["output_dir"] = containerOutputDir
+	}
+	args, err := renderArgs(cmd.Args, data)
+	if err != nil {
+		return nil, err
+	}
+	env, err := renderEnv(cmd.Env, data)
+	if err != nil {
+		return nil, err
+	}
+	stdin, err := renderTemplate("wasm.stdin", wasm.Stdin, data, rawEscape)
+	if err != nil {
+		return nil, err
+	}
+
+	code, err := os.ReadFile(wasm.Module)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read wasm module: %w", err)
+	}
+
+	runtimeConfig := wazero.NewRuntimeConfig().
+		WithCompilationCache(e.cache).
+		WithCloseOnContextDone(true)
+	if limit := wasm.MemoryBytes(); limit > 0 {
+		runtimeConfig = runtimeConfig.WithMemoryLimitPages(uint32((limit + wasmPageSize - 1) / wasmPageSize))
+	}
+	runtime := wazero.NewRuntimeWithConfig(ctx, runtimeConfig)
+	defer runtime.Close(context.Background())
+
+	if _, err := wasi_snapshot_preview1.Instantiate(ctx, runtime); err != nil {
+		return nil, err
+	}
+	compiled, err := runtime.CompileModule(ctx, code)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile wasm module: %w", err)
+	}
+
+	fsConfig := wazero.NewFSConfig()
+	for _, mount := range wasm.Mounts {
+		if mount.ReadOnly {
+			fsConfig = fsConfig.WithReadOnlyDirMount(mount.Source, mount.Target)
+		} else {
+			fsConfig = fsConfig.WithDirMount(mount.Source, mount.Target)
+		}
+	}
+	if outputDir != "" {
+		fsConfig = fsConfig.WithDirMount(outputDir, containerOutputDir)
+		env[outputDirEnv] = containerOutputDir
+	}
+
+	output := newOutputCapture(ctx)
+	moduleConfig := wazero.NewModuleConfig().
+		WithName("").
+		WithArgs(append([]string{filepath.Base(wasm.Module)}, args...)...).
+		WithStdin(strings.NewReader(stdin)).
+		WithStdout(output.stdout).
+		WithStderr(output.stderr).
+		WithFSConfig(fsConfig).
+		WithSysWalltime().
+		WithSysNanotime().
+		WithRandSource(rand.Reader)
+	for k, v := range env {
+		moduleConfig = moduleConfig.WithEnv(k, v)
+	}
+
+	start := time.Now()
+	module, err := runtime.InstantiateModule(ctx, compiled, moduleConfig)
+	if module != nil {
+		module.Close(context.Background())
+	}
+
+	result := output.result()
+	result.Duration = time.Since(start)
+
+	var exitErr *sys.ExitError
+	switch {
+	case err == nil:
+	case ctx.Err() != nil:
+		result.ExitCode = -1
+		err = ctx.Err()
+	case errors.As(err, &exitErr):
+		result.ExitCode = int(exitErr.ExitCode())
+		err = nil
+		if result.ExitCode != 0 {
+			err = fmt.Errorf("exit status %d", result.ExitCode)
+		}
+	default:
+		// Traps such as unreachable or out-of-bounds memory access
+		result.ExitCode = -1
+	}
+
+	if err == nil && outputDir != "" {
+		result.Artifacts, err = collectArtifacts(outputDir, cmd.Artifacts)
+	}
+	return result, err
+}
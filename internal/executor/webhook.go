@@ -9,32 +9,118 @@ import (
 	"io"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gleicon/mcpfier/internal/config"
 )
 
+// Default HTTP client settings, overridable per command with webhook.client
+const (
+	defaultWebhookTimeout      = 30 * time.Second
+	defaultMaxIdleConns        = 100
+	defaultMaxIdleConnsPerHost = 10
+	defaultIdleConnTimeout     = 90 * time.Second
+	defaultMaxRedirects        = 10
+	defaultMaxResponseSize     = 10 << 20 // 10MB
+)
+
 // WebhookExecutor handles webhook/API calls
 type WebhookExecutor struct {
-	client *http.Client
+	mu      sync.Mutex
+	clients map[string]*http.Client // Per-command clients, so connections are reused
 }
 
 // NewWebhookExecutor creates a new webhook executor
 func NewWebhookExecutor() *WebhookExecutor {
 	return &WebhookExecutor{
-		client: &http.Client{
-			Timeout: 30 * time.Second, // Default timeout
+		clients: make(map[string]*http.Client),
+	}
+}
+
+// clientFor returns the cached HTTP client for a command, creating it on first use.
+// Clients are never modified after creation, so concurrent calls can share them.
+func (e *WebhookExecutor) clientFor(name string, settings *config.WebhookClient) *http.Client {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if client, ok := e.clients[name]; ok {
+		return client
+	}
+	client := newHTTPClient(settings)
+	e.clients[name] = client
+	return client
+}
+
+// newHTTPClient builds an HTTP client from per-command settings
+func newHTTPClient(settings *config.WebhookClient) *http.Client {
+	if settings == nil {
+		settings = &config.WebhookClient{}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConns = defaultMaxIdleConns
+	transport.MaxIdleConnsPerHost = defaultMaxIdleConnsPerHost
+	transport.IdleConnTimeout = defaultIdleConnTimeout
+	if settings.MaxIdleConns > 0 {
+		transport.MaxIdleConns = settings.MaxIdleConns
+	}
+	if settings.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = settings.MaxIdleConnsPerHost
+	}
+	if settings.MaxConnsPerHost > 0 {
+		transport.MaxConnsPerHost = settings.MaxConnsPerHost
+	}
+	if settings.IdleConnTimeoutDuration > 0 {
+		transport.IdleConnTimeout = settings.IdleConnTimeoutDuration
+	}
+	transport.DisableKeepAlives = settings.DisableKeepAlives
+
+	timeout := defaultWebhookTimeout
+	if settings.TimeoutDuration > 0 {
+		timeout = settings.TimeoutDuration
+	}
+
+	maxRedirects := defaultMaxRedirects
+	if settings.MaxRedirects > 0 {
+		maxRedirects = settings.MaxRedirects
+	}
+	followRedirects := settings.FollowRedirects == nil || *settings.FollowRedirects
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if !followRedirects {
+				return http.ErrUseLastResponse
+			}
+			if len(via) >= maxRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxRedirects)
+			}
+			return nil
 		},
 	}
 }
 
+// maxResponseSize returns the response size limit for a client
+func maxResponseSize(settings *config.WebhookClient) int64 {
+	if settings != nil && settings.MaxResponseSize > 0 {
+		return settings.MaxResponseSize
+	}
+	return defaultMaxResponseSize
+}
+
 // Execute performs a webhook/API call
-func (e *WebhookExecutor) Execute(ctx context.Context, cmd *config.Command) (string, error) {
+func (e *WebhookExecutor) Execute(ctx context.Context, cmd *config.Command, params map[string]interface{}) (*Result, error) {
 	if cmd.Webhook == nil {
-		return "", fmt.Errorf("webhook configuration is nil")
+		return nil, fmt.Errorf("webhook configuration is nil")
 	}
 
-	webhook := cmd.Webhook
+	// Substitute caller-supplied arguments into URL, headers and body
+	webhook, err := e.renderWebhook(cmd.Webhook, params)
+	if err != nil {
+		return nil, err
+	}
 	
 	// Set default method if not specified
 	method := webhook.Method
@@ -47,7 +133,7 @@ func (e *WebhookExecutor) Execute(ctx context.Context, cmd *config.Command) (str
 	if webhook.Body != "" {
 		bodyContent, err := e.prepareRequestBody(webhook.Body, webhook.BodyFormat)
 		if err != nil {
-			return "", fmt.Errorf("failed to prepare request body: %w", err)
+			return nil, fmt.Errorf("failed to prepare request body: %w", err)
 		}
 		body = bytes.NewReader(bodyContent)
 	}
@@ -55,7 +141,7 @@ func (e *WebhookExecutor) Execute(ctx context.Context, cmd *config.Command) (str
 	// Create HTTP request
 	req, err := http.NewRequestWithContext(ctx, method, webhook.URL, body)
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	// Set headers
@@ -63,35 +149,80 @@ func (e *WebhookExecutor) Execute(ctx context.Context, cmd *config.Command) (str
 	
 	// Set authentication
 	if err := e.setAuthentication(req, webhook.Auth); err != nil {
-		return "", fmt.Errorf("failed to set authentication: %w", err)
-	}
-
-	// Apply timeout from command config
-	if cmd.Timeout != "" {
-		if duration, err := time.ParseDuration(cmd.Timeout); err == nil {
-			e.client.Timeout = duration
-		}
+		return nil, fmt.Errorf("failed to set authentication: %w", err)
 	}
 
-	// Execute request with retries
-	response, err := e.executeWithRetry(req, webhook.Retry)
+	// Execute request with retries. The command timeout is already applied to ctx as
+	// an overall deadline; the client timeout bounds each attempt.
+	response, err := e.executeWithRetry(e.clientFor(cmd.Name, webhook.Client), req, webhook.Retry)
 	if err != nil {
-		return "", fmt.Errorf("request failed: %w", err)
+		return nil, fmt.Errorf("request failed: %w", err)
 	}
 	defer response.Body.Close()
 
-	// Read response
-	responseBody, err := io.ReadAll(response.Body)
+	// Read response, refusing to buffer more than the configured maximum
+	limit := maxResponseSize(webhook.Client)
+	responseBody, err := io.ReadAll(io.LimitReader(response.Body, limit+1))
 	if err != nil {
-		return "", fmt.Errorf("failed to read response: %w", err)
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	result := &Result{HTTPStatus: response.StatusCode}
+	if int64(len(responseBody)) > limit {
+		result.Stdout = string(responseBody[:limit])
+		result.StdoutTruncated = true
+		result.ExitCode = 1
+		return result, fmt.Errorf("response exceeds max_response_size of %d bytes", limit)
+	}
+
+	// Binary responses (images, audio, PDFs, ...) are returned as artifacts instead of text
+	contentType := mediaType(response.Header.Get("Content-Type"))
+	if response.StatusCode < 400 && len(responseBody) > 0 && !isTextual(contentType) {
+		if contentType == "application/octet-stream" {
+			contentType = detectMIMEType(response.Request.URL.Path, responseBody)
+		}
+		result.Artifacts = []Artifact{{
+			Name:     "response",
+			MIMEType: contentType,
+			Data:     responseBody,
+		}}
+		return result, nil
 	}
+	result.Stdout = string(responseBody)
 
 	// Check if response indicates an error
 	if response.StatusCode >= 400 {
-		return string(responseBody), fmt.Errorf("HTTP %d: %s", response.StatusCode, response.Status)
+		result.ExitCode = 1
+		return result, fmt.Errorf("HTTP %d: %s", response.StatusCode, response.Status)
 	}
 
-	return string(responseBody), nil
+	return result, nil
+}
+
+// renderWebhook returns a copy of the webhook config with templates rendered,
+// escaping values for the part of the request they end up in
+func (e *WebhookExecutor) renderWebhook(webhook *config.WebhookConfig, params map[string]interface{}) (*config.WebhookConfig, error) {
+	rendered := *webhook
+	data := templateData(params)
+
+	var err error
+	if rendered.URL, err = renderTemplate("webhook.url", webhook.URL, data, urlEscape); err != nil {
+		return nil, err
+	}
+
+	if len(webhook.Headers) > 0 {
+		rendered.Headers = make(map[string]string, len(webhook.Headers))
+		for key, value := range webhook.Headers {
+			if rendered.Headers[key], err = renderTemplate("webhook.headers."+key, value, data, headerEscape); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if rendered.Body, err = renderTemplate("webhook.body", webhook.Body, data, bodyEscaper(webhook.BodyFormat)); err != nil {
+		return nil, err
+	}
+
+	return &rendered, nil
 }
 
 // prepareRequestBody formats the request body according to the specified format
@@ -182,7 +313,7 @@ func (e *WebhookExecutor) setAuthentication(req *http.Request, auth *config.Webh
 }
 
 // executeWithRetry executes the request with retry logic
-func (e *WebhookExecutor) executeWithRetry(req *http.Request, retryConfig *config.WebhookRetry) (*http.Response, error) {
+func (e *WebhookExecutor) executeWithRetry(client *http.Client, req *http.Request, retryConfig *config.WebhookRetry) (*http.Response, error) {
 	maxRetries := 3
 	delay := time.Second
 	backoff := "exponential"
@@ -213,7 +344,7 @@ func (e *WebhookExecutor) executeWithRetry(req *http.Request, retryConfig *confi
 			reqClone.Body = body
 		}
 
-		resp, err := e.client.Do(reqClone)
+		resp, err := client.Do(reqClone)
 		if err != nil {
 			lastErr = err
 		} else if e.shouldRetry(resp.StatusCode, retryConfig) {
@@ -223,10 +354,16 @@ func (e *WebhookExecutor) executeWithRetry(req *http.Request, retryConfig *confi
 			return resp, nil
 		}
 
-		// Don't sleep after the last attempt
+		// Don't sleep after the last attempt, and stop waiting if the request is cancelled
 		if attempt < maxRetries {
 			sleepDuration := e.calculateDelay(delay, attempt, backoff)
-			time.Sleep(sleepDuration)
+			timer := time.NewTimer(sleepDuration)
+			select {
+			case <-req.Context().Done():
+				timer.Stop()
+				return nil, req.Context().Err()
+			case <-timer.C:
+			}
 		}
 	}
 
@@ -0,0 +1,220 @@
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gleicon/mcpfier/internal/config"
+)
+
+// WorkflowExecutor runs workflow commands: their steps run other configured
+// commands, one after the other or in parallel groups
+type WorkflowExecutor struct {
+	// run executes the steps' commands; set by Service so they get timeouts,
+	// fallback and analytics like any other call
+	run func(ctx context.Context, cmd *config.Command, params map[string]interface{}) (*Result, error)
+}
+
+// NewWorkflowExecutor creates a new workflow executor
+func NewWorkflowExecutor() *WorkflowExecutor {
+	return &WorkflowExecutor{}
+}
+
+// stepResult is the outcome of one step
+type stepResult struct {
+	result *Result
+	err    error
+}
+
+// Execute runs the workflow's steps in order. Step params are rendered against
+// the tool arguments and the results of earlier steps, available to templates
+// as .params and .steps.<id>. The tool output is the rendered output template,
+// or the stdout of the final step.
+func (e *WorkflowExecutor) Execute(ctx context.Context, cmd *config.Command, params map[string]interface{}) (*Result, error) {
+	output := newOutputCapture(ctx)
+	start := time.Now()
+
+	steps := make(map[string]interface{})
+	data := templateData(params)
+	data["steps"] = steps
+
+	var last []*config.WorkflowStep
+	var lastResults []stepResult
+	for i := range cmd.Workflow.Steps {
+		group := []*config.WorkflowStep{&cmd.Workflow.Steps[i]}
+		if parallel := cmd.Workflow.Steps[i].Parallel; len(parallel) > 0 {
+			group = group[:0]
+			for j := range parallel {
+				group = append(group, &parallel[j])
+			}
+		}
+
+		results := e.runGroup(ctx, group, data)
+		for j, step := range group {
+			steps[step.ID] = stepData(results[j])
+		}
+		if ctx.Err() != nil {
+			result := output.result()
+			result.Duration = time.Since(start)
+			result.ExitCode = -1
+			return result, ctx.Err()
+		}
+
+		for j, step := range group {
+			if results[j].err == nil {
+				continue
+			}
+			writeStepFailure(output.stderr, "step", step.ID, results[j])
+			if step.OnFailure == config.OnFailureContinue {
+				continue
+			}
+
+			err := fmt.Errorf("step '%s' failed: %w", step.ID, results[j].err)
+			if step.OnFailure == config.OnFailureCompensate {
+				compensation := e.runStep(ctx, step.Compensate, data)
+				if compensation.err != nil {
+					writeStepFailure(output.stderr, "compensating step", step.Compensate.ID, compensation)
+					err = fmt.Errorf("%w; compensating step '%s' failed: %v", err, step.Compensate.ID, compensation.err)
+				}
+			}
+			result := output.result()
+			result.Duration = time.Since(start)
+			result.ExitCode = results[j].result.ExitCode
+			if result.ExitCode == 0 {
+				result.ExitCode = -1
+			}
+			return result, err
+		}
+		last, lastResults = group, results
+	}
+
+	if cmd.Workflow.Output != "" {
+		text, err := renderTemplate("workflow.output", cmd.Workflow.Output, data, rawEscape)
+		if err != nil {
+			return nil, err
+		}
+		io.WriteString(output.stdout, text)
+	} else {
+		for _, r := range lastResults {
+			io.WriteString(output.stdout, r.result.Stdout)
+		}
+	}
+
+	result := output.result()
+	result.Duration = time.Since(start)
+	if cmd.Workflow.Output == "" && len(last) == 1 {
+		result.Structured = lastResults[0].result.Structured
+	}
+	return result, nil
+}
+
+// runGroup runs a single step, or the steps of a parallel group concurrently.
+// Steps in a group only see the results of the steps before the group.
+func (e *WorkflowExecutor) runGroup(ctx context.Context, group []*config.WorkflowStep, data map[string]interface{}) []stepResult {
+	results := make([]stepResult, len(group))
+	if len(group) == 1 {
+		results[0] = e.runStep(ctx, group[0], data)
+		return results
+	}
+
+	var wg sync.WaitGroup
+	for i, step := range group {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = e.runStep(ctx, step, data)
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// runStep renders a step's params and runs its command
+func (e *WorkflowExecutor) runStep(ctx context.Context, step *config.WorkflowStep, data map[string]interface{}) stepResult {
+	params, err := stepParams(step, data)
+	if err != nil {
+		return stepResult{result: &Result{ExitCode: -1}, err: err}
+	}
+	result, err := e.run(ctx, step.CommandRef, params)
+	if result == nil {
+		result = &Result{ExitCode: -1}
+	}
+	return stepResult{result: result, err: err}
+}
+
+// stepParams renders the string params of a step and validates them against
+// the parameters of the step's command. Rendered values for number, boolean and
+// array parameters are decoded as JSON, and values rendering empty are left out
+// so the command's defaults apply.
+func stepParams(step *config.WorkflowStep, data map[string]interface{}) (map[string]interface{}, error) {
+	types := make(map[string]string, len(step.CommandRef.Parameters))
+	for _, param := range step.CommandRef.Parameters {
+		types[param.Name] = param.GetType()
+	}
+
+	raw := make(map[string]interface{}, len(step.Params))
+	for name, value := range step.Params {
+		text, ok := value.(string)
+		if !ok {
+			raw[name] = value
+			continue
+		}
+		rendered, err := renderTemplate(fmt.Sprintf("%s.params.%s", step.ID, name), text, data, rawEscape)
+		if err != nil {
+			return nil, err
+		}
+		switch types[name] {
+		case config.ParamNumber, config.ParamBoolean, config.ParamArray:
+			if strings.TrimSpace(rendered) == "" {
+				continue
+			}
+			var decoded interface{}
+			if err := json.Unmarshal([]byte(rendered), &decoded); err != nil {
+				return nil, fmt.Errorf("argument '%s': expected a JSON %s, got %q", name, types[name], rendered)
+			}
+			raw[name] = decoded
+		default:
+			if rendered == "" && text != "" {
+				continue
+			}
+			raw[name] = rendered
+		}
+	}
+	return step.CommandRef.ResolveArguments(raw)
+}
+
+// stepData is what templates see of a step's result as .steps.<id>. json holds
+// the step's structured content, or its stdout parsed as JSON if it is valid.
+func stepData(r stepResult) map[string]interface{} {
+	data := map[string]interface{}{
+		"stdout":    r.result.Stdout,
+		"stderr":    r.result.Stderr,
+		"exit_code": r.result.ExitCode,
+		"ok":        r.err == nil,
+		"error":     getErrorString(r.err),
+		"json":      r.result.Structured,
+	}
+	if data["json"] == nil {
+		var decoded interface{}
+		if json.Unmarshal([]byte(r.result.Stdout), &decoded) == nil {
+			data["json"] = decoded
+		}
+	}
+	return data
+}
+
+// writeStepFailure notes a failed step and its stderr in the workflow's stderr
+func writeStepFailure(w io.Writer, kind, id string, r stepResult) {
+	fmt.Fprintf(w, "%s '%s' failed: %v\n", kind, id, r.err)
+	if r.result.Stderr != "" {
+		io.WriteString(w, r.result.Stderr)
+		if !strings.HasSuffix(r.result.Stderr, "\n") {
+			io.WriteString(w, "\n")
+		}
+	}
+}
@@ -0,0 +1,224 @@
+package jobs
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gleicon/mcpfier/internal/analytics"
+	_ "modernc.org/sqlite"
+)
+
+// Job states
+const (
+	StatusRunning     = "running"
+	StatusSucceeded   = "succeeded"
+	StatusFailed      = "failed"
+	StatusCancelled   = "cancelled"
+	StatusInterrupted = "interrupted" // The server running the job stopped before it finished
+)
+
+// ErrNotFound is returned for unknown or expired jobs
+var ErrNotFound = errors.New("job not found")
+
+// heartbeatInterval is how often a store marks its running jobs as alive.
+// Running jobs without a heartbeat for staleHeartbeats intervals belonged
+// to a server that stopped, and are reported as interrupted.
+var heartbeatInterval = 30 * time.Second
+
+const staleHeartbeats = 3
+
+// Job is an async command run and, once it finished, its result
+type Job struct {
+	ID         string
+	Command    string
+	Owner      string // Authenticated user that started the job, if any
+	Status     string
+	Error      string
+	Result     []byte // JSON encoded result, set when the job finished
+	CreatedAt  time.Time
+	FinishedAt time.Time // Zero while running
+}
+
+// Finished returns true if the job is no longer running
+func (j *Job) Finished() bool {
+	return j.Status != StatusRunning
+}
+
+// Store keeps jobs in a SQLite database, so their results outlive client
+// connections and server restarts. Finished jobs are deleted after the
+// retention period.
+type Store struct {
+	db        *sql.DB
+	instance  string // Identifies the jobs this store runs
+	retention time.Duration
+
+	stop     chan struct{}
+	stopped  sync.WaitGroup
+	stopOnce sync.Once
+}
+
+// Open opens the job store in the SQLite database at path, creating the jobs
+// table if needed
+func Open(path string, retention time.Duration) (*Store, error) {
+	resolved, err := analytics.ResolvePath(path)
+	if err != nil {
+		return nil, err
+	}
+	// The database may be shared with analytics, so wait for its writes
+	db, err := sql.Open("sqlite", "file:"+resolved+"?_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Store{
+		db:        db,
+		instance:  newID(),
+		retention: retention,
+		stop:      make(chan struct{}),
+	}
+	if err := s.createTables(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create jobs table: %w", err)
+	}
+	s.maintain()
+
+	s.stopped.Add(1)
+	go s.heartbeat()
+	return s, nil
+}
+
+// createTables creates the jobs table
+func (s *Store) createTables() error {
+	_, err := s.db.Exec(`
+	CREATE TABLE IF NOT EXISTS jobs (
+		id TEXT PRIMARY KEY,
+		command_name TEXT,
+		owner TEXT DEFAULT '',
+		status TEXT,
+		error_message TEXT DEFAULT '',
+		result TEXT DEFAULT '',
+		instance TEXT,
+		created_at INTEGER,
+		finished_at INTEGER DEFAULT 0,
+		heartbeat_at INTEGER
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_jobs_status ON jobs(status);
+	`)
+	return err
+}
+
+// Create records a new running job for command
+func (s *Store) Create(command, owner string) (*Job, error) {
+	now := time.Now()
+	job := &Job{
+		ID:        newID(),
+		Command:   command,
+		Owner:     owner,
+		Status:    StatusRunning,
+		CreatedAt: now,
+	}
+	_, err := s.db.Exec(`
+		INSERT INTO jobs (id, command_name, owner, status, instance, created_at, heartbeat_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		job.ID, job.Command, job.Owner, job.Status, s.instance, now.UnixMilli(), now.UnixMilli())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create job: %w", err)
+	}
+	return job, nil
+}
+
+// Finish records the final status and result of a job
+func (s *Store) Finish(id, status string, result []byte, errMessage string) error {
+	_, err := s.db.Exec(`
+		UPDATE jobs SET status = ?, result = ?, error_message = ?, finished_at = ?
+		WHERE id = ?`,
+		status, string(result), errMessage, time.Now().UnixMilli(), id)
+	if err != nil {
+		return fmt.Errorf("failed to record result of job %s: %w", id, err)
+	}
+	return nil
+}
+
+// Get returns a job by ID
+func (s *Store) Get(id string) (*Job, error) {
+	s.markStale()
+
+	var job Job
+	var result string
+	var created, finished int64
+	err := s.db.QueryRow(`
+		SELECT id, command_name, owner, status, error_message, result, created_at, finished_at
+		FROM jobs WHERE id = ?`, id).
+		Scan(&job.ID, &job.Command, &job.Owner, &job.Status, &job.Error, &result, &created, &finished)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	job.CreatedAt = time.UnixMilli(created)
+	if finished > 0 {
+		job.FinishedAt = time.UnixMilli(finished)
+	}
+	if result != "" {
+		job.Result = []byte(result)
+	}
+	return &job, nil
+}
+
+// Close stops the heartbeat and closes the database. Jobs still running are
+// reported as interrupted once their heartbeat is stale.
+func (s *Store) Close() error {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+	})
+	s.stopped.Wait()
+	return s.db.Close()
+}
+
+// heartbeat keeps this store's running jobs alive and deletes expired jobs
+func (s *Store) heartbeat() {
+	defer s.stopped.Done()
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.db.Exec(`UPDATE jobs SET heartbeat_at = ? WHERE instance = ? AND status = ?`,
+				time.Now().UnixMilli(), s.instance, StatusRunning)
+			s.maintain()
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// maintain marks abandoned jobs as interrupted and deletes finished jobs past the retention period
+func (s *Store) maintain() {
+	s.markStale()
+	s.db.Exec(`DELETE FROM jobs WHERE status != ? AND finished_at < ?`,
+		StatusRunning, time.Now().Add(-s.retention).UnixMilli())
+}
+
+// markStale marks running jobs of other stores whose heartbeat stopped as interrupted
+func (s *Store) markStale() {
+	now := time.Now()
+	s.db.Exec(`
+		UPDATE jobs SET status = ?, error_message = ?, finished_at = ?
+		WHERE status = ? AND instance != ? AND heartbeat_at < ?`,
+		StatusInterrupted, "the server running the job stopped", now.UnixMilli(),
+		StatusRunning, s.instance, now.Add(-staleHeartbeats*heartbeatInterval).UnixMilli())
+}
+
+// newID returns a random job identifier
+func newID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return fmt.Sprintf("%x", b)
+}
@@ -0,0 +1,97 @@
+package jobs
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStoreJobs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.db")
+	store, err := Open(path, time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+	defer store.Close()
+
+	job, err := store.Create("build", "ci")
+	if err != nil {
+		t.Fatalf("Failed to create job: %v", err)
+	}
+	got, err := store.Get(job.ID)
+	if err != nil || got.Status != StatusRunning || got.Command != "build" || got.Owner != "ci" || got.Finished() {
+		t.Fatalf("Expected running job, got %+v, %v", got, err)
+	}
+
+	if err := store.Finish(job.ID, StatusFailed, []byte(`{"Stdout":"done"}`), "exit status 2"); err != nil {
+		t.Fatalf("Failed to finish job: %v", err)
+	}
+	store.Close()
+
+	// Results survive reopening the database
+	store, err = Open(path, time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to reopen store: %v", err)
+	}
+	defer store.Close()
+	got, err = store.Get(job.ID)
+	if err != nil || got.Status != StatusFailed || got.Error != "exit status 2" || string(got.Result) != `{"Stdout":"done"}` || got.FinishedAt.IsZero() {
+		t.Errorf("Expected finished job, got %+v, %v", got, err)
+	}
+
+	if _, err := store.Get("nope"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected not found, got %v", err)
+	}
+}
+
+func TestStoreRetention(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.db")
+	store, err := Open(path, time.Millisecond)
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+	defer store.Close()
+
+	finished, _ := store.Create("build", "")
+	running, _ := store.Create("build", "")
+	store.Finish(finished.ID, StatusSucceeded, nil, "")
+	time.Sleep(5 * time.Millisecond)
+	store.maintain()
+
+	if _, err := store.Get(finished.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected finished job past retention to be deleted, got %v", err)
+	}
+	if _, err := store.Get(running.ID); err != nil {
+		t.Errorf("Expected running job to be kept, got %v", err)
+	}
+}
+
+func TestStoreInterruptedJobs(t *testing.T) {
+	interval := heartbeatInterval
+	heartbeatInterval = 10 * time.Millisecond
+	defer func() { heartbeatInterval = interval }()
+
+	path := filepath.Join(t.TempDir(), "jobs.db")
+	stopped, err := Open(path, time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+	job, _ := stopped.Create("build", "")
+	stopped.Close()
+
+	store, err := Open(path, time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+	defer store.Close()
+	alive, _ := store.Create("build", "")
+
+	time.Sleep(5 * staleHeartbeats * heartbeatInterval)
+	if got, err := store.Get(job.ID); err != nil || got.Status != StatusInterrupted {
+		t.Errorf("Expected job of a stopped server to be interrupted, got %+v, %v", got, err)
+	}
+	if got, err := store.Get(alive.ID); err != nil || got.Status != StatusRunning {
+		t.Errorf("Expected job with a heartbeat to keep running, got %+v, %v", got, err)
+	}
+}
@@ -0,0 +1,154 @@
+package runs
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"unicode/utf8"
+)
+
+// ErrNotFound is returned for unknown or expired runs and streams
+var ErrNotFound = errors.New("run output not found")
+
+// validName restricts run IDs and stream names to safe file names
+var validName = regexp.MustCompile(`^[a-z0-9]+$`)
+
+// Store keeps the full output of recent runs in a temporary directory.
+// Only the most recent maxRuns runs are kept; older ones are deleted.
+type Store struct {
+	mu      sync.Mutex
+	dir     string
+	maxRuns int
+	order   []string
+}
+
+// NewStore creates a store backed by a new temporary directory
+func NewStore(maxRuns int) (*Store, error) {
+	dir, err := os.MkdirTemp("", "mcpfier-runs-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create run store: %w", err)
+	}
+	return &Store{dir: dir, maxRuns: maxRuns}, nil
+}
+
+// Save stores the given streams (e.g. "stdout", "stderr") under a new run ID
+func (s *Store) Save(streams map[string]string) (string, error) {
+	id := newID()
+	runDir := filepath.Join(s.dir, id)
+	if err := os.Mkdir(runDir, 0700); err != nil {
+		return "", fmt.Errorf("failed to store run output: %w", err)
+	}
+	for name, data := range streams {
+		if !validName.MatchString(name) {
+			return "", fmt.Errorf("invalid stream name '%s'", name)
+		}
+		if err := os.WriteFile(filepath.Join(runDir, name), []byte(data), 0600); err != nil {
+			os.RemoveAll(runDir)
+			return "", fmt.Errorf("failed to store run output: %w", err)
+		}
+	}
+
+	s.mu.Lock()
+	s.order = append(s.order, id)
+	var expired []string
+	if s.maxRuns > 0 && len(s.order) > s.maxRuns {
+		expired = s.order[:len(s.order)-s.maxRuns]
+		s.order = append([]string(nil), s.order[len(s.order)-s.maxRuns:]...)
+	}
+	s.mu.Unlock()
+
+	for _, old := range expired {
+		os.RemoveAll(filepath.Join(s.dir, old))
+	}
+	return id, nil
+}
+
+// Page is one page of a stored stream
+type Page struct {
+	Text       string
+	Page       int // 1-based
+	Pages      int
+	TotalBytes int64
+}
+
+// ReadPage returns the given 1-based page of a stream. Page boundaries are moved
+// forward to the next UTF-8 character start so multi-byte characters are never split.
+func (s *Store) ReadPage(id, stream string, page, pageSize int) (*Page, error) {
+	if !validName.MatchString(id) || !validName.MatchString(stream) {
+		return nil, ErrNotFound
+	}
+	if page < 1 || pageSize < 1 {
+		return nil, fmt.Errorf("invalid page %d", page)
+	}
+
+	f, err := os.Open(filepath.Join(s.dir, id, stream))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	total := info.Size()
+	pages := int((total + int64(pageSize) - 1) / int64(pageSize))
+	if pages == 0 {
+		pages = 1
+	}
+	if page > pages {
+		return nil, fmt.Errorf("page %d out of range, run has %d pages", page, pages)
+	}
+
+	// Read a little past both boundaries so they can be aligned to character starts
+	start := int64(page-1) * int64(pageSize)
+	buf := make([]byte, pageSize+2*utf8.UTFMax)
+	n, err := f.ReadAt(buf, start)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	buf = buf[:n]
+
+	from := 0
+	if start > 0 {
+		from = alignRune(buf, 0)
+	}
+	to := len(buf)
+	if start+int64(pageSize) < total {
+		to = alignRune(buf, pageSize)
+	}
+
+	return &Page{
+		Text:       string(buf[from:to]),
+		Page:       page,
+		Pages:      pages,
+		TotalBytes: total,
+	}, nil
+}
+
+// Close removes all stored output
+func (s *Store) Close() error {
+	return os.RemoveAll(s.dir)
+}
+
+// alignRune moves i forward to the next UTF-8 character start in buf
+func alignRune(buf []byte, i int) int {
+	for limit := i + utf8.UTFMax; i < len(buf) && i < limit && !utf8.RuneStart(buf[i]); i++ {
+	}
+	return i
+}
+
+// newID returns a random run ID
+func newID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return fmt.Sprintf("%x", b)
+}
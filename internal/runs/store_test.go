@@ -0,0 +1,77 @@
+package runs
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestStorePages(t *testing.T) {
+	store, err := NewStore(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	// "é" is two bytes, so a 3-byte page size would split characters
+	output := strings.Repeat("é", 5)
+	id, err := store.Save(map[string]string{"stdout": output, "stderr": ""})
+	if err != nil {
+		t.Fatalf("Failed to save run: %v", err)
+	}
+
+	var pages []string
+	for page := 1; ; page++ {
+		p, err := store.ReadPage(id, "stdout", page, 3)
+		if err != nil {
+			t.Fatalf("Failed to read page %d: %v", page, err)
+		}
+		if p.TotalBytes != int64(len(output)) {
+			t.Errorf("Expected %d total bytes, got %d", len(output), p.TotalBytes)
+		}
+		pages = append(pages, p.Text)
+		if page == p.Pages {
+			break
+		}
+	}
+
+	if strings.Join(pages, "") != output {
+		t.Errorf("Expected pages to reassemble the output, got %q", pages)
+	}
+	for _, page := range pages {
+		if strings.ContainsRune(page, '�') {
+			t.Errorf("Page split a character: %q", page)
+		}
+	}
+
+	if _, err := store.ReadPage(id, "stdout", 99, 3); err == nil {
+		t.Error("Expected error for page out of range")
+	}
+	if _, err := store.ReadPage("../etc", "passwd", 1, 3); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected not found for invalid ID, got %v", err)
+	}
+}
+
+func TestStoreEvictsOldRuns(t *testing.T) {
+	store, err := NewStore(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	var ids []string
+	for i := 0; i < 3; i++ {
+		id, err := store.Save(map[string]string{"stdout": "out"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+
+	if _, err := store.ReadPage(ids[0], "stdout", 1, 10); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected oldest run to be evicted, got %v", err)
+	}
+	if _, err := store.ReadPage(ids[2], "stdout", 1, 10); err != nil {
+		t.Errorf("Expected newest run to be kept, got %v", err)
+	}
+}
@@ -0,0 +1,41 @@
+// Package sandbox runs commands in a Linux sandbox built from user, mount, PID
+// and network namespaces, a landlock filesystem allowlist, rlimits and a seccomp
+// profile. mcpfier re-executes itself to set the sandbox up, so main must call
+// Init before doing anything else.
+package sandbox
+
+import "errors"
+
+// ErrUnsupported is returned when the platform cannot provide the sandbox
+var ErrUnsupported = errors.New("sandbox is only supported on Linux")
+
+// Argv[0] values that mark a re-executed mcpfier as one of the sandbox stages
+const (
+	initStage = "mcpfier-sandbox-init" // PID 1 of the sandbox: sets up mounts and reaps processes
+	execStage = "mcpfier-sandbox-exec" // Applies rlimits, landlock and seccomp, then execs the command
+)
+
+// policyEnv carries the JSON-encoded Policy to the sandbox stages; it is removed
+// before the command runs
+const policyEnv = "MCPFIER_SANDBOX_POLICY"
+
+// Policy describes what a sandboxed command may do
+type Policy struct {
+	Read      []string `json:"read"`      // Paths readable and executable, in addition to SystemRead
+	Write     []string `json:"write"`     // Paths fully writable, in addition to SystemWrite
+	Network   bool     `json:"network"`   // Share the host network namespace
+	CPU       uint64   `json:"cpu"`       // RLIMIT_CPU in seconds, 0 for unlimited
+	Memory    uint64   `json:"memory"`    // RLIMIT_AS in bytes, 0 for unlimited
+	OpenFiles uint64   `json:"openFiles"` // RLIMIT_NOFILE, 0 for unlimited
+	Processes uint64   `json:"processes"` // RLIMIT_NPROC, 0 for unlimited
+}
+
+// SystemRead lists the paths every sandboxed command may read, so binaries,
+// shared libraries and basic configuration are available. Missing paths are skipped.
+var SystemRead = []string{
+	"/usr", "/bin", "/sbin", "/lib", "/lib32", "/lib64", "/etc", "/proc",
+	"/dev/null", "/dev/zero", "/dev/random", "/dev/urandom",
+}
+
+// SystemWrite lists the paths every sandboxed command may write
+var SystemWrite = []string{"/dev/null"}
@@ -0,0 +1,199 @@
+//go:build linux
+
+package sandbox
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// setupFailure is the exit status of a sandbox stage that could not set up the sandbox
+const setupFailure = 126
+
+// Command returns a command that runs path with args inside the sandbox. It
+// re-executes mcpfier in new namespaces; env is the command's environment, and
+// nil inherits mcpfier's.
+func Command(policy Policy, path string, args []string, env []string) (*exec.Cmd, error) {
+	data, err := json.Marshal(policy)
+	if err != nil {
+		return nil, err
+	}
+	if env == nil {
+		env = os.Environ()
+	}
+
+	flags := syscall.CLONE_NEWUSER | syscall.CLONE_NEWNS | syscall.CLONE_NEWPID |
+		syscall.CLONE_NEWIPC | syscall.CLONE_NEWUTS
+	if !policy.Network {
+		flags |= syscall.CLONE_NEWNET
+	}
+
+	// Keep the caller's uid and gid so file ownership checks behave as they do outside.
+	// A non-root uid has no capabilities in the new namespace after exec, so the
+	// stages are granted the ones they need to set up mounts; runExec drops them.
+	uid, gid := os.Getuid(), os.Getgid()
+	cmd := &exec.Cmd{
+		Path: "/proc/self/exe",
+		Args: append([]string{initStage, path}, args...),
+		Env:  append(append([]string(nil), env...), policyEnv+"="+string(data)),
+		SysProcAttr: &syscall.SysProcAttr{
+			Cloneflags:  uintptr(flags),
+			UidMappings: []syscall.SysProcIDMap{{ContainerID: uid, HostID: uid, Size: 1}},
+			GidMappings: []syscall.SysProcIDMap{{ContainerID: gid, HostID: gid, Size: 1}},
+			AmbientCaps: []uintptr{unix.CAP_SYS_ADMIN, unix.CAP_SETPCAP},
+		},
+	}
+	return cmd, nil
+}
+
+// Init runs the sandbox stages when mcpfier was re-executed by Command, and
+// returns immediately otherwise
+func Init() {
+	switch os.Args[0] {
+	case initStage:
+		os.Exit(runInit())
+	case execStage:
+		err := runExec()
+		fmt.Fprintf(os.Stderr, "mcpfier sandbox: %v\n", err)
+		os.Exit(setupFailure)
+	}
+}
+
+// runInit is PID 1 of the sandbox. It mounts a private /proc, starts the exec
+// stage and reaps processes until it exits; when PID 1 exits the kernel kills
+// everything left in the sandbox.
+func runInit() int {
+	// Keep mount changes inside the sandbox, and show only its own processes in /proc
+	if err := unix.Mount("", "/", "", unix.MS_REC|unix.MS_PRIVATE, ""); err != nil {
+		fmt.Fprintf(os.Stderr, "mcpfier sandbox: making mounts private: %v\n", err)
+		return setupFailure
+	}
+	if err := unix.Mount("proc", "/proc", "proc", unix.MS_NOSUID|unix.MS_NODEV|unix.MS_NOEXEC, ""); err != nil {
+		fmt.Fprintf(os.Stderr, "mcpfier sandbox: mounting /proc: %v\n", err)
+		return setupFailure
+	}
+
+	// Termination signals reach the command directly through its process group.
+	// Handle them here so they do not stop PID 1 before the command has exited.
+	signal.Notify(make(chan os.Signal, 1), syscall.SIGTERM, syscall.SIGINT, syscall.SIGHUP)
+
+	child, err := os.StartProcess("/proc/self/exe", append([]string{execStage}, os.Args[1:]...), &os.ProcAttr{
+		Env:   os.Environ(),
+		Files: []*os.File{os.Stdin, os.Stdout, os.Stderr},
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "mcpfier sandbox: %v\n", err)
+		return setupFailure
+	}
+
+	for {
+		var status unix.WaitStatus
+		pid, err := unix.Wait4(-1, &status, 0, nil)
+		if err == unix.EINTR {
+			continue
+		}
+		if err != nil {
+			return setupFailure
+		}
+		if pid == child.Pid {
+			if status.Signaled() {
+				return 128 + int(status.Signal())
+			}
+			return status.ExitStatus()
+		}
+	}
+}
+
+// runExec restricts itself and execs the command. It only returns on failure.
+func runExec() error {
+	// no_new_privs, capabilities and landlock apply to the calling thread, which must be the one that execs
+	runtime.LockOSThread()
+
+	var policy Policy
+	if err := json.Unmarshal([]byte(os.Getenv(policyEnv)), &policy); err != nil {
+		return fmt.Errorf("invalid policy: %w", err)
+	}
+	os.Unsetenv(policyEnv)
+	if len(os.Args) < 2 {
+		return fmt.Errorf("no command to run")
+	}
+
+	if err := dropCapabilities(); err != nil {
+		return err
+	}
+	if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+		return fmt.Errorf("setting no_new_privs: %w", err)
+	}
+	if err := restrictFilesystem(policy); err != nil {
+		return err
+	}
+	if err := installSeccomp(); err != nil {
+		return err
+	}
+	// Limits come last so setting up the sandbox is not subject to them
+	if err := setRlimits(policy); err != nil {
+		return err
+	}
+
+	path := os.Args[1]
+	return unix.Exec(path, append([]string{path}, os.Args[2:]...), os.Environ())
+}
+
+// dropCapabilities empties the bounding, ambient and current capability sets,
+// so the command runs without the capabilities used to set up the sandbox and
+// does not regain any when it is executed
+func dropCapabilities() error {
+	lastCap := unix.CAP_LAST_CAP
+	if data, err := os.ReadFile("/proc/sys/kernel/cap_last_cap"); err == nil {
+		if n, err := strconv.Atoi(strings.TrimSpace(string(data))); err == nil {
+			lastCap = n
+		}
+	}
+	for c := 0; c <= lastCap; c++ {
+		if err := unix.Prctl(unix.PR_CAPBSET_DROP, uintptr(c), 0, 0, 0); err != nil && err != unix.EINVAL {
+			return fmt.Errorf("dropping capability %d: %w", c, err)
+		}
+	}
+	if err := unix.Prctl(unix.PR_CAP_AMBIENT, unix.PR_CAP_AMBIENT_CLEAR_ALL, 0, 0, 0); err != nil {
+		return fmt.Errorf("clearing ambient capabilities: %w", err)
+	}
+	header := unix.CapUserHeader{Version: unix.LINUX_CAPABILITY_VERSION_3}
+	var data [2]unix.CapUserData
+	if err := unix.Capset(&header, &data[0]); err != nil {
+		return fmt.Errorf("dropping capabilities: %w", err)
+	}
+	return nil
+}
+
+// setRlimits applies the policy's resource limits
+func setRlimits(policy Policy) error {
+	limits := []struct {
+		resource int
+		value    uint64
+		name     string
+	}{
+		{unix.RLIMIT_CPU, policy.CPU, "cpu"},
+		{unix.RLIMIT_AS, policy.Memory, "memory"},
+		{unix.RLIMIT_NOFILE, policy.OpenFiles, "open_files"},
+		{unix.RLIMIT_NPROC, policy.Processes, "processes"},
+	}
+	for _, limit := range limits {
+		if limit.value == 0 {
+			continue
+		}
+		rlimit := unix.Rlimit{Cur: limit.value, Max: limit.value}
+		if err := unix.Setrlimit(limit.resource, &rlimit); err != nil {
+			return fmt.Errorf("setting %s limit: %w", limit.name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package sandbox
+
+import "os/exec"
+
+// Init is a no-op outside Linux
+func Init() {}
+
+// Command is not available outside Linux
+func Command(policy Policy, path string, args []string, env []string) (*exec.Cmd, error) {
+	return nil, ErrUnsupported
+}
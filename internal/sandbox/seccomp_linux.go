@@ -0,0 +1,91 @@
+//go:build linux
+
+package sandbox
+
+import (
+	"fmt"
+	"runtime"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// Offsets into struct seccomp_data
+const (
+	seccompNr   = 0
+	seccompArch = 4
+	seccompArg0 = 16 // Low 32 bits on little-endian architectures
+)
+
+// namespaceFlags are the clone flags that create namespaces
+const namespaceFlags = unix.CLONE_NEWNS | unix.CLONE_NEWUTS | unix.CLONE_NEWIPC | unix.CLONE_NEWUSER |
+	unix.CLONE_NEWPID | unix.CLONE_NEWNET | unix.CLONE_NEWCGROUP
+
+// installSeccomp blocks syscalls that reconfigure the kernel, escape the
+// namespaces or inspect other processes. Everything else is allowed.
+func installSeccomp() error {
+	if auditArch == 0 {
+		return fmt.Errorf("seccomp is not supported on %s", runtime.GOARCH)
+	}
+
+	filter := seccompFilter()
+	prog := unix.SockFprog{Len: uint16(len(filter)), Filter: &filter[0]}
+	// TSYNC applies the filter to every thread of the Go runtime
+	_, _, errno := unix.Syscall(unix.SYS_SECCOMP, unix.SECCOMP_SET_MODE_FILTER, unix.SECCOMP_FILTER_FLAG_TSYNC,
+		uintptr(unsafe.Pointer(&prog)))
+	if errno != 0 {
+		return fmt.Errorf("installing seccomp filter: %w", errno)
+	}
+	return nil
+}
+
+// seccompFilter builds the BPF program for installSeccomp
+func seccompFilter() []unix.SockFilter {
+	stmt := func(code uint16, k uint32) unix.SockFilter {
+		return unix.SockFilter{Code: code, K: k}
+	}
+	jump := func(code uint16, k uint32, jt, jf uint8) unix.SockFilter {
+		return unix.SockFilter{Code: code, Jt: jt, Jf: jf, K: k}
+	}
+	load := func(offset uint32) unix.SockFilter {
+		return stmt(unix.BPF_LD|unix.BPF_W|unix.BPF_ABS, offset)
+	}
+	ret := func(action uint32) unix.SockFilter {
+		return stmt(unix.BPF_RET|unix.BPF_K, action)
+	}
+	deny := func(errno unix.Errno) unix.SockFilter {
+		return ret(unix.SECCOMP_RET_ERRNO | uint32(errno))
+	}
+	jeq := unix.BPF_JMP | unix.BPF_JEQ | unix.BPF_K
+
+	// Syscall numbers differ between architectures, so anything else is killed
+	filter := []unix.SockFilter{
+		load(seccompArch),
+		jump(uint16(jeq), auditArch, 1, 0),
+		ret(unix.SECCOMP_RET_KILL_PROCESS),
+		load(seccompNr),
+	}
+	if syscallBit != 0 {
+		filter = append(filter,
+			jump(unix.BPF_JMP|unix.BPF_JGE|unix.BPF_K, syscallBit, 0, 1),
+			deny(unix.EPERM))
+	}
+	for _, nr := range deniedSyscalls {
+		filter = append(filter,
+			jump(uint16(jeq), uint32(nr), 0, 1),
+			deny(unix.EPERM))
+	}
+
+	// clone3 arguments cannot be inspected; libc falls back to clone on ENOSYS,
+	// whose flags are checked for new namespaces
+	filter = append(filter,
+		jump(uint16(jeq), unix.SYS_CLONE3, 0, 1),
+		deny(unix.ENOSYS),
+		jump(uint16(jeq), unix.SYS_CLONE, 0, 3),
+		load(seccompArg0),
+		jump(unix.BPF_JMP|unix.BPF_JSET|unix.BPF_K, namespaceFlags, 0, 1),
+		deny(unix.EPERM),
+		ret(unix.SECCOMP_RET_ALLOW),
+	)
+	return filter
+}
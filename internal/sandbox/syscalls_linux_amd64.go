@@ -0,0 +1,12 @@
+package sandbox
+
+import "golang.org/x/sys/unix"
+
+const auditArch = unix.AUDIT_ARCH_X86_64
+
+// syscallBit marks x32 syscalls, which are denied
+const syscallBit = 0x40000000
+
+var deniedSyscalls = append(commonDeniedSyscalls,
+	unix.SYS_IOPL, unix.SYS_IOPERM, unix.SYS_USELIB, unix.SYS__SYSCTL,
+)
@@ -0,0 +1,11 @@
+//go:build linux && !amd64 && !arm64
+
+package sandbox
+
+// The seccomp profile is only defined for amd64 and arm64; installSeccomp fails elsewhere
+const (
+	auditArch  = 0
+	syscallBit = 0
+)
+
+var deniedSyscalls []uintptr
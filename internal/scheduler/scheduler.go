@@ -0,0 +1,240 @@
+// Package scheduler runs commands on their cron schedules, independent of any
+// MCP client, and keeps the latest result of each.
+package scheduler
+
+import (
+	"context"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/gleicon/mcpfier/internal/config"
+	"github.com/gleicon/mcpfier/internal/executor"
+)
+
+// Session is the analytics session scheduled runs are recorded under
+const Session = "scheduled"
+
+// Runner executes commands; implemented by executor.Service
+type Runner interface {
+	Execute(ctx context.Context, cmd *config.Command, params map[string]interface{}) (*executor.Result, error)
+}
+
+// Run is the outcome of one scheduled run
+type Run struct {
+	Command   string
+	StartedAt time.Time
+	Result    *executor.Result // Nil if the command could not start
+	Err       error
+}
+
+// Scheduler runs the commands that have a schedule
+type Scheduler struct {
+	runner  Runner
+	entries []*entry
+	onRun   func(run *Run)
+
+	mu     sync.Mutex
+	latest map[string]*Run
+
+	// next returns when a command runs next; replaced in tests
+	next func(cmd *config.Command, now time.Time) time.Time
+
+	ctx      context.Context // Cancelled when Stop gives up waiting for running commands
+	cancel   context.CancelFunc
+	stop     chan struct{}
+	stopOnce sync.Once
+	loops    sync.WaitGroup
+	runs     sync.WaitGroup
+}
+
+// entry is a scheduled command and its running and queued runs
+type entry struct {
+	cmd *config.Command
+
+	mu      sync.Mutex
+	running int
+	queued  bool
+}
+
+// New creates a scheduler for the commands with a schedule. Nothing runs until Start.
+func New(commands []config.Command, runner Runner) *Scheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Scheduler{
+		runner: runner,
+		latest: make(map[string]*Run),
+		next:   nextRun,
+		ctx:    ctx,
+		cancel: cancel,
+		stop:   make(chan struct{}),
+	}
+	for i := range commands {
+		if commands[i].Schedule != nil {
+			s.entries = append(s.entries, &entry{cmd: &commands[i]})
+		}
+	}
+	return s
+}
+
+// Commands returns the scheduled commands
+func (s *Scheduler) Commands() []*config.Command {
+	commands := make([]*config.Command, len(s.entries))
+	for i, e := range s.entries {
+		commands[i] = e.cmd
+	}
+	return commands
+}
+
+// OnRun sets a function called after every scheduled run. It must be set before Start.
+func (s *Scheduler) OnRun(fn func(run *Run)) {
+	s.onRun = fn
+}
+
+// Start starts running the scheduled commands
+func (s *Scheduler) Start() {
+	for _, e := range s.entries {
+		s.loops.Add(1)
+		go s.loop(e)
+	}
+}
+
+// Latest returns the latest finished run of a command, or nil if it has not run yet
+func (s *Scheduler) Latest(name string) *Run {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.latest[name]
+}
+
+// Next returns when a scheduled command runs next, before jitter
+func (s *Scheduler) Next(cmd *config.Command) time.Time {
+	return s.next(cmd, time.Now())
+}
+
+// Stop stops scheduling runs and waits for running commands to finish. Commands
+// still running when ctx is done are cancelled, and Stop returns once they stopped.
+func (s *Scheduler) Stop(ctx context.Context) error {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+	})
+	s.loops.Wait()
+
+	done := make(chan struct{})
+	go func() {
+		s.runs.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		s.cancel()
+		return nil
+	case <-ctx.Done():
+		s.cancel()
+		<-done
+		return ctx.Err()
+	}
+}
+
+// loop waits for each activation of a command's schedule and fires it
+func (s *Scheduler) loop(e *entry) {
+	defer s.loops.Done()
+	for {
+		next := s.next(e.cmd, time.Now())
+		if next.IsZero() {
+			log.Printf("Schedule of command %s never fires", e.cmd.Name)
+			return
+		}
+		timer := time.NewTimer(time.Until(next) + jitter(e.cmd.Schedule.JitterDuration))
+		select {
+		case <-timer.C:
+			s.fire(e)
+		case <-s.stop:
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// fire starts a run unless the previous one is still running and the overlap
+// policy says otherwise
+func (s *Scheduler) fire(e *entry) {
+	e.mu.Lock()
+	if e.running > 0 {
+		switch e.cmd.Schedule.Overlap {
+		case config.OverlapSkip:
+			e.mu.Unlock()
+			log.Printf("Skipping scheduled run of %s: the previous run is still running", e.cmd.Name)
+			return
+		case config.OverlapQueue:
+			e.queued = true
+			e.mu.Unlock()
+			return
+		}
+	}
+	e.running++
+	e.mu.Unlock()
+	s.start(e)
+}
+
+// start runs a command in the background, then the run queued meanwhile if any
+func (s *Scheduler) start(e *entry) {
+	s.runs.Add(1)
+	go func() {
+		defer s.runs.Done()
+		s.run(e)
+
+		e.mu.Lock()
+		again := e.queued && !s.stopping()
+		e.queued = false
+		if !again {
+			e.running--
+		}
+		e.mu.Unlock()
+		if again {
+			s.start(e)
+		}
+	}()
+}
+
+// run executes a command with its scheduled params and records the outcome
+func (s *Scheduler) run(e *entry) {
+	run := &Run{Command: e.cmd.Name, StartedAt: time.Now()}
+	params, err := e.cmd.ResolveArguments(e.cmd.Schedule.Params)
+	if err == nil {
+		run.Result, err = s.runner.Execute(executor.WithSessionID(s.ctx, Session), e.cmd, params)
+	}
+	run.Err = err
+	if err != nil {
+		log.Printf("Scheduled run of %s failed: %v", e.cmd.Name, err)
+	}
+
+	s.mu.Lock()
+	s.latest[e.cmd.Name] = run
+	s.mu.Unlock()
+	if s.onRun != nil {
+		s.onRun(run)
+	}
+}
+
+// stopping returns true once Stop was called
+func (s *Scheduler) stopping() bool {
+	select {
+	case <-s.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// nextRun returns the next activation of a command's cron schedule in its time zone
+func nextRun(cmd *config.Command, now time.Time) time.Time {
+	return cmd.Schedule.CronSchedule.Next(now.In(cmd.Schedule.Location))
+}
+
+// jitter returns a random delay of up to max
+func jitter(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(max) + 1))
+}
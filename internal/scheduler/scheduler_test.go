@@ -0,0 +1,189 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gleicon/mcpfier/internal/analytics"
+	"github.com/gleicon/mcpfier/internal/config"
+	"github.com/gleicon/mcpfier/internal/executor"
+)
+
+// recordingAnalytics keeps the command events it receives
+type recordingAnalytics struct {
+	analytics.NoOpAnalytics
+	mu     sync.Mutex
+	events []analytics.CommandEvent
+}
+
+func (r *recordingAnalytics) RecordCommand(ctx context.Context, event analytics.CommandEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+// blockingRunner counts its calls and blocks them until released or cancelled
+type blockingRunner struct {
+	mu      sync.Mutex
+	started int
+	running int
+	peak    int
+	release chan struct{}
+}
+
+func (r *blockingRunner) Execute(ctx context.Context, cmd *config.Command, params map[string]interface{}) (*executor.Result, error) {
+	r.mu.Lock()
+	r.started++
+	r.running++
+	r.peak = max(r.peak, r.running)
+	r.mu.Unlock()
+	defer func() {
+		r.mu.Lock()
+		r.running--
+		r.mu.Unlock()
+	}()
+
+	select {
+	case <-r.release:
+		return &executor.Result{Stdout: "done"}, nil
+	case <-ctx.Done():
+		return &executor.Result{ExitCode: -1}, executor.ErrCancelled
+	}
+}
+
+func (r *blockingRunner) counts() (started, running, peak int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.started, r.running, r.peak
+}
+
+// waitFor polls cond until it holds or the test times out
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestSchedulerRuns(t *testing.T) {
+	recorder := &recordingAnalytics{}
+	commands := []config.Command{
+		{Name: "unscheduled", Script: "echo"},
+		{
+			Name:       "greet",
+			Script:     "echo",
+			Args:       []string{"hello {{.params.name}}"},
+			Parameters: []config.Parameter{{Name: "name", Type: config.ParamString}},
+			Schedule:   &config.ScheduleConfig{Overlap: config.OverlapSkip, Params: map[string]interface{}{"name": "cron"}},
+		},
+	}
+	s := New(commands, executor.New().WithAnalytics(recorder))
+	s.next = func(cmd *config.Command, now time.Time) time.Time {
+		return now.Add(10 * time.Millisecond)
+	}
+	if got := s.Commands(); len(got) != 1 || got[0].Name != "greet" {
+		t.Fatalf("Expected only greet to be scheduled, got %v", got)
+	}
+
+	runs := make(chan *Run, 10)
+	s.OnRun(func(run *Run) { runs <- run })
+	s.Start()
+	var run *Run
+	select {
+	case run = <-runs:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Scheduled command did not run")
+	}
+	if err := s.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop failed: %v", err)
+	}
+
+	if run.Err != nil || run.Result.Stdout != "hello cron\n" {
+		t.Fatalf("Expected greeting, got %+v", run)
+	}
+	if latest := s.Latest("greet"); latest == nil || latest.Result.Stdout != "hello cron\n" {
+		t.Errorf("Expected latest run to be recorded, got %+v", latest)
+	}
+	if s.Latest("unscheduled") != nil {
+		t.Errorf("Expected no run for unscheduled command")
+	}
+
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	if len(recorder.events) == 0 || recorder.events[0].SessionID != Session {
+		t.Errorf("Expected runs recorded in the %s session, got %+v", Session, recorder.events)
+	}
+}
+
+func TestSchedulerOverlap(t *testing.T) {
+	tests := []struct {
+		overlap     string
+		wantStarted int
+		wantPeak    int
+	}{
+		{config.OverlapSkip, 1, 1},
+		{config.OverlapQueue, 2, 1},
+		{config.OverlapAllow, 3, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.overlap, func(t *testing.T) {
+			runner := &blockingRunner{release: make(chan struct{})}
+			commands := []config.Command{{Name: "slow", Schedule: &config.ScheduleConfig{Overlap: tt.overlap}}}
+			s := New(commands, runner)
+			e := s.entries[0]
+
+			// Three activations while the first run blocks
+			s.fire(e)
+			waitFor(t, "first run", func() bool { started, _, _ := runner.counts(); return started == 1 })
+			s.fire(e)
+			s.fire(e)
+			waitFor(t, "overlapping runs", func() bool { started, _, _ := runner.counts(); return started == tt.wantPeak })
+			close(runner.release)
+			waitFor(t, "runs to finish", func() bool {
+				started, running, _ := runner.counts()
+				return started == tt.wantStarted && running == 0
+			})
+			if err := s.Stop(context.Background()); err != nil {
+				t.Fatalf("Stop failed: %v", err)
+			}
+
+			started, _, peak := runner.counts()
+			if started != tt.wantStarted || peak != tt.wantPeak {
+				t.Errorf("Expected %d runs with at most %d at once, got %d and %d", tt.wantStarted, tt.wantPeak, started, peak)
+			}
+		})
+	}
+}
+
+func TestSchedulerStopCancelsRuns(t *testing.T) {
+	runner := &blockingRunner{release: make(chan struct{})}
+	commands := []config.Command{{Name: "stuck", Schedule: &config.ScheduleConfig{Overlap: config.OverlapQueue}}}
+	s := New(commands, runner)
+	runs := make(chan *Run, 10)
+	s.OnRun(func(run *Run) { runs <- run })
+
+	s.fire(s.entries[0])
+	waitFor(t, "run to start", func() bool { started, _, _ := runner.counts(); return started == 1 })
+	// Queued runs do not start once the scheduler stops
+	s.fire(s.entries[0])
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := s.Stop(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected Stop to give up waiting, got %v", err)
+	}
+	run := <-runs
+	if !errors.Is(run.Err, executor.ErrCancelled) {
+		t.Errorf("Expected the run to be cancelled, got %v", run.Err)
+	}
+	if started, running, _ := runner.counts(); started != 1 || running != 0 {
+		t.Errorf("Expected one stopped run, got %d started and %d running", started, running)
+	}
+}
@@ -0,0 +1,123 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/gleicon/mcpfier/internal/auth"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// requestIDMetaKey is where the JSON-RPC request ID is stashed in the tool call's _meta,
+// since mcp-go doesn't pass it to tool handlers
+const requestIDMetaKey = "mcpfier/requestId"
+
+// cancellations tracks running tool calls so notifications/cancelled can stop them
+type cancellations struct {
+	mu        sync.Mutex
+	running   map[string][]*runningCall
+	stateless bool // Sessions are not tracked, so calls are scoped to the authenticated user
+}
+
+// runningCall is a tracked tool call. Calls with the same key are told apart by identity.
+type runningCall struct {
+	cancel context.CancelFunc
+}
+
+// newCancellations creates a tracker and registers its hooks and notification handler.
+// On stateless HTTP the session ID is whatever the client sends, so only calls from
+// authenticated users are tracked, and only their own notifications cancel them.
+func newCancellations(hooks *server.Hooks, stateless bool) *cancellations {
+	c := &cancellations{running: make(map[string][]*runningCall), stateless: stateless}
+	hooks.AddBeforeCallTool(c.tagRequest)
+	return c
+}
+
+// register installs the notifications/cancelled handler on an MCP server
+func (c *cancellations) register(mcpServer *server.MCPServer) {
+	mcpServer.AddNotificationHandler("notifications/cancelled", c.handleCancelled)
+}
+
+// tagRequest records the request ID on the tool call so the handler can find it
+func (c *cancellations) tagRequest(ctx context.Context, id any, request *mcp.CallToolRequest) {
+	if request.Params.Meta == nil {
+		request.Params.Meta = &mcp.Meta{}
+	}
+	if request.Params.Meta.AdditionalFields == nil {
+		request.Params.Meta.AdditionalFields = make(map[string]any)
+	}
+	request.Params.Meta.AdditionalFields[requestIDMetaKey] = id
+}
+
+// track returns a context that is cancelled when the client cancels the request.
+// The returned function must be called when the tool call finishes.
+func (c *cancellations) track(ctx context.Context, request mcp.CallToolRequest) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	if request.Params.Meta == nil {
+		return ctx, cancel
+	}
+	key, ok := c.requestKey(ctx, request.Params.Meta.AdditionalFields[requestIDMetaKey])
+	if !ok {
+		return ctx, cancel
+	}
+
+	call := &runningCall{cancel: cancel}
+	c.mu.Lock()
+	c.running[key] = append(c.running[key], call)
+	c.mu.Unlock()
+
+	return ctx, func() {
+		c.mu.Lock()
+		calls := slices.DeleteFunc(c.running[key], func(other *runningCall) bool { return other == call })
+		if len(calls) == 0 {
+			delete(c.running, key)
+		} else {
+			c.running[key] = calls
+		}
+		c.mu.Unlock()
+		cancel()
+	}
+}
+
+// handleCancelled cancels the running tool calls named by a notifications/cancelled message
+func (c *cancellations) handleCancelled(ctx context.Context, notification mcp.JSONRPCNotification) {
+	key, ok := c.requestKey(ctx, notification.Params.AdditionalFields["requestId"])
+	if !ok {
+		return
+	}
+
+	c.mu.Lock()
+	calls := slices.Clone(c.running[key])
+	c.mu.Unlock()
+
+	for _, call := range calls {
+		call.cancel()
+	}
+}
+
+// requestKey scopes a request ID to the client session it belongs to or, on stateless
+// HTTP, to the authenticated user. The ID's type is part of the key, so 1 and "1" differ.
+// It returns false if the call cannot be told apart from other clients' calls.
+func (c *cancellations) requestKey(ctx context.Context, id any) (string, bool) {
+	requestID := mcp.NewRequestId(id)
+	if requestID.IsNil() {
+		return "", false
+	}
+
+	if c.stateless {
+		authCtx, ok := auth.AuthContextFromRequest(ctx)
+		if !ok || authCtx.UserID == "" {
+			return "", false
+		}
+		return fmt.Sprintf("user %s/%s", authCtx.UserID, requestID), true
+	}
+
+	sessionID := ""
+	if session := server.ClientSessionFromContext(ctx); session != nil {
+		sessionID = session.SessionID()
+	}
+	return fmt.Sprintf("session %s/%s", sessionID, requestID), true
+}
@@ -0,0 +1,165 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/gleicon/mcpfier/internal/auth"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// otherSession is a second client session with its own ID
+type otherSession struct {
+	*testSession
+}
+
+func (s otherSession) SessionID() string { return "other" }
+
+// heldCall is a running tool call that waits until it is released
+type heldCall struct {
+	ctx      context.Context
+	release  chan struct{}
+	finished chan struct{}
+}
+
+// newCancelServer returns an MCP server with a wait tool that blocks until released,
+// and a function that starts a call to it with a JSON-RPC ID
+func newCancelServer(stateless bool) (*server.MCPServer, func(ctx context.Context, id string) *heldCall) {
+	hooks := &server.Hooks{}
+	cancels := newCancellations(hooks, stateless)
+	mcpServer := server.NewMCPServer("test", "1.0.0", server.WithToolCapabilities(true), server.WithHooks(hooks))
+	cancels.register(mcpServer)
+
+	held := make(chan *heldCall)
+	mcpServer.AddTool(mcp.NewTool("wait"), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		ctx, done := cancels.track(ctx, request)
+		defer done()
+		call := &heldCall{ctx: ctx, release: make(chan struct{})}
+		held <- call
+		<-call.release
+		return mcp.NewToolResultText("done"), nil
+	})
+
+	start := func(ctx context.Context, id string) *heldCall {
+		finished := make(chan struct{})
+		go func() {
+			defer close(finished)
+			mcpServer.HandleMessage(ctx, json.RawMessage(fmt.Sprintf(`{"jsonrpc":"2.0","id":%s,"method":"tools/call","params":{"name":"wait"}}`, id)))
+		}()
+		call := <-held
+		call.finished = finished
+		return call
+	}
+	return mcpServer, start
+}
+
+// cancelRequest sends notifications/cancelled for a JSON-RPC ID
+func cancelRequest(ctx context.Context, mcpServer *server.MCPServer, id string) {
+	mcpServer.HandleMessage(ctx, json.RawMessage(fmt.Sprintf(`{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":%s}}`, id)))
+}
+
+func TestCancellations(t *testing.T) {
+	user := func(id string) context.Context {
+		return auth.WithAuthContext(context.Background(), &auth.AuthContext{UserID: id, Permissions: []string{"*"}})
+	}
+	session, other := newTestSession(), otherSession{newTestSession()}
+
+	tests := []struct {
+		name       string
+		stateless  bool
+		callCtx    func(*server.MCPServer) context.Context
+		callID     string
+		cancelCtx  func(*server.MCPServer) context.Context
+		cancelID   string
+		wantCancel bool
+	}{
+		{
+			name:       "same session",
+			callCtx:    func(s *server.MCPServer) context.Context { return s.WithContext(context.Background(), session) },
+			callID:     "1",
+			cancelCtx:  func(s *server.MCPServer) context.Context { return s.WithContext(context.Background(), session) },
+			cancelID:   "1",
+			wantCancel: true,
+		},
+		{
+			name:      "other session",
+			callCtx:   func(s *server.MCPServer) context.Context { return s.WithContext(context.Background(), session) },
+			callID:    "1",
+			cancelCtx: func(s *server.MCPServer) context.Context { return s.WithContext(context.Background(), other) },
+			cancelID:  "1",
+		},
+		{
+			name:       "string IDs",
+			callCtx:    func(s *server.MCPServer) context.Context { return s.WithContext(context.Background(), session) },
+			callID:     `"job-1"`,
+			cancelCtx:  func(s *server.MCPServer) context.Context { return s.WithContext(context.Background(), session) },
+			cancelID:   `"job-1"`,
+			wantCancel: true,
+		},
+		{
+			name:      "a string ID does not match a number",
+			callCtx:   func(s *server.MCPServer) context.Context { return s.WithContext(context.Background(), session) },
+			callID:    "1",
+			cancelCtx: func(s *server.MCPServer) context.Context { return s.WithContext(context.Background(), session) },
+			cancelID:  `"1"`,
+		},
+		{
+			name:       "stateless HTTP, same user",
+			stateless:  true,
+			callCtx:    func(*server.MCPServer) context.Context { return user("alice") },
+			callID:     "1",
+			cancelCtx:  func(*server.MCPServer) context.Context { return user("alice") },
+			cancelID:   "1",
+			wantCancel: true,
+		},
+		{
+			name:      "stateless HTTP, other user",
+			stateless: true,
+			callCtx:   func(*server.MCPServer) context.Context { return user("alice") },
+			callID:    "1",
+			cancelCtx: func(*server.MCPServer) context.Context { return user("bob") },
+			cancelID:  "1",
+		},
+		{
+			name:      "stateless HTTP without authentication",
+			stateless: true,
+			callCtx:   func(*server.MCPServer) context.Context { return context.Background() },
+			callID:    "1",
+			cancelCtx: func(*server.MCPServer) context.Context { return context.Background() },
+			cancelID:  "1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mcpServer, start := newCancelServer(tt.stateless)
+			call := start(tt.callCtx(mcpServer), tt.callID)
+			cancelRequest(tt.cancelCtx(mcpServer), mcpServer, tt.cancelID)
+			if cancelled := call.ctx.Err() != nil; cancelled != tt.wantCancel {
+				t.Errorf("Expected cancelled=%v, got %v", tt.wantCancel, cancelled)
+			}
+			close(call.release)
+			<-call.finished
+		})
+	}
+}
+
+func TestCancellationsSameID(t *testing.T) {
+	mcpServer, start := newCancelServer(true)
+	ctx := auth.WithAuthContext(context.Background(), &auth.AuthContext{UserID: "alice", Permissions: []string{"*"}})
+
+	// A call that finishes does not stop tracking another call with the same ID
+	first := start(ctx, "1")
+	second := start(ctx, "1")
+	close(first.release)
+	<-first.finished
+
+	cancelRequest(ctx, mcpServer, "1")
+	if second.ctx.Err() == nil {
+		t.Error("Expected the remaining call to be cancelled")
+	}
+	close(second.release)
+	<-second.finished
+}
@@ -2,10 +2,14 @@ package server
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/gleicon/mcpfier/internal/analytics"
@@ -23,6 +27,11 @@ type HTTPServer struct {
 	httpServer   *server.StreamableHTTPServer
 	executor     *executor.Service
 	analytics    analytics.Analytics
+
+	cancellations *cancellations
+	output        *outputLimiter
+	jobs          *jobRunner
+	schedules     *schedules
 }
 
 // NewHTTP creates a new HTTP MCP server instance
@@ -40,21 +49,35 @@ func NewHTTP(cfg *config.Config) *HTTPServer {
 		}
 	}
 	
-	executorService := executor.New().WithAnalytics(analyticsService)
+	executorService := executor.NewFromConfig(cfg, analyticsService, true)
 	
 	// Create MCP server
+	hooks := &server.Hooks{}
+	cancels := newCancellations(hooks, true)
 	mcpServer := server.NewMCPServer(
 		"mcpfier",
 		"1.0.0",
 		server.WithToolCapabilities(true),
+		server.WithLogging(),
+		server.WithHooks(hooks),
 	)
+	cancels.register(mcpServer)
+	output := newOutputLimiter(cfg.Output)
+	output.register(mcpServer)
+	// Stateless HTTP has no session to notify, so schedule resources are read-only
+	schedules := newSchedules(cfg, executorService, cfg.Server.HTTP.Auth.Enabled, nil)
+	schedules.register(mcpServer)
 	
 	// Create HTTP server instance
 	httpSrv := &HTTPServer{
-		config:    cfg,
-		mcpServer: mcpServer,
-		executor:  executorService,
-		analytics: analyticsService,
+		config:        cfg,
+		mcpServer:     mcpServer,
+		executor:      executorService,
+		analytics:     analyticsService,
+		cancellations: cancels,
+		output:        output,
+		jobs:          newJobRunner(cfg, executorService, output, cfg.Server.HTTP.Auth.Enabled),
+		schedules:     schedules,
 	}
 	
 	// Register tools
@@ -70,23 +93,28 @@ func NewHTTP(cfg *config.Config) *HTTPServer {
 	return httpSrv
 }
 
-// registerTools registers all configured commands as MCP tools
+// registerTools registers all configured commands as MCP tools, and the job tools if any command is async
 func (s *HTTPServer) registerTools() {
 	for _, cmd := range s.config.Commands {
 		cmdCopy := cmd // Capture loop variable
 		s.mcpServer.AddTool(
-			mcp.NewTool(cmdCopy.Name,
-				mcp.WithDescription(cmdCopy.GetDescription()),
-			),
+			newTool(cmdCopy),
 			func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-				return s.executeCommand(ctx, cmdCopy.Name)
+				// Cancelled by notifications/cancelled or when the HTTP connection drops
+				ctx, done := s.cancellations.track(ctx, request)
+				defer done()
+				ctx = withOutputStreaming(ctx, s.mcpServer, &cmdCopy, request)
+				return s.executeCommand(ctx, &cmdCopy, request.GetArguments())
 			},
 		)
 	}
+	s.jobs.register(s.mcpServer)
 }
 
-// executeCommand executes a command with authentication checks
-func (s *HTTPServer) executeCommand(ctx context.Context, commandName string) (*mcp.CallToolResult, error) {
+// executeCommand executes a command with authentication checks and argument validation
+func (s *HTTPServer) executeCommand(ctx context.Context, cmd *config.Command, arguments map[string]any) (*mcp.CallToolResult, error) {
+	commandName := cmd.Name
+
 	// Check authentication and permissions
 	authCtx, hasAuth := auth.AuthContextFromRequest(ctx)
 	if s.config.Server.HTTP.Auth.Enabled {
@@ -116,28 +144,28 @@ func (s *HTTPServer) executeCommand(ctx context.Context, commandName string) (*m
 		}
 	}
 	
-	// Execute the command
-	output, err := s.executor.ExecuteByName(ctx, s.config, commandName)
+	// Validate arguments against the declared parameters
+	params, err := cmd.ResolveArguments(arguments)
 	if err != nil {
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{
 				mcp.TextContent{
 					Type: "text",
-					Text: fmt.Sprintf("Command execution failed: %v\nOutput: %s", err, output),
+					Text: fmt.Sprintf("Invalid arguments: %v", err),
 				},
 			},
 			IsError: true,
 		}, nil
 	}
 
-	return &mcp.CallToolResult{
-		Content: []mcp.Content{
-			mcp.TextContent{
-				Type: "text",
-				Text: output,
-			},
-		},
-	}, nil
+	// Async commands return a job ID and run in the background
+	if cmd.Async {
+		return s.jobs.start(ctx, cmd, params), nil
+	}
+
+	// Execute the command
+	result, err := s.executor.Execute(ctx, cmd, params)
+	return s.output.toolResult(cmd, result, err), nil
 }
 
 // Start starts the HTTP MCP server
@@ -201,7 +229,22 @@ func (s *HTTPServer) Start() error {
 	analyticsHandler := s.analyticsMiddleware(mux)
 	handler := LoggingMiddleware()(analyticsHandler)
 	
-	return http.ListenAndServe(addr, handler)
+	// Shut down gracefully on SIGINT/SIGTERM so Close can drain container pools
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	srv := &http.Server{Addr: addr, Handler: handler}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+
+	if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
 }
 
 // analyticsMiddleware creates middleware for recording HTTP analytics
@@ -395,6 +438,7 @@ func (s *HTTPServer) analyticsWeb(w http.ResponseWriter, r *http.Request) {
                             <th class="px-4 py-2 text-left text-xs font-medium text-gray-500 uppercase">Uses</th>
                             <th class="px-4 py-2 text-left text-xs font-medium text-gray-500 uppercase">Success Rate</th>
                             <th class="px-4 py-2 text-left text-xs font-medium text-gray-500 uppercase">Avg Duration</th>
+                            <th class="px-4 py-2 text-left text-xs font-medium text-gray-500 uppercase">Fallbacks</th>
                         </tr>
                     </thead>
                     <tbody class="divide-y divide-gray-200">`,
@@ -416,8 +460,9 @@ func (s *HTTPServer) analyticsWeb(w http.ResponseWriter, r *http.Request) {
                             <td class="px-4 py-2 text-sm text-gray-500">%d</td>
                             <td class="px-4 py-2 text-sm text-green-600">%.1f%%</td>
                             <td class="px-4 py-2 text-sm text-gray-500">%dms</td>
+                            <td class="px-4 py-2 text-sm text-gray-500">%d</td>
                         </tr>`,
-			cmd.Name, cmd.Count, cmd.SuccessRate, cmd.AvgDuration,
+			cmd.Name, cmd.Count, cmd.SuccessRate, cmd.AvgDuration, cmd.Fallbacks,
 		)
 	}
 	
@@ -475,9 +520,28 @@ func (s *HTTPServer) analyticsWeb(w http.ResponseWriter, r *http.Request) {
 
 // healthCheck provides a health check endpoint
 func (s *HTTPServer) healthCheck(w http.ResponseWriter, r *http.Request) {
+	health := map[string]interface{}{
+		"status":  "healthy",
+		"version": "1.0.0",
+		"server":  "mcpfier",
+	}
+
+	// Report warm container pools; a pool that cannot start containers degrades
+	// health, and probes see it as unavailable
+	status := http.StatusOK
+	if pools := s.executor.PoolStatus(); len(pools) > 0 {
+		health["pools"] = pools
+		for _, pool := range pools {
+			if !pool.Healthy {
+				health["status"] = "degraded"
+				status = http.StatusServiceUnavailable
+			}
+		}
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	fmt.Fprintf(w, `{"status":"healthy","version":"1.0.0","server":"mcpfier"}`)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(health)
 }
 
 // applyCORSHeaders applies CORS headers to the response
@@ -518,8 +582,12 @@ func (s *HTTPServer) applyCORSHeaders(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// Close closes the server and analytics
+// Close stops the scheduler, cancels running jobs, drains container pools, closes database connections, removes stored run output and closes analytics
 func (s *HTTPServer) Close() error {
+	s.schedules.Close()
+	s.jobs.Close()
+	s.executor.Close()
+	s.output.Close()
 	return s.analytics.Close()
 }
 
@@ -0,0 +1,59 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gleicon/mcpfier/internal/config"
+	"github.com/gleicon/mcpfier/internal/executor"
+)
+
+func TestHealthCheck(t *testing.T) {
+	check := func(s *HTTPServer) (int, string) {
+		recorder := httptest.NewRecorder()
+		s.healthCheck(recorder, httptest.NewRequest(http.MethodGet, "/health", nil))
+		var health struct {
+			Status string `json:"status"`
+		}
+		json.NewDecoder(recorder.Body).Decode(&health)
+		return recorder.Code, health.Status
+	}
+
+	healthy := &HTTPServer{executor: executor.New()}
+	if code, status := check(healthy); code != http.StatusOK || status != "healthy" {
+		t.Errorf("Expected 200 healthy without pools, got %d %s", code, status)
+	}
+
+	// A pool whose runtime cannot start containers degrades health
+	service := executor.New().WithContainerRuntime(executor.NewCLIRuntime("mcpfier-no-such-runtime"))
+	commands := []config.Command{{
+		Name:      "pooled",
+		Container: "alpine",
+		Pool: &config.PoolConfig{
+			Size:                1,
+			MaxUses:             config.DefaultPoolMaxUses,
+			Keepalive:           config.DefaultPoolKeepalive,
+			IdleTimeoutDuration: config.DefaultPoolIdleTimeout,
+		},
+	}}
+	if err := service.StartPools(commands); err != nil {
+		t.Fatalf("Failed to start pools: %v", err)
+	}
+	defer service.Close()
+	degraded := &HTTPServer{executor: service}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		code, status := check(degraded)
+		if code == http.StatusServiceUnavailable && status == "degraded" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected 503 degraded for a failing pool, got %d %s", code, status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,315 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gleicon/mcpfier/internal/auth"
+	"github.com/gleicon/mcpfier/internal/config"
+	"github.com/gleicon/mcpfier/internal/executor"
+	"github.com/gleicon/mcpfier/internal/jobs"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// jobRunner runs the calls of async commands in the background, keeps their
+// results in the job store and serves the job_status, job_result and
+// job_cancel tools
+type jobRunner struct {
+	store       *jobs.Store // nil without async commands, or if the store could not be opened
+	executor    *executor.Service
+	output      *outputLimiter
+	commands    map[string]*config.Command
+	authEnabled bool // Jobs are only visible to callers allowed to run their command
+
+	mu      sync.Mutex
+	running map[string]context.CancelFunc // Jobs running in this server by ID
+	closing bool                          // Jobs cancelled from now on were interrupted by shutdown
+	wg      sync.WaitGroup
+}
+
+// newJobRunner opens the job store when any command is async
+func newJobRunner(cfg *config.Config, executorService *executor.Service, output *outputLimiter, authEnabled bool) *jobRunner {
+	j := &jobRunner{
+		executor:    executorService,
+		output:      output,
+		commands:    make(map[string]*config.Command),
+		authEnabled: authEnabled,
+		running:     make(map[string]context.CancelFunc),
+	}
+	if !cfg.HasAsync() {
+		return j
+	}
+	for i := range cfg.Commands {
+		j.commands[cfg.Commands[i].Name] = &cfg.Commands[i]
+	}
+	store, err := jobs.Open(cfg.Jobs.DatabasePath, cfg.Jobs.RetentionDuration)
+	if err != nil {
+		log.Printf("Job store unavailable, async commands will fail: %v", err)
+		return j
+	}
+	j.store = store
+	return j
+}
+
+// register adds the job tools when any command is async
+func (j *jobRunner) register(mcpServer *server.MCPServer) {
+	if len(j.commands) == 0 {
+		return
+	}
+	descriptions := map[string]string{
+		"job_status": "Report the status of a background job started by an async tool",
+		"job_result": "Return the output of a finished background job",
+		"job_cancel": "Cancel a running background job",
+	}
+	handlers := map[string]server.ToolHandlerFunc{
+		"job_status": j.handleStatus,
+		"job_result": j.handleResult,
+		"job_cancel": j.handleCancel,
+	}
+	for _, name := range config.JobTools {
+		mcpServer.AddTool(
+			mcp.NewTool(name,
+				mcp.WithDescription(descriptions[name]),
+				mcp.WithString("job_id", mcp.Required(), mcp.Description("Job ID returned by the async tool")),
+			),
+			handlers[name],
+		)
+	}
+}
+
+// start runs a validated call of an async command as a job and returns its ID
+func (j *jobRunner) start(ctx context.Context, cmd *config.Command, params map[string]interface{}) *mcp.CallToolResult {
+	if j.store == nil {
+		return jobError("Async jobs are unavailable: the job store could not be opened")
+	}
+	job, err := j.store.Create(cmd.Name, jobOwner(ctx))
+	if err != nil {
+		return jobError("Failed to start job: %v", err)
+	}
+
+	// The job outlives the call, and its output is not streamed since nobody waits for it
+	jobCtx, cancel := context.WithCancel(executor.WithOutput(context.WithoutCancel(ctx), nil))
+	j.mu.Lock()
+	j.running[job.ID] = cancel
+	j.mu.Unlock()
+
+	j.wg.Add(1)
+	go func() {
+		defer j.wg.Done()
+		result, err := j.executor.Execute(jobCtx, cmd, params)
+		j.finish(job.ID, result, err)
+
+		j.mu.Lock()
+		delete(j.running, job.ID)
+		j.mu.Unlock()
+		cancel()
+	}()
+
+	status := map[string]any{"jobId": job.ID, "status": job.Status}
+	return &mcp.CallToolResult{
+		Result: mcp.Result{Meta: mcp.NewMetaFromMap(status)},
+		Content: []mcp.Content{
+			mcp.TextContent{
+				Type: "text",
+				Text: fmt.Sprintf("Started job %s. Check on it with job_status and read its output with job_result.", job.ID),
+			},
+		},
+		StructuredContent: status,
+	}
+}
+
+// finish records the outcome of a job
+func (j *jobRunner) finish(id string, result *executor.Result, err error) {
+	j.mu.Lock()
+	closing := j.closing
+	j.mu.Unlock()
+
+	status := jobs.StatusSucceeded
+	switch {
+	case errors.Is(err, executor.ErrCancelled) && closing:
+		status = jobs.StatusInterrupted
+		err = errors.New("the server stopped while the job ran")
+	case errors.Is(err, executor.ErrCancelled):
+		status = jobs.StatusCancelled
+	case err != nil:
+		status = jobs.StatusFailed
+	}
+
+	encoded, encodeErr := json.Marshal(result)
+	if encodeErr != nil {
+		status = jobs.StatusFailed
+		err = fmt.Errorf("failed to encode result: %w", encodeErr)
+		encoded = nil
+	}
+	errMessage := ""
+	if err != nil {
+		errMessage = err.Error()
+	}
+	if err := j.store.Finish(id, status, encoded, errMessage); err != nil {
+		log.Printf("Job %s: %v", id, err)
+	}
+}
+
+// handleStatus serves job_status
+func (j *jobRunner) handleStatus(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	job, failure := j.lookup(ctx, request)
+	if failure != nil {
+		return failure, nil
+	}
+
+	status := map[string]any{
+		"jobId":     job.ID,
+		"command":   job.Command,
+		"status":    job.Status,
+		"createdAt": job.CreatedAt.UTC().Format(time.RFC3339),
+	}
+	if job.Finished() {
+		status["finishedAt"] = job.FinishedAt.UTC().Format(time.RFC3339)
+		if job.Error != "" {
+			status["error"] = job.Error
+		}
+		var result executor.Result
+		if json.Unmarshal(job.Result, &result) == nil {
+			status["exitCode"] = result.ExitCode
+			status["durationMs"] = result.Duration.Milliseconds()
+		}
+	}
+
+	text, _ := json.MarshalIndent(status, "", "  ")
+	return &mcp.CallToolResult{
+		Content:           []mcp.Content{mcp.TextContent{Type: "text", Text: string(text)}},
+		StructuredContent: status,
+	}, nil
+}
+
+// handleResult serves job_result: the tool result the command would have
+// returned had it run synchronously
+func (j *jobRunner) handleResult(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	job, failure := j.lookup(ctx, request)
+	if failure != nil {
+		return failure, nil
+	}
+	if !job.Finished() {
+		return &mcp.CallToolResult{
+			Result: mcp.Result{Meta: mcp.NewMetaFromMap(map[string]any{"jobId": job.ID, "status": job.Status})},
+			Content: []mcp.Content{
+				mcp.TextContent{
+					Type: "text",
+					Text: fmt.Sprintf("Job %s is still running", job.ID),
+				},
+			},
+		}, nil
+	}
+	if len(job.Result) == 0 {
+		return jobError("Job %s %s without a result: %s", job.ID, job.Status, job.Error), nil
+	}
+
+	var result executor.Result
+	if err := json.Unmarshal(job.Result, &result); err != nil {
+		return jobError("Job %s has an unreadable result: %v", job.ID, err), nil
+	}
+	var err error
+	if job.Error != "" {
+		err = errors.New(job.Error)
+	}
+	cmd, ok := j.commands[job.Command]
+	if !ok {
+		// The command was removed from the config since the job ran
+		cmd = &config.Command{Name: job.Command}
+	}
+	toolResult := j.output.toolResult(cmd, &result, err)
+	toolResult.Meta.AdditionalFields["jobId"] = job.ID
+	toolResult.Meta.AdditionalFields["status"] = job.Status
+	return toolResult, nil
+}
+
+// handleCancel serves job_cancel
+func (j *jobRunner) handleCancel(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	job, failure := j.lookup(ctx, request)
+	if failure != nil {
+		return failure, nil
+	}
+	if job.Finished() {
+		return jobText("Job %s already finished with status %s", job.ID, job.Status), nil
+	}
+
+	j.mu.Lock()
+	cancel, ok := j.running[job.ID]
+	j.mu.Unlock()
+	if !ok {
+		return jobError("Job %s is running on another server", job.ID), nil
+	}
+	cancel()
+	return jobText("Cancelling job %s; job_status reports it as cancelled once it stopped", job.ID), nil
+}
+
+// lookup returns the job named by the job_id argument if the caller may see it,
+// or the tool result explaining why not
+func (j *jobRunner) lookup(ctx context.Context, request mcp.CallToolRequest) (*jobs.Job, *mcp.CallToolResult) {
+	id, err := request.RequireString("job_id")
+	if err != nil {
+		return nil, jobError("Invalid arguments: %v", err)
+	}
+	if j.store == nil {
+		return nil, jobError("Async jobs are unavailable: the job store could not be opened")
+	}
+	job, err := j.store.Get(id)
+	if err != nil && !errors.Is(err, jobs.ErrNotFound) {
+		return nil, jobError("Failed to read job %s: %v", id, err)
+	}
+	// Jobs of other users are reported as unknown
+	if err != nil || job.Owner != jobOwner(ctx) {
+		return nil, jobError("Job %s not found; finished jobs are kept for a limited time", id)
+	}
+	if j.authEnabled {
+		authCtx, _ := auth.AuthContextFromRequest(ctx)
+		if !authCtx.HasPermission(job.Command) {
+			return nil, jobError("Permission denied for tool '%s'", job.Command)
+		}
+	}
+	return job, nil
+}
+
+// Close cancels running jobs, waits for them to record their result and closes the store
+func (j *jobRunner) Close() error {
+	j.mu.Lock()
+	j.closing = true
+	for _, cancel := range j.running {
+		cancel()
+	}
+	j.mu.Unlock()
+	j.wg.Wait()
+
+	if j.store == nil {
+		return nil
+	}
+	return j.store.Close()
+}
+
+// jobOwner returns the authenticated user of a call, if any
+func jobOwner(ctx context.Context) string {
+	if authCtx, ok := auth.AuthContextFromRequest(ctx); ok && authCtx != nil {
+		return authCtx.UserID
+	}
+	return ""
+}
+
+// jobText returns a plain text tool result
+func jobText(format string, args ...any) *mcp.CallToolResult {
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{mcp.TextContent{Type: "text", Text: fmt.Sprintf(format, args...)}},
+	}
+}
+
+// jobError returns an error tool result
+func jobError(format string, args ...any) *mcp.CallToolResult {
+	result := jobText(format, args...)
+	result.IsError = true
+	return result
+}
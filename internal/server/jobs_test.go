@@ -0,0 +1,218 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gleicon/mcpfier/internal/auth"
+	"github.com/gleicon/mcpfier/internal/config"
+	"github.com/gleicon/mcpfier/internal/executor"
+	"github.com/gleicon/mcpfier/internal/jobs"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// newTestJobRunner returns a job runner for async greet and wait commands, keeping
+// its jobs in a temporary store, and the path of that store
+func newTestJobRunner(t *testing.T, authEnabled bool) (*jobRunner, *config.Config, string) {
+	t.Helper()
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "jobs.db")
+	path := filepath.Join(dir, "config.yaml")
+	os.WriteFile(path, []byte(fmt.Sprintf(`
+jobs:
+  database_path: %s
+commands:
+  - name: greet
+    script: echo
+    args: ["hello {{.params.name}}"]
+    async: true
+    parameters:
+      - name: name
+  - name: wait
+    script: sleep
+    args: ["30"]
+    async: true
+`, dbPath)), 0644)
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	output := newOutputLimiter(config.OutputConfig{PageSize: 1024, MaxRuns: 10})
+	t.Cleanup(func() { output.Close() })
+	runner := newJobRunner(cfg, executor.New(), output, authEnabled)
+	if runner.store == nil {
+		t.Fatal("Expected the job store to open")
+	}
+	return runner, cfg, dbPath
+}
+
+// testCommand returns the named command of cfg
+func testCommand(t *testing.T, cfg *config.Config, name string) *config.Command {
+	t.Helper()
+	for i := range cfg.Commands {
+		if cfg.Commands[i].Name == name {
+			return &cfg.Commands[i]
+		}
+	}
+	t.Fatalf("No command %s", name)
+	return nil
+}
+
+// callJobTool calls a job tool handler for a job ID
+func callJobTool(t *testing.T, ctx context.Context, handler server.ToolHandlerFunc, id string) *mcp.CallToolResult {
+	t.Helper()
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = map[string]any{"job_id": id}
+	result, err := handler(ctx, request)
+	if err != nil {
+		t.Fatalf("Job tool failed: %v", err)
+	}
+	return result
+}
+
+// resultText returns the text of a tool result's first content
+func resultText(result *mcp.CallToolResult) string {
+	if len(result.Content) == 0 {
+		return ""
+	}
+	text, _ := result.Content[0].(mcp.TextContent)
+	return text.Text
+}
+
+// startJob starts an async command and returns the job ID
+func startJob(t *testing.T, ctx context.Context, runner *jobRunner, cmd *config.Command, params map[string]interface{}) string {
+	t.Helper()
+	result := runner.start(ctx, cmd, params)
+	status, _ := result.StructuredContent.(map[string]any)
+	id, _ := status["jobId"].(string)
+	if result.IsError || id == "" || status["status"] != jobs.StatusRunning {
+		t.Fatalf("Expected a running job, got %+v", result)
+	}
+	return id
+}
+
+// waitForJob waits until a job has finished
+func waitForJob(t *testing.T, store *jobs.Store, id string) *jobs.Job {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		job, err := store.Get(id)
+		if err != nil {
+			t.Fatalf("Failed to get job %s: %v", id, err)
+		}
+		if job.Finished() {
+			return job
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Job %s did not finish", id)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestJobRunner(t *testing.T) {
+	runner, cfg, _ := newTestJobRunner(t, false)
+	defer runner.Close()
+	ctx := context.Background()
+
+	id := startJob(t, ctx, runner, testCommand(t, cfg, "greet"), map[string]interface{}{"name": "ada"})
+	waitForJob(t, runner.store, id)
+
+	status := callJobTool(t, ctx, runner.handleStatus, id)
+	fields, _ := status.StructuredContent.(map[string]any)
+	if status.IsError || fields["status"] != jobs.StatusSucceeded || fields["command"] != "greet" || fields["exitCode"] != 0 {
+		t.Errorf("Expected succeeded greet job, got %+v", fields)
+	}
+
+	result := callJobTool(t, ctx, runner.handleResult, id)
+	if result.IsError || !strings.Contains(resultText(result), "hello ada") {
+		t.Errorf("Expected the command output, got %+v", result)
+	}
+	if result.Meta.AdditionalFields["jobId"] != id || result.Meta.AdditionalFields["status"] != jobs.StatusSucceeded {
+		t.Errorf("Expected job meta, got %+v", result.Meta.AdditionalFields)
+	}
+
+	if cancel := callJobTool(t, ctx, runner.handleCancel, id); !strings.Contains(resultText(cancel), "already finished") {
+		t.Errorf("Expected finished job not to be cancelled, got %q", resultText(cancel))
+	}
+
+	// A running job has no result yet and can be cancelled
+	id = startJob(t, ctx, runner, testCommand(t, cfg, "wait"), nil)
+	if result := callJobTool(t, ctx, runner.handleResult, id); result.IsError || !strings.Contains(resultText(result), "still running") {
+		t.Errorf("Expected running job without a result, got %+v", result)
+	}
+	if cancel := callJobTool(t, ctx, runner.handleCancel, id); cancel.IsError || !strings.Contains(resultText(cancel), "Cancelling job") {
+		t.Errorf("Expected job to be cancelled, got %+v", cancel)
+	}
+	if job := waitForJob(t, runner.store, id); job.Status != jobs.StatusCancelled {
+		t.Errorf("Expected cancelled job, got %s", job.Status)
+	}
+
+	for _, handler := range []server.ToolHandlerFunc{runner.handleStatus, runner.handleResult, runner.handleCancel} {
+		if result := callJobTool(t, ctx, handler, "unknown"); !result.IsError || !strings.Contains(resultText(result), "not found") {
+			t.Errorf("Expected unknown job to be reported, got %+v", result)
+		}
+	}
+}
+
+func TestJobRunnerOwners(t *testing.T) {
+	runner, cfg, _ := newTestJobRunner(t, true)
+	defer runner.Close()
+	user := func(id string, permissions ...string) context.Context {
+		return auth.WithAuthContext(context.Background(), &auth.AuthContext{UserID: id, Permissions: permissions})
+	}
+
+	id := startJob(t, user("alice", "greet"), runner, testCommand(t, cfg, "greet"), map[string]interface{}{"name": "ada"})
+	waitForJob(t, runner.store, id)
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		wantErr string
+	}{
+		{"owner", user("alice", "greet"), ""},
+		{"owner with wildcard permission", user("alice", "*"), ""},
+		{"other user", user("bob", "*"), "not found"},
+		{"anonymous caller", context.Background(), "not found"},
+		{"owner without permission for the command", user("alice", "wait"), "Permission denied for tool 'greet'"},
+	}
+	for _, tt := range tests {
+		for tool, handler := range map[string]server.ToolHandlerFunc{"job_status": runner.handleStatus, "job_result": runner.handleResult, "job_cancel": runner.handleCancel} {
+			result := callJobTool(t, tt.ctx, handler, id)
+			if tt.wantErr == "" && result.IsError {
+				t.Errorf("%s: expected %s to succeed, got %q", tt.name, tool, resultText(result))
+			}
+			if tt.wantErr != "" && (!result.IsError || !strings.Contains(resultText(result), tt.wantErr)) {
+				t.Errorf("%s: expected %s to fail with %q, got %q", tt.name, tool, tt.wantErr, resultText(result))
+			}
+		}
+	}
+}
+
+func TestJobRunnerCloseInterrupts(t *testing.T) {
+	runner, cfg, dbPath := newTestJobRunner(t, false)
+	id := startJob(t, context.Background(), runner, testCommand(t, cfg, "wait"), nil)
+	if err := runner.Close(); err != nil {
+		t.Fatalf("Failed to close job runner: %v", err)
+	}
+
+	store, err := jobs.Open(dbPath, config.DefaultJobRetention)
+	if err != nil {
+		t.Fatalf("Failed to reopen job store: %v", err)
+	}
+	defer store.Close()
+	job, err := store.Get(id)
+	if err != nil {
+		t.Fatalf("Failed to get job: %v", err)
+	}
+	if job.Status != jobs.StatusInterrupted || job.Error != "the server stopped while the job ran" {
+		t.Errorf("Expected job interrupted by shutdown, got %s (%s)", job.Status, job.Error)
+	}
+}
@@ -0,0 +1,193 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/gleicon/mcpfier/internal/config"
+	"github.com/gleicon/mcpfier/internal/executor"
+	"github.com/gleicon/mcpfier/internal/runs"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// runResourceTemplate is the URI template for the full output of truncated runs
+const runResourceTemplate = "mcpfier://runs/{id}/{stream}{?page}"
+
+// outputLimiter truncates large output in tool results and keeps the full
+// text in a run store, exposed as paged MCP resources
+type outputLimiter struct {
+	store    *runs.Store // nil if the store could not be created
+	pageSize int
+}
+
+// newOutputLimiter creates the run store used for truncated output
+func newOutputLimiter(cfg config.OutputConfig) *outputLimiter {
+	store, err := runs.NewStore(cfg.MaxRuns)
+	if err != nil {
+		log.Printf("Output store unavailable, truncated output will not be retrievable: %v", err)
+	}
+	return &outputLimiter{store: store, pageSize: cfg.PageSize}
+}
+
+// register exposes stored run output as a resource template
+func (o *outputLimiter) register(mcpServer *server.MCPServer) {
+	if o.store == nil {
+		return
+	}
+	mcpServer.AddResourceTemplate(
+		mcp.NewResourceTemplate(
+			runResourceTemplate,
+			"Command output",
+			mcp.WithTemplateDescription("Full stdout or stderr of a run whose tool result was truncated, one page at a time"),
+			mcp.WithTemplateMIMEType("text/plain"),
+		),
+		o.readResource,
+	)
+}
+
+// readResource serves one page of a stored stream
+func (o *outputLimiter) readResource(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	id := templateArgument(request, "id")
+	stream := templateArgument(request, "stream")
+
+	page := 1
+	if value := templateArgument(request, "page"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid page '%s'", value)
+		}
+		page = n
+	}
+
+	result, err := o.store.ReadPage(id, stream, page, o.pageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	meta := map[string]any{
+		"page":       result.Page,
+		"pages":      result.Pages,
+		"totalBytes": result.TotalBytes,
+	}
+	if result.Page < result.Pages {
+		meta["nextPage"] = runURI(id, stream) + "?page=" + strconv.Itoa(result.Page+1)
+	}
+
+	return []mcp.ResourceContents{
+		mcp.TextResourceContents{
+			Meta:     mcp.NewMetaFromMap(meta),
+			URI:      request.Params.URI,
+			MIMEType: "text/plain",
+			Text:     result.Text,
+		},
+	}, nil
+}
+
+// toolResult builds the tool result, truncating each stream to the command's
+// max_output_bytes and linking to the stored full output
+func (o *outputLimiter) toolResult(cmd *config.Command, result *executor.Result, err error) *mcp.CallToolResult {
+	limit := cmd.MaxOutputBytes
+	if result == nil || limit <= 0 || (len(result.Stdout) <= limit && len(result.Stderr) <= limit) {
+		return newToolResult(result, err)
+	}
+
+	runID := ""
+	if o.store != nil {
+		id, saveErr := o.store.Save(map[string]string{
+			"stdout": result.Stdout,
+			"stderr": result.Stderr,
+		})
+		if saveErr != nil {
+			log.Printf("Failed to store output of %s: %v", cmd.Name, saveErr)
+		} else {
+			runID = id
+		}
+	}
+
+	truncated := *result
+	var links []mcp.Content
+	for _, stream := range []struct {
+		name      string
+		text      *string
+		truncated *bool
+	}{
+		{"stdout", &truncated.Stdout, &truncated.StdoutTruncated},
+		{"stderr", &truncated.Stderr, &truncated.StderrTruncated},
+	} {
+		if len(*stream.text) <= limit {
+			continue
+		}
+		*stream.truncated = true
+
+		uri := ""
+		if runID != "" {
+			uri = runURI(runID, stream.name)
+			links = append(links, mcp.NewResourceLink(
+				uri,
+				fmt.Sprintf("%s %s", cmd.Name, stream.name),
+				fmt.Sprintf("Full %s (%d bytes)", stream.name, len(*stream.text)),
+				"text/plain",
+			))
+		}
+		*stream.text = truncateMiddle(*stream.text, limit, uri)
+	}
+
+	toolResult := newToolResult(&truncated, err)
+	toolResult.Content = append(toolResult.Content, links...)
+	if runID != "" {
+		toolResult.Meta.AdditionalFields["runId"] = runID
+	}
+	return toolResult
+}
+
+// Close removes the stored run output
+func (o *outputLimiter) Close() error {
+	if o.store == nil {
+		return nil
+	}
+	return o.store.Close()
+}
+
+// truncateMiddle keeps the head and tail of s within limit bytes and marks where it
+// was cut. uri, if set, is where the full output can be read.
+func truncateMiddle(s string, limit int, uri string) string {
+	head := limit / 2
+	tail := limit - head
+
+	// Keep cuts on character boundaries
+	for head > 0 && !utf8.RuneStart(s[head]) {
+		head--
+	}
+	tailStart := len(s) - tail
+	for tailStart < len(s) && !utf8.RuneStart(s[tailStart]) {
+		tailStart++
+	}
+
+	marker := fmt.Sprintf("\n\n[... %d bytes truncated ...]\n\n", tailStart-head)
+	if uri != "" {
+		marker = fmt.Sprintf("\n\n[... %d bytes truncated, full output: %s ...]\n\n", tailStart-head, uri)
+	}
+	return s[:head] + marker + s[tailStart:]
+}
+
+// runURI returns the resource URI for a stored stream
+func runURI(id, stream string) string {
+	return fmt.Sprintf("mcpfier://runs/%s/%s", id, stream)
+}
+
+// templateArgument returns a URI template variable as a string
+func templateArgument(request mcp.ReadResourceRequest, name string) string {
+	switch v := request.Params.Arguments[name].(type) {
+	case string:
+		return v
+	case []string:
+		if len(v) > 0 {
+			return v[0]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,152 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/gleicon/mcpfier/internal/config"
+	"github.com/gleicon/mcpfier/internal/executor"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// resourceResponse is the decoded response to a resources/read request
+type resourceResponse struct {
+	Result *struct {
+		Contents []struct {
+			URI      string         `json:"uri"`
+			MIMEType string         `json:"mimeType"`
+			Text     string         `json:"text"`
+			Meta     map[string]any `json:"_meta"`
+		} `json:"contents"`
+	} `json:"result"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// readResource sends a resources/read request for uri through the server's message handling
+func readResource(t *testing.T, ctx context.Context, mcpServer *server.MCPServer, uri string) resourceResponse {
+	t.Helper()
+	request, _ := json.Marshal(map[string]any{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  "resources/read",
+		"params":  map[string]any{"uri": uri},
+	})
+	encoded, err := json.Marshal(mcpServer.HandleMessage(ctx, request))
+	if err != nil {
+		t.Fatalf("Failed to encode response: %v", err)
+	}
+	var response resourceResponse
+	if err := json.Unmarshal(encoded, &response); err != nil {
+		t.Fatalf("Failed to decode response %s: %v", encoded, err)
+	}
+	return response
+}
+
+func TestTruncateMiddle(t *testing.T) {
+	text := strings.Repeat("a", 50) + strings.Repeat("b", 100) + strings.Repeat("c", 50)
+
+	got := truncateMiddle(text, 100, "")
+	if !strings.HasPrefix(got, strings.Repeat("a", 50)+"\n\n[... 100 bytes truncated ...]\n\n") || !strings.HasSuffix(got, strings.Repeat("c", 50)) {
+		t.Errorf("Expected head and tail around the marker, got %q", got)
+	}
+
+	got = truncateMiddle(text, 100, "mcpfier://runs/abc/stdout")
+	if !strings.Contains(got, "[... 100 bytes truncated, full output: mcpfier://runs/abc/stdout ...]") {
+		t.Errorf("Expected marker with the resource URI, got %q", got)
+	}
+
+	// Cuts never split multi-byte characters
+	got = truncateMiddle(strings.Repeat("é", 20), 5, "")
+	head, tail, _ := strings.Cut(got, "\n\n[...")
+	_, tail, _ = strings.Cut(tail, "]\n\n")
+	if head != "é" || tail != "é" || !strings.Contains(got, "[... 36 bytes truncated ...]") {
+		t.Errorf("Expected cuts on character boundaries, got %q", got)
+	}
+}
+
+func TestOutputLimiterToolResult(t *testing.T) {
+	output := newOutputLimiter(config.OutputConfig{PageSize: 16, MaxRuns: 10})
+	defer output.Close()
+	cmd := &config.Command{Name: "logs", MaxOutputBytes: 20}
+
+	// Output within the limit is returned as is
+	small := output.toolResult(cmd, &executor.Result{Stdout: "short"}, nil)
+	if _, ok := small.Meta.AdditionalFields["runId"]; ok || len(small.Content) != 1 {
+		t.Errorf("Expected untruncated result without a run, got %+v", small)
+	}
+
+	stdout := strings.Repeat("0123456789", 5)
+	result := output.toolResult(cmd, &executor.Result{Stdout: stdout, Stderr: "warning"}, nil)
+	runID, _ := result.Meta.AdditionalFields["runId"].(string)
+	if runID == "" || result.Meta.AdditionalFields["stdoutTruncated"] != true {
+		t.Fatalf("Expected truncated stdout stored as a run, got meta %+v", result.Meta.AdditionalFields)
+	}
+	if _, ok := result.Meta.AdditionalFields["stderrTruncated"]; ok {
+		t.Error("Expected stderr within the limit not to be truncated")
+	}
+
+	uri := "mcpfier://runs/" + runID + "/stdout"
+	text := result.Content[0].(mcp.TextContent).Text
+	if !strings.HasPrefix(text, "0123456789") || !strings.Contains(text, "full output: "+uri) {
+		t.Errorf("Expected truncated stdout linking to %s, got %q", uri, text)
+	}
+	var links []mcp.ResourceLink
+	for _, content := range result.Content {
+		if link, ok := content.(mcp.ResourceLink); ok {
+			links = append(links, link)
+		}
+	}
+	if len(links) != 1 || links[0].URI != uri || links[0].MIMEType != "text/plain" {
+		t.Errorf("Expected one resource link to %s, got %+v", uri, links)
+	}
+}
+
+func TestOutputLimiterReadResource(t *testing.T) {
+	output := newOutputLimiter(config.OutputConfig{PageSize: 16, MaxRuns: 10})
+	defer output.Close()
+	mcpServer := server.NewMCPServer("test", "1.0.0")
+	output.register(mcpServer)
+
+	stdout := strings.Repeat("0123456789", 4)
+	id, err := output.store.Save(map[string]string{"stdout": stdout})
+	if err != nil {
+		t.Fatalf("Failed to store output: %v", err)
+	}
+	uri := runURI(id, "stdout")
+
+	tests := []struct {
+		uri      string
+		wantText string
+		wantPage float64
+		wantNext string
+	}{
+		{uri, stdout[:16], 1, uri + "?page=2"},
+		{uri + "?page=2", stdout[16:32], 2, uri + "?page=3"},
+		{uri + "?page=3", stdout[32:], 3, ""},
+	}
+	for _, tt := range tests {
+		response := readResource(t, context.Background(), mcpServer, tt.uri)
+		if response.Result == nil || len(response.Result.Contents) != 1 {
+			t.Fatalf("%s: expected one content, got %+v", tt.uri, response)
+		}
+		content := response.Result.Contents[0]
+		if content.Text != tt.wantText || content.URI != tt.uri || content.MIMEType != "text/plain" {
+			t.Errorf("%s: unexpected content %+v", tt.uri, content)
+		}
+		next, _ := content.Meta["nextPage"].(string)
+		if content.Meta["page"] != tt.wantPage || content.Meta["pages"] != float64(3) || content.Meta["totalBytes"] != float64(40) || next != tt.wantNext {
+			t.Errorf("%s: unexpected meta %+v", tt.uri, content.Meta)
+		}
+	}
+
+	for _, invalid := range []string{uri + "?page=x", uri + "?page=4", runURI(id, "stderr"), runURI("unknown", "stdout")} {
+		if response := readResource(t, context.Background(), mcpServer, invalid); response.Error == nil {
+			t.Errorf("%s: expected error, got %+v", invalid, response.Result)
+		}
+	}
+}
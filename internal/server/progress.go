@@ -0,0 +1,105 @@
+package server
+
+import (
+	"context"
+	"sync"
+
+	"github.com/gleicon/mcpfier/internal/config"
+	"github.com/gleicon/mcpfier/internal/executor"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// outputNotifier forwards streamed command output to the MCP client as
+// notifications/progress and, optionally, notifications/message
+type outputNotifier struct {
+	mu        sync.Mutex
+	ctx       context.Context
+	server    *server.MCPServer
+	token     mcp.ProgressToken
+	logger    string
+	logOutput bool
+	progress  float64
+	total     float64
+	markers   bool // The command reports progress markers, so plain lines no longer count
+}
+
+// withOutputStreaming returns a context that streams command output to the client when
+// the request carries a progressToken or the command has log_output enabled
+func withOutputStreaming(ctx context.Context, mcpServer *server.MCPServer, cmd *config.Command, request mcp.CallToolRequest) context.Context {
+	var token mcp.ProgressToken
+	if request.Params.Meta != nil {
+		token = request.Params.Meta.ProgressToken
+	}
+	if token == nil && !cmd.LogOutput {
+		return ctx
+	}
+
+	n := &outputNotifier{
+		ctx:       ctx,
+		server:    mcpServer,
+		token:     token,
+		logger:    cmd.Name,
+		logOutput: cmd.LogOutput,
+	}
+	return executor.WithOutput(ctx, n.notify)
+}
+
+// notify sends a single output line to the client
+func (n *outputNotifier) notify(line executor.OutputLine) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.token != nil && n.advance(line) {
+		message := line.Text
+		if line.Progress != nil {
+			message = line.Progress.Message
+		}
+
+		params := map[string]any{
+			"progressToken": n.token,
+			"progress":      n.progress,
+			"message":       message,
+		}
+		if n.total > 0 {
+			params["total"] = n.total
+		}
+		n.server.SendNotificationToClient(n.ctx, "notifications/progress", params)
+	}
+
+	if n.logOutput && line.Progress == nil {
+		level := mcp.LoggingLevelInfo
+		if line.Stream == "stderr" {
+			level = mcp.LoggingLevelWarning
+		}
+		n.server.SendLogMessageToClient(n.ctx, mcp.NewLoggingMessageNotification(level, n.logger, map[string]any{
+			"stream": line.Stream,
+			"line":   line.Text,
+		}))
+	}
+}
+
+// advance updates the progress for an output line and returns true if it
+// should be sent. Until the command prints a progress marker, plain lines are
+// counted; the first marker switches to the progress and total it reports, and
+// plain lines no longer count. Progress never stays or goes backwards within
+// either mode, and a marker without a total keeps the last one reported.
+func (n *outputNotifier) advance(line executor.OutputLine) bool {
+	if line.Progress == nil {
+		if n.markers {
+			return false
+		}
+		n.progress++
+		return true
+	}
+
+	if line.Progress.Total > 0 {
+		n.total = line.Progress.Total
+	}
+	if n.markers && line.Progress.Current <= n.progress {
+		return false
+	}
+	n.markers = true
+	n.progress = line.Progress.Current
+	return true
+}
@@ -0,0 +1,148 @@
+package server
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/gleicon/mcpfier/internal/executor"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// testSession is an initialized client session that keeps the notifications sent to it
+type testSession struct {
+	notifications chan mcp.JSONRPCNotification
+}
+
+func newTestSession() *testSession {
+	return &testSession{notifications: make(chan mcp.JSONRPCNotification, 100)}
+}
+
+func (s *testSession) Initialize()                                         {}
+func (s *testSession) Initialized() bool                                   { return true }
+func (s *testSession) NotificationChannel() chan<- mcp.JSONRPCNotification { return s.notifications }
+func (s *testSession) SessionID() string                                   { return "test" }
+
+// received returns the notifications sent so far
+func (s *testSession) received() []mcp.JSONRPCNotification {
+	var received []mcp.JSONRPCNotification
+	for {
+		select {
+		case n := <-s.notifications:
+			received = append(received, n)
+		default:
+			return received
+		}
+	}
+}
+
+func TestOutputNotifierAdvance(t *testing.T) {
+	marker := func(current, total float64) executor.OutputLine {
+		return executor.OutputLine{Stream: "stdout", Progress: &executor.Progress{Current: current, Total: total}}
+	}
+	plain := executor.OutputLine{Stream: "stdout", Text: "working"}
+
+	tests := []struct {
+		name         string
+		lines        []executor.OutputLine
+		wantSent     []bool
+		wantProgress float64
+		wantTotal    float64
+	}{
+		{
+			name:         "plain lines count without a total",
+			lines:        []executor.OutputLine{plain, plain, plain},
+			wantSent:     []bool{true, true, true},
+			wantProgress: 3,
+		},
+		{
+			name:         "markers that do not increase are dropped",
+			lines:        []executor.OutputLine{marker(5, 10), marker(5, 10), marker(3, 10), marker(7, 10)},
+			wantSent:     []bool{true, false, false, true},
+			wantProgress: 7,
+			wantTotal:    10,
+		},
+		{
+			name:         "plain lines do not count once a marker is seen",
+			lines:        []executor.OutputLine{marker(1, 4), plain, plain, marker(2, 4)},
+			wantSent:     []bool{true, false, false, true},
+			wantProgress: 2,
+			wantTotal:    4,
+		},
+		{
+			name:         "markers without a total keep the last one",
+			lines:        []executor.OutputLine{marker(1, 8), marker(2, 0), plain},
+			wantSent:     []bool{true, true, false},
+			wantProgress: 2,
+			wantTotal:    8,
+		},
+		{
+			name:         "the first marker replaces the line count",
+			lines:        []executor.OutputLine{plain, plain, plain, marker(2, 0), marker(4, 0)},
+			wantSent:     []bool{true, true, true, true, true},
+			wantProgress: 4,
+		},
+		{
+			name:         "early markers after many plain lines are sent",
+			lines:        append(slices.Repeat([]executor.OutputLine{plain}, 50), marker(10, 100), plain, marker(20, 100)),
+			wantSent:     append(slices.Repeat([]bool{true}, 50), true, false, true),
+			wantProgress: 20,
+			wantTotal:    100,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := &outputNotifier{}
+			last := 0.0
+			for i, line := range tt.lines {
+				markers := n.markers
+				sent := n.advance(line)
+				if sent != tt.wantSent[i] {
+					t.Fatalf("Line %d: expected sent=%v, got %v", i, tt.wantSent[i], sent)
+				}
+				// Progress only increases, except when the first marker replaces the line count
+				if sent && n.progress <= last && markers == n.markers {
+					t.Fatalf("Line %d: progress went from %v to %v", i, last, n.progress)
+				}
+				last = n.progress
+			}
+			if n.progress != tt.wantProgress || n.total != tt.wantTotal {
+				t.Errorf("Expected progress %v of %v, got %v of %v", tt.wantProgress, tt.wantTotal, n.progress, n.total)
+			}
+		})
+	}
+}
+
+func TestOutputNotifierNotify(t *testing.T) {
+	mcpServer := server.NewMCPServer("test", "1.0.0")
+	session := newTestSession()
+	n := &outputNotifier{
+		ctx:    mcpServer.WithContext(context.Background(), session),
+		server: mcpServer,
+		token:  "tok",
+	}
+
+	for _, line := range []executor.OutputLine{
+		{Stream: "stdout", Progress: &executor.Progress{Current: 2, Total: 10, Message: "two"}},
+		{Stream: "stdout", Text: "plain"},
+		{Stream: "stdout", Progress: &executor.Progress{Current: 1, Message: "back"}},
+		{Stream: "stdout", Progress: &executor.Progress{Current: 6, Message: "six"}},
+	} {
+		n.notify(line)
+	}
+
+	received := session.received()
+	if len(received) != 2 {
+		t.Fatalf("Expected 2 progress notifications, got %d: %+v", len(received), received)
+	}
+	for i, want := range []struct {
+		progress float64
+		message  string
+	}{{2, "two"}, {6, "six"}} {
+		fields := received[i].Params.AdditionalFields
+		if fields["progress"] != want.progress || fields["total"] != float64(10) || fields["message"] != want.message {
+			t.Errorf("Notification %d: expected progress %v of 10 (%s), got %v", i, want.progress, want.message, fields)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gleicon/mcpfier/internal/auth"
+	"github.com/gleicon/mcpfier/internal/config"
+	"github.com/gleicon/mcpfier/internal/executor"
+	"github.com/gleicon/mcpfier/internal/scheduler"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// scheduleStopTimeout is how long scheduled runs may finish at shutdown before they are cancelled
+const scheduleStopTimeout = 10 * time.Second
+
+// scheduleURI returns the resource URI of a scheduled command's latest run
+func scheduleURI(name string) string {
+	return "mcpfier://schedules/" + name
+}
+
+// schedules runs the scheduled commands and serves the latest run of each as a
+// resource, notifying subscribed clients when it changes
+type schedules struct {
+	scheduler     *scheduler.Scheduler // nil without scheduled commands
+	authEnabled   bool                 // Resources are only readable by callers allowed to run their command
+	subscriptions *subscriptions       // nil on transports that cannot notify clients
+}
+
+// newSchedules creates the scheduler when any command has a schedule
+func newSchedules(cfg *config.Config, executorService *executor.Service, authEnabled bool, subs *subscriptions) *schedules {
+	s := &schedules{authEnabled: authEnabled, subscriptions: subs}
+	if cfg.HasSchedules() {
+		s.scheduler = scheduler.New(cfg.Commands, executorService)
+	}
+	return s
+}
+
+// register adds a resource for each scheduled command and starts the scheduler
+func (s *schedules) register(mcpServer *server.MCPServer) {
+	if s.scheduler == nil {
+		return
+	}
+	for _, cmd := range s.scheduler.Commands() {
+		mcpServer.AddResource(
+			mcp.NewResource(
+				scheduleURI(cmd.Name),
+				cmd.Name+" schedule",
+				mcp.WithResourceDescription(fmt.Sprintf("Latest scheduled run of %s (%s)", cmd.Name, cmd.Schedule.Cron)),
+				mcp.WithMIMEType("application/json"),
+			),
+			func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+				return s.read(ctx, cmd)
+			},
+		)
+	}
+
+	s.scheduler.OnRun(func(run *scheduler.Run) {
+		uri := scheduleURI(run.Command)
+		if s.subscriptions != nil && s.subscriptions.subscribed(uri) {
+			mcpServer.SendNotificationToAllClients(mcp.MethodNotificationResourceUpdated, map[string]any{"uri": uri})
+		}
+	})
+	s.scheduler.Start()
+	log.Printf("Scheduler started for %d command(s)", len(s.scheduler.Commands()))
+}
+
+// read serves the latest run of a scheduled command
+func (s *schedules) read(ctx context.Context, cmd *config.Command) ([]mcp.ResourceContents, error) {
+	if s.authEnabled {
+		authCtx, _ := auth.AuthContextFromRequest(ctx)
+		if !authCtx.HasPermission(cmd.Name) {
+			return nil, fmt.Errorf("permission denied for schedule '%s'", cmd.Name)
+		}
+	}
+
+	status := scheduleStatus(cmd, s.scheduler.Latest(cmd.Name), s.scheduler.Next(cmd))
+	text, _ := json.MarshalIndent(status, "", "  ")
+	return []mcp.ResourceContents{
+		mcp.TextResourceContents{
+			URI:      scheduleURI(cmd.Name),
+			MIMEType: "application/json",
+			Text:     string(text),
+		},
+	}, nil
+}
+
+// scheduleStatus describes a scheduled command's latest run, nil if it has not
+// run yet, and its next run, zero if there is none
+func scheduleStatus(cmd *config.Command, run *scheduler.Run, next time.Time) map[string]any {
+	status := map[string]any{
+		"command":  cmd.Name,
+		"schedule": cmd.Schedule.Cron,
+		"status":   "pending",
+	}
+	if !next.IsZero() {
+		status["nextRun"] = next.UTC().Format(time.RFC3339)
+	}
+	if run == nil {
+		return status
+	}
+
+	status["status"] = "succeeded"
+	status["startedAt"] = run.StartedAt.UTC().Format(time.RFC3339)
+	if run.Err != nil {
+		status["status"] = "failed"
+		status["error"] = run.Err.Error()
+	}
+	if run.Result != nil {
+		status["exitCode"] = run.Result.ExitCode
+		status["durationMs"] = run.Result.Duration.Milliseconds()
+		status["stdout"] = limitOutput(run.Result.Stdout, cmd.MaxOutputBytes)
+		status["stderr"] = limitOutput(run.Result.Stderr, cmd.MaxOutputBytes)
+	}
+	return status
+}
+
+// Close stops scheduling runs, giving running ones scheduleStopTimeout to finish
+func (s *schedules) Close() error {
+	if s.scheduler == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), scheduleStopTimeout)
+	defer cancel()
+	if err := s.scheduler.Stop(ctx); err != nil {
+		log.Printf("Scheduled runs cancelled at shutdown: %v", err)
+		return err
+	}
+	return nil
+}
+
+// limitOutput truncates text to the command's max_output_bytes
+func limitOutput(text string, limit int) string {
+	if limit <= 0 || len(text) <= limit {
+		return text
+	}
+	return truncateMiddle(text, limit, "")
+}
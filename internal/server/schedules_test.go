@@ -0,0 +1,130 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gleicon/mcpfier/internal/auth"
+	"github.com/gleicon/mcpfier/internal/config"
+	"github.com/gleicon/mcpfier/internal/executor"
+	"github.com/gleicon/mcpfier/internal/scheduler"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+func TestScheduleStatus(t *testing.T) {
+	cmd := &config.Command{Name: "backup", MaxOutputBytes: 10, Schedule: &config.ScheduleConfig{Cron: "0 3 * * *"}}
+	next := time.Date(2026, 10, 17, 3, 0, 0, 0, time.UTC)
+	started := time.Date(2026, 10, 16, 3, 0, 0, 0, time.FixedZone("CEST", 2*60*60))
+
+	tests := []struct {
+		name string
+		run  *scheduler.Run
+		next time.Time
+		want string
+	}{
+		{
+			name: "pending",
+			next: next,
+			want: `{"command":"backup","nextRun":"2026-10-17T03:00:00Z","schedule":"0 3 * * *","status":"pending"}`,
+		},
+		{
+			name: "pending without a next run",
+			want: `{"command":"backup","schedule":"0 3 * * *","status":"pending"}`,
+		},
+		{
+			name: "succeeded with truncated output",
+			run: &scheduler.Run{Command: "backup", StartedAt: started, Result: &executor.Result{
+				Stdout:   "0123456789abcdefghij",
+				Stderr:   "warning",
+				Duration: 1500 * time.Millisecond,
+			}},
+			next: next,
+			want: `{"command":"backup","durationMs":1500,"exitCode":0,"nextRun":"2026-10-17T03:00:00Z","schedule":"0 3 * * *","startedAt":"2026-10-16T01:00:00Z","status":"succeeded","stderr":"warning","stdout":"01234\n\n[... 10 bytes truncated ...]\n\nfghij"}`,
+		},
+		{
+			name: "failed with a result",
+			run:  &scheduler.Run{Command: "backup", StartedAt: started, Result: &executor.Result{ExitCode: 2, Stderr: "disk full"}, Err: errors.New("exit status 2")},
+			next: next,
+			want: `{"command":"backup","durationMs":0,"error":"exit status 2","exitCode":2,"nextRun":"2026-10-17T03:00:00Z","schedule":"0 3 * * *","startedAt":"2026-10-16T01:00:00Z","status":"failed","stderr":"disk full","stdout":""}`,
+		},
+		{
+			name: "failed to start",
+			run:  &scheduler.Run{Command: "backup", StartedAt: started, Err: errors.New("parameter 'target': required")},
+			next: next,
+			want: `{"command":"backup","error":"parameter 'target': required","nextRun":"2026-10-17T03:00:00Z","schedule":"0 3 * * *","startedAt":"2026-10-16T01:00:00Z","status":"failed"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := json.Marshal(scheduleStatus(cmd, tt.run, tt.next))
+			if err != nil {
+				t.Fatalf("Failed to encode status: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestSchedulesReadResource(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	os.WriteFile(path, []byte(`
+commands:
+  - name: backup
+    script: echo
+    schedule: "0 3 * * *"
+  - name: report
+    script: echo
+`), 0644)
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	schedules := newSchedules(cfg, executor.New(), true, nil)
+	defer schedules.Close()
+	mcpServer := server.NewMCPServer("test", "1.0.0")
+	schedules.register(mcpServer)
+
+	allowed := auth.WithAuthContext(context.Background(), &auth.AuthContext{UserID: "ops", Permissions: []string{"backup"}})
+	response := readResource(t, allowed, mcpServer, "mcpfier://schedules/backup")
+	if response.Result == nil || len(response.Result.Contents) != 1 {
+		t.Fatalf("Expected one content, got %+v", response)
+	}
+	content := response.Result.Contents[0]
+	if content.URI != "mcpfier://schedules/backup" || content.MIMEType != "application/json" {
+		t.Errorf("Unexpected content %+v", content)
+	}
+	var status map[string]any
+	if err := json.Unmarshal([]byte(content.Text), &status); err != nil {
+		t.Fatalf("Expected JSON, got %q: %v", content.Text, err)
+	}
+	nextRun, _ := status["nextRun"].(string)
+	next, err := time.Parse(time.RFC3339, nextRun)
+	if err != nil || !next.After(time.Now()) || next.After(time.Now().Add(25*time.Hour)) {
+		t.Errorf("Expected the next run within a day, got %q", nextRun)
+	}
+	if status["command"] != "backup" || status["schedule"] != "0 3 * * *" || status["status"] != "pending" || len(status) != 4 {
+		t.Errorf("Expected a pending schedule, got %v", status)
+	}
+
+	// Commands without a schedule have no resource, and callers need permission for the command
+	for uri, ctx := range map[string]context.Context{
+		"mcpfier://schedules/report": allowed,
+		"mcpfier://schedules/backup": auth.WithAuthContext(context.Background(), &auth.AuthContext{UserID: "dev", Permissions: []string{"report"}}),
+	} {
+		if response := readResource(t, ctx, mcpServer, uri); response.Error == nil {
+			t.Errorf("%s: expected error, got %+v", uri, response.Result)
+		} else if strings.Contains(uri, "backup") && !strings.Contains(response.Error.Message, "permission denied") {
+			t.Errorf("%s: expected permission error, got %s", uri, response.Error.Message)
+		}
+	}
+}
@@ -3,6 +3,9 @@ package server
 import (
 	"context"
 	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/gleicon/mcpfier/internal/analytics"
 	"github.com/gleicon/mcpfier/internal/config"
@@ -17,6 +20,12 @@ type MCPFierServer struct {
 	executor  *executor.Service
 	server    *server.MCPServer
 	analytics analytics.Analytics
+
+	cancellations *cancellations
+	output        *outputLimiter
+	jobs          *jobRunner
+	schedules     *schedules
+	subscriptions *subscriptions
 }
 
 // New creates a new MCPFier STDIO server instance
@@ -34,68 +43,94 @@ func New(cfg *config.Config) *MCPFierServer {
 		}
 	}
 	
-	executorService := executor.New().WithAnalytics(analyticsService)
+	executorService := executor.NewFromConfig(cfg, analyticsService, true)
+
+	hooks := &server.Hooks{}
+	cancels := newCancellations(hooks, false)
+	options := []server.ServerOption{
+		server.WithToolCapabilities(true),
+		server.WithLogging(),
+		server.WithHooks(hooks),
+	}
+	// Clients can subscribe to the latest run of scheduled commands
+	if cfg.HasSchedules() {
+		options = append(options, server.WithResourceCapabilities(true, false))
+	}
+	mcpServer := server.NewMCPServer("mcpfier", "1.0.0", options...)
+	cancels.register(mcpServer)
+	output := newOutputLimiter(cfg.Output)
+	output.register(mcpServer)
+	subs := newSubscriptions()
+	schedules := newSchedules(cfg, executorService, false, subs)
+	schedules.register(mcpServer)
 	
 	return &MCPFierServer{
-		config:    cfg,
-		executor:  executorService,
-		analytics: analyticsService,
-		server: server.NewMCPServer(
-			"mcpfier",
-			"1.0.0",
-			server.WithToolCapabilities(true),
-		),
+		config:        cfg,
+		executor:      executorService,
+		analytics:     analyticsService,
+		server:        mcpServer,
+		cancellations: cancels,
+		output:        output,
+		jobs:          newJobRunner(cfg, executorService, output, false),
+		schedules:     schedules,
+		subscriptions: subs,
 	}
 }
 
-// RegisterTools registers all configured commands as MCP tools
+// RegisterTools registers all configured commands as MCP tools, and the job tools if any command is async
 func (s *MCPFierServer) RegisterTools() {
 	for _, cmd := range s.config.Commands {
 		cmdCopy := cmd // Capture loop variable
 		s.server.AddTool(
-			mcp.NewTool(cmdCopy.Name,
-				mcp.WithDescription(cmdCopy.GetDescription()),
-			),
+			newTool(cmdCopy),
 			func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-				return s.executeCommand(ctx, cmdCopy.Name)
+				ctx, done := s.cancellations.track(ctx, request)
+				defer done()
+				ctx = withOutputStreaming(ctx, s.server, &cmdCopy, request)
+				return s.executeCommand(ctx, &cmdCopy, request.GetArguments())
 			},
 		)
 	}
+	s.jobs.register(s.server)
 }
 
-// executeCommand executes a command and returns MCP-formatted result
-func (s *MCPFierServer) executeCommand(ctx context.Context, commandName string) (*mcp.CallToolResult, error) {
-	output, err := s.executor.ExecuteByName(ctx, s.config, commandName)
+// executeCommand validates the arguments, executes a command and returns MCP-formatted result
+func (s *MCPFierServer) executeCommand(ctx context.Context, cmd *config.Command, arguments map[string]any) (*mcp.CallToolResult, error) {
+	params, err := cmd.ResolveArguments(arguments)
 	if err != nil {
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{
 				mcp.TextContent{
 					Type: "text",
-					Text: fmt.Sprintf("Command execution failed: %v\nOutput: %s", err, output),
+					Text: fmt.Sprintf("Invalid arguments: %v", err),
 				},
 			},
 			IsError: true,
 		}, nil
 	}
 
-	return &mcp.CallToolResult{
-		Content: []mcp.Content{
-			mcp.TextContent{
-				Type: "text",
-				Text: output,
-			},
-		},
-	}, nil
+	if cmd.Async {
+		return s.jobs.start(ctx, cmd, params), nil
+	}
+	result, err := s.executor.Execute(ctx, cmd, params)
+	return s.output.toolResult(cmd, result, err), nil
 }
 
-// Start starts the MCP stdio server
+// Start starts the MCP stdio server, reading requests through the resource subscription filter
 func (s *MCPFierServer) Start() error {
 	s.RegisterTools()
-	return server.ServeStdio(s.server)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return server.NewStdioServer(s.server).Listen(ctx, s.subscriptions.filter(os.Stdin), os.Stdout)
 }
 
-// Close closes the server and analytics
+// Close stops the scheduler, cancels running jobs, drains container pools, closes database connections, removes stored run output and closes analytics
 func (s *MCPFierServer) Close() error {
+	s.schedules.Close()
+	s.jobs.Close()
+	s.executor.Close()
+	s.output.Close()
 	return s.analytics.Close()
 }
 
@@ -0,0 +1,94 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Resource subscription methods, which mcp-go does not define
+const (
+	methodResourcesSubscribe   = "resources/subscribe"
+	methodResourcesUnsubscribe = "resources/unsubscribe"
+)
+
+// subscriptions tracks the resources a stdio client subscribed to. mcp-go
+// does not handle resources/subscribe, so the requests are recorded before
+// they reach it and rewritten as a ping, whose empty result is also the
+// result of subscribe and unsubscribe.
+type subscriptions struct {
+	mu   sync.Mutex
+	uris map[string]bool
+}
+
+// newSubscriptions creates an empty subscription set
+func newSubscriptions() *subscriptions {
+	return &subscriptions{uris: make(map[string]bool)}
+}
+
+// subscribed returns true if the client subscribed to uri
+func (s *subscriptions) subscribed(uri string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.uris[uri]
+}
+
+// filter returns a reader of the messages in r with subscribe and unsubscribe
+// requests recorded and rewritten
+func (s *subscriptions) filter(r io.Reader) io.Reader {
+	pr, pw := io.Pipe()
+	go func() {
+		reader := bufio.NewReader(r)
+		for {
+			line, err := reader.ReadBytes('\n')
+			if len(line) > 0 {
+				if _, werr := pw.Write(s.rewrite(line)); werr != nil {
+					return
+				}
+			}
+			if err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+		}
+	}()
+	return pr
+}
+
+// rewrite records a subscribe or unsubscribe request and returns a ping with
+// the same ID in its place; other messages are returned unchanged
+func (s *subscriptions) rewrite(line []byte) []byte {
+	var request struct {
+		ID     json.RawMessage `json:"id"`
+		Method string          `json:"method"`
+		Params struct {
+			URI string `json:"uri"`
+		} `json:"params"`
+	}
+	if json.Unmarshal(line, &request) != nil || request.ID == nil || request.Params.URI == "" {
+		return line
+	}
+
+	switch request.Method {
+	case methodResourcesSubscribe:
+		s.mu.Lock()
+		s.uris[request.Params.URI] = true
+		s.mu.Unlock()
+	case methodResourcesUnsubscribe:
+		s.mu.Lock()
+		delete(s.uris, request.Params.URI)
+		s.mu.Unlock()
+	default:
+		return line
+	}
+
+	ping, _ := json.Marshal(map[string]any{
+		"jsonrpc": mcp.JSONRPC_VERSION,
+		"id":      request.ID,
+		"method":  string(mcp.MethodPing),
+	})
+	return append(ping, '\n')
+}
@@ -0,0 +1,90 @@
+package server
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestSubscriptionsFilter(t *testing.T) {
+	longURI := "mcpfier://schedules/" + strings.Repeat("x", 100_000)
+	large := `{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"echo","arguments":{"text":"` + strings.Repeat("a", 1<<20) + `"}}}` + "\n"
+
+	tests := []struct {
+		input string
+		want  string
+	}{
+		// Subscribe and unsubscribe requests become pings with the same ID
+		{
+			`{"jsonrpc":"2.0","id":1,"method":"resources/subscribe","params":{"uri":"mcpfier://schedules/backup"}}` + "\n",
+			`{"id":1,"jsonrpc":"2.0","method":"ping"}` + "\n",
+		},
+		{
+			`{"jsonrpc":"2.0","id":"two","method":"resources/subscribe","params":{"uri":"mcpfier://schedules/report"}}` + "\n",
+			`{"id":"two","jsonrpc":"2.0","method":"ping"}` + "\n",
+		},
+		{
+			`{"jsonrpc":"2.0","id":2,"method":"resources/unsubscribe","params":{"uri":"mcpfier://schedules/report"}}` + "\n",
+			`{"id":2,"jsonrpc":"2.0","method":"ping"}` + "\n",
+		},
+		// Other messages pass through byte for byte, including ones that do not parse
+		{
+			`{ "jsonrpc": "2.0", "id": 7, "method": "resources/read", "params": {"uri": "mcpfier://schedules/backup"} }` + "\r\n",
+			`{ "jsonrpc": "2.0", "id": 7, "method": "resources/read", "params": {"uri": "mcpfier://schedules/backup"} }` + "\r\n",
+		},
+		{
+			`{"jsonrpc":"2.0","method":"resources/subscribe","params":{"uri":"mcpfier://schedules/notification"}}` + "\n",
+			`{"jsonrpc":"2.0","method":"resources/subscribe","params":{"uri":"mcpfier://schedules/notification"}}` + "\n",
+		},
+		{"not json\n", "not json\n"},
+		{"\n", "\n"},
+		// Lines longer than any read buffer are handled whole
+		{large, large},
+		{
+			`{"jsonrpc":"2.0","id":4,"method":"resources/subscribe","params":{"uri":"` + longURI + `"}}` + "\n",
+			`{"id":4,"jsonrpc":"2.0","method":"ping"}` + "\n",
+		},
+		// A last line without a newline is still delivered
+		{`{"jsonrpc":"2.0","id":5,"method":"ping"}`, `{"jsonrpc":"2.0","id":5,"method":"ping"}`},
+	}
+
+	var input, want strings.Builder
+	for _, tt := range tests {
+		input.WriteString(tt.input)
+		want.WriteString(tt.want)
+	}
+
+	// Write the input in small chunks, so lines arrive in several reads
+	pr, pw := io.Pipe()
+	go func() {
+		data := input.String()
+		for len(data) > 0 {
+			n := min(len(data), 7)
+			if _, err := pw.Write([]byte(data[:n])); err != nil {
+				return
+			}
+			data = data[n:]
+		}
+		pw.Close()
+	}()
+
+	subs := newSubscriptions()
+	got, err := io.ReadAll(subs.filter(pr))
+	if err != nil {
+		t.Fatalf("Failed to read filtered input: %v", err)
+	}
+	if string(got) != want.String() {
+		t.Errorf("Unexpected filtered output (%d bytes, want %d):\n%.500q", len(got), want.Len(), got)
+	}
+
+	for uri, want := range map[string]bool{
+		"mcpfier://schedules/backup":       true,
+		"mcpfier://schedules/report":       false,
+		"mcpfier://schedules/notification": false,
+		longURI:                            true,
+	} {
+		if subs.subscribed(uri) != want {
+			t.Errorf("Expected subscribed(%.40s) to be %v", uri, want)
+		}
+	}
+}
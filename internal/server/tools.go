@@ -0,0 +1,156 @@
+package server
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/gleicon/mcpfier/internal/config"
+	"github.com/gleicon/mcpfier/internal/executor"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// newTool builds the MCP tool definition for a command, including its inputSchema
+func newTool(cmd config.Command) mcp.Tool {
+	description := cmd.GetDescription()
+	if cmd.Async {
+		description += " (runs in the background: returns a job ID for job_status, job_result and job_cancel)"
+	}
+	opts := []mcp.ToolOption{
+		mcp.WithDescription(description),
+	}
+
+	for _, param := range cmd.Parameters {
+		opts = append(opts, parameterOption(param))
+	}
+
+	return mcp.NewTool(cmd.Name, opts...)
+}
+
+// parameterOption translates a configured parameter into a JSON Schema property
+func parameterOption(param config.Parameter) mcp.ToolOption {
+	var propOpts []mcp.PropertyOption
+	if param.Description != "" {
+		propOpts = append(propOpts, mcp.Description(param.Description))
+	}
+	if param.Required {
+		propOpts = append(propOpts, mcp.Required())
+	}
+	if param.Default != nil {
+		propOpts = append(propOpts, withDefault(param.Default))
+	}
+
+	switch param.GetType() {
+	case config.ParamNumber:
+		return mcp.WithNumber(param.Name, propOpts...)
+	case config.ParamBoolean:
+		return mcp.WithBoolean(param.Name, propOpts...)
+	case config.ParamEnum:
+		propOpts = append(propOpts, mcp.Enum(param.Values...))
+		return mcp.WithString(param.Name, propOpts...)
+	case config.ParamArray:
+		switch param.GetItems() {
+		case config.ParamNumber:
+			propOpts = append(propOpts, mcp.WithNumberItems())
+		case config.ParamBoolean:
+			propOpts = append(propOpts, mcp.WithBooleanItems())
+		default:
+			propOpts = append(propOpts, mcp.WithStringItems())
+		}
+		return mcp.WithArray(param.Name, propOpts...)
+	default:
+		return mcp.WithString(param.Name, propOpts...)
+	}
+}
+
+// withDefault sets the schema default regardless of the property type
+func withDefault(value interface{}) mcp.PropertyOption {
+	return func(schema map[string]any) {
+		schema["default"] = value
+	}
+}
+
+// newToolResult renders an execution result as MCP content: stdout and stderr go into
+// separate text blocks and the exit status is reported in the result metadata
+func newToolResult(result *executor.Result, err error) *mcp.CallToolResult {
+	if result == nil {
+		result = &executor.Result{ExitCode: -1}
+	}
+
+	var content []mcp.Content
+	if err != nil {
+		content = append(content, mcp.TextContent{
+			Type: "text",
+			Text: fmt.Sprintf("Command execution failed: %v", err),
+		})
+	}
+	if result.Stdout != "" || (err == nil && result.Stderr == "" && len(result.Artifacts) == 0) {
+		content = append(content, mcp.TextContent{
+			Type: "text",
+			Text: result.Stdout,
+		})
+	}
+	if result.Stderr != "" {
+		content = append(content, mcp.TextContent{
+			Type: "text",
+			Text: "stderr:\n" + result.Stderr,
+		})
+	}
+	for _, artifact := range result.Artifacts {
+		content = append(content, artifactContent(artifact))
+	}
+
+	meta := map[string]any{
+		"exitCode":   result.ExitCode,
+		"durationMs": result.Duration.Milliseconds(),
+	}
+	if result.Signal != "" {
+		meta["signal"] = result.Signal
+	}
+	if result.HTTPStatus != 0 {
+		meta["httpStatus"] = result.HTTPStatus
+	}
+	if result.StdoutTruncated {
+		meta["stdoutTruncated"] = true
+	}
+	if result.StderrTruncated {
+		meta["stderrTruncated"] = true
+	}
+
+	return &mcp.CallToolResult{
+		Result:            mcp.Result{Meta: mcp.NewMetaFromMap(meta)},
+		Content:           content,
+		StructuredContent: result.Structured,
+		IsError:           err != nil,
+	}
+}
+
+// artifactContent returns images and audio as their MCP content types and
+// anything else as an embedded resource
+func artifactContent(artifact executor.Artifact) mcp.Content {
+	data := base64.StdEncoding.EncodeToString(artifact.Data)
+
+	switch {
+	case strings.HasPrefix(artifact.MIMEType, "image/"):
+		return mcp.ImageContent{
+			Type:     "image",
+			Data:     data,
+			MIMEType: artifact.MIMEType,
+		}
+	case strings.HasPrefix(artifact.MIMEType, "audio/"):
+		return mcp.AudioContent{
+			Type:     "audio",
+			Data:     data,
+			MIMEType: artifact.MIMEType,
+		}
+	default:
+		return mcp.EmbeddedResource{
+			Type: "resource",
+			Resource: mcp.BlobResourceContents{
+				URI:      "mcpfier://artifacts/" + artifact.Name,
+				MIMEType: artifact.MIMEType,
+				Blob:     data,
+			},
+		}
+	}
+}
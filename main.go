@@ -11,11 +11,16 @@ import (
 	"github.com/gleicon/mcpfier/internal/analytics"
 	"github.com/gleicon/mcpfier/internal/config"
 	"github.com/gleicon/mcpfier/internal/executor"
+	"github.com/gleicon/mcpfier/internal/sandbox"
 	"github.com/gleicon/mcpfier/internal/server"
 	"github.com/gleicon/mcpfier/internal/setup"
 )
 
 func main() {
+	// When mcpfier re-executes itself to set up a command sandbox, this runs the
+	// sandbox and never returns
+	sandbox.Init()
+
 	// Parse command line arguments
 	args := parseArgs()
 	
@@ -50,7 +55,9 @@ func startMCPServer() {
 	}
 
 	mcpServer := server.New(cfg)
-	if err := mcpServer.Start(); err != nil {
+	err = mcpServer.Start()
+	mcpServer.Close()
+	if err != nil {
 		log.Fatalf("Server error: %v", err)
 	}
 }
@@ -62,7 +69,9 @@ func startHTTPServer() {
 	}
 
 	httpServer := server.NewHTTP(cfg)
-	if err := httpServer.Start(); err != nil {
+	err = httpServer.Start()
+	httpServer.Close()
+	if err != nil {
 		log.Fatalf("HTTP server error: %v", err)
 	}
 }
@@ -77,11 +86,11 @@ func executeLegacyCommand(commandName string) {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 
-	// Find the command
+	// Find the command; it points into cfg so plugins can describe it below
 	var foundCmd *config.Command
-	for _, cmd := range cfg.Commands {
-		if cmd.Name == commandName {
-			foundCmd = &cmd
+	for i := range cfg.Commands {
+		if cfg.Commands[i].Name == commandName {
+			foundCmd = &cfg.Commands[i]
 			break
 		}
 	}
@@ -101,17 +110,24 @@ func executeLegacyCommand(commandName string) {
 		}
 	}
 
-	// Execute using the executor service to get analytics
-	executorService := executor.New().WithAnalytics(analyticsService)
+	// Execute using the executor service to get analytics; a single run needs no warm pools
+	executorService := executor.NewFromConfig(cfg, analyticsService, false)
 	ctx := context.Background()
-	
-	output, err := executorService.Execute(ctx, foundCmd)
+
+	// Legacy mode takes no tool arguments, so parameters fall back to their defaults
+	params, err := foundCmd.ResolveArguments(nil)
 	if err != nil {
 		log.Fatalf("Failed to run command '%s': %v", commandName, err)
 	}
 	
+	result, err := executorService.Execute(ctx, foundCmd, params)
+	
 	// Print output to maintain compatibility
-	fmt.Print(output)
+	fmt.Print(result.Stdout)
+	fmt.Fprint(os.Stderr, result.Stderr)
+	if err != nil {
+		log.Fatalf("Failed to run command '%s': %v", commandName, err)
+	}
 }
 
 func showAnalytics() {